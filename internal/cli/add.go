@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"github.com/ti-lo/tilokit/internal/core/detect"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// RunAddFeatures adds features to an existing project
+func (m *Manager) RunAddFeatures() error {
+	utils.SetQuiet(m.Quiet)
+
+	dir := m.Project
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve project directory")
+	}
+
+	project, err := detect.Detect(dir)
+	if err != nil {
+		return err
+	}
+	utils.Info("🔍 Detected a %s project built with %s (from %s)",
+		project.Config.Framework, project.Config.BuildTool, project.Source)
+
+	selection, added, err := features.Builtin().Add(project.Config.Framework, project.Config.Features, m.AddFeatures)
+	if err != nil {
+		return err
+	}
+
+	// The project is augmented in place, whatever name it was generated with
+	config := project.Config
+	config.ProjectName = filepath.Base(dir)
+	config.OutputDir = filepath.Dir(dir)
+	config.Features = selection.Features
+	config.GitInit = selection.Has(features.Git)
+	config.InstallDeps = selection.Has(features.InstallDeps)
+	config.ExcludePlugins = m.SkipPlugins
+
+	gen, err := m.newGenerator(nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := gen.AddFeatures(ctx, &config, added); err != nil {
+		utils.Error("Adding features failed: %v", err)
+		return err
+	}
+
+	utils.Success("Added %s to %s", strings.Join(added, ", "), dir)
+	return nil
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ti-lo/tilokit/internal/core/archive"
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/utils"
+	"github.com/ti-lo/tilokit/pkg/tilokit"
+)
+
+// stdoutPath is the --output value that streams an archive to stdout
+const stdoutPath = "-"
+
+// resolveOutputFormat validates --output-format and sets up -o -, which
+// streams a tar archive unless another format is given. Messages then go to
+// stderr and nothing is prompted for. With an archive format, an -o whose
+// extension matches it names the archive file rather than its directory.
+func (m *Manager) resolveOutputFormat() error {
+	format, err := archive.ParseFormat(m.OutputFormat)
+	if err != nil {
+		return err
+	}
+
+	if m.OutputDir == stdoutPath {
+		if format == archive.Dir {
+			format = archive.Tar
+		}
+		m.toStdout = true
+		m.OutputDir = "."
+		m.NoInput = true
+		utils.SetOutput(os.Stderr)
+	}
+	if pathFormat, ok := archive.FormatOf(m.OutputDir); ok && format != archive.Dir {
+		if pathFormat != format {
+			return fmt.Errorf("-o %s is a %s archive, but --output-format is %s", m.OutputDir, pathFormat, format)
+		}
+		m.archiveFile = m.OutputDir
+		m.OutputDir = filepath.Dir(m.OutputDir)
+	}
+	m.OutputFormat = string(format)
+	return nil
+}
+
+// writesArchive reports whether the project is written as an archive
+// rather than a directory
+func (m *Manager) writesArchive() bool {
+	return m.OutputFormat != "" && m.OutputFormat != string(archive.Dir)
+}
+
+// writeArchive generates the project into fsys, an in-memory filesystem, and
+// writes it as an archive. Plugins skip git and other side effects there.
+func (m *Manager) writeArchive(ctx context.Context, gen *tilokit.Generator, fsys utils.FS, config *tilocontext.ProjectConfig) error {
+	format := archive.Format(m.OutputFormat)
+
+	result, err := gen.Generate(ctx, config)
+	if errors.Is(err, context.Canceled) {
+		utils.Warning("Project generation cancelled, no files were written")
+		return err
+	}
+	if err != nil {
+		utils.Error("Project generation failed: %v", err)
+		return err
+	}
+
+	location := "stdout"
+	if m.toStdout {
+		err = archive.Write(os.Stdout, format, fsys, result.ProjectPath, config.ProjectName, result.StartTime)
+	} else {
+		location = m.archiveFile
+		if location == "" {
+			location = filepath.Join(config.OutputDir, config.ProjectName+format.Extension())
+		}
+		err = m.writeArchiveFile(location, format, fsys, result)
+	}
+	if err != nil {
+		utils.Error("Failed to write archive: %v", err)
+		return err
+	}
+
+	for _, skipped := range result.Skipped {
+		utils.Warning("Skipped %s (%s): %s", skipped.Step, skipped.Plugin, skipped.Reason)
+	}
+	utils.Success("%s project '%s' written to %s as %s (%d files)",
+		m.Framework, m.ProjectName, location, format, len(result.Files))
+	return nil
+}
+
+// writeArchiveFile writes the archive next to its final path and renames it
+// into place, so a failed run leaves no partial archive behind
+func (m *Manager) writeArchiveFile(path string, format archive.Format, fsys utils.FS, result *tilokit.Result) error {
+	if utils.FileExists(path) && !m.Force {
+		return fmt.Errorf("'%s' already exists. Use --force to overwrite", path)
+	}
+	if err := utils.EnsureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	name := filepath.Base(result.ProjectPath)
+	if err := archive.Write(file, format, fsys, result.ProjectPath, name, result.StartTime); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
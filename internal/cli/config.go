@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ti-lo/tilokit/internal/config"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// defaultEditor is used by --config-edit when neither VISUAL nor EDITOR is set
+const defaultEditor = "vi"
+
+// HasConfigFlags reports whether a configuration management flag was given
+func (m *Manager) HasConfigFlags() bool {
+	return m.ConfigGet != "" || m.ConfigSet != "" || m.ConfigList || m.ConfigEdit || m.ConfigValidate
+}
+
+// RunConfigCommand handles the --config-* flags
+func (m *Manager) RunConfigCommand() error {
+	if m.ShowOrigin && !m.ConfigList && m.ConfigGet == "" {
+		return fmt.Errorf("--show-origin requires --config-list or --config-get")
+	}
+
+	switch {
+	case m.ConfigSet != "":
+		return m.setConfigValue()
+	case m.ConfigEdit:
+		return m.editConfig()
+	case m.ConfigValidate:
+		return m.validateConfig()
+	case m.ConfigGet != "":
+		return m.getConfigValue()
+	default:
+		return m.listConfig()
+	}
+}
+
+func (m *Manager) loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(config.LoadOptions{Flags: m.configFlags})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg, nil
+}
+
+func (m *Manager) getConfigValue() error {
+	cfg, err := m.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	value, err := cfg.Get(m.ConfigGet)
+	if err != nil {
+		return err
+	}
+
+	if m.ShowOrigin {
+		fmt.Printf("%s\t%s\n", cfg.Origin(m.ConfigGet), config.FormatValue(value))
+		return nil
+	}
+	fmt.Println(config.FormatValue(value))
+	return nil
+}
+
+func (m *Manager) setConfigValue() error {
+	key, value, found := strings.Cut(m.ConfigSet, "=")
+	if !found {
+		return fmt.Errorf("--config-set expects key=value, got %q", m.ConfigSet)
+	}
+
+	path := config.GlobalConfigPath()
+	if err := config.SetValue(path, strings.TrimSpace(key), value); err != nil {
+		return err
+	}
+
+	utils.Success("Set %s in %s", strings.TrimSpace(key), path)
+	return nil
+}
+
+func (m *Manager) listConfig() error {
+	cfg, err := m.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	entries, err := cfg.Entries()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if m.ShowOrigin {
+			fmt.Printf("%s\t%s=%s\n", entry.Origin, entry.Key, config.FormatValue(entry.Value))
+			continue
+		}
+		fmt.Printf("%s=%s\n", entry.Key, config.FormatValue(entry.Value))
+	}
+	return nil
+}
+
+func (m *Manager) editConfig() error {
+	path := config.GlobalConfigPath()
+	if !utils.FileExists(path) {
+		if err := utils.WriteFile(path, "# TiLoKit user configuration\n"); err != nil {
+			return fmt.Errorf("failed to create config file: %w", err)
+		}
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = defaultEditor
+	}
+
+	// The editor may carry arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	// #nosec G204 - the editor is chosen by the user running the command
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", parts[0], err)
+	}
+
+	if _, err := config.ReadLayer(path); err != nil {
+		utils.Warning("The edited config is not valid, run --config-edit again to fix it")
+		return err
+	}
+	utils.Success("Config saved to %s", path)
+	return nil
+}
+
+func (m *Manager) validateConfig() error {
+	cfg, err := m.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	files := cfg.Files()
+	if len(files) == 0 {
+		utils.Success("Configuration is valid (built-in defaults only)")
+		return nil
+	}
+	for _, file := range files {
+		utils.Info("Checked %s", file)
+	}
+	utils.Success("Configuration is valid")
+	return nil
+}
@@ -33,26 +33,79 @@ func ShowUsageTable() error {
 	fmt.Printf("  %-20s %s\n", "-n, --name", "Project name (required)")
 	fmt.Printf("  %-20s %s\n", "-f, --framework", "Framework to use")
 	fmt.Printf("  %-20s %s\n", "-b, --build-tool", "Build tool to use")
-	fmt.Printf("  %-20s %s\n\n", "-o, --output", "Output directory")
+	fmt.Printf("  %-20s %s\n", "-o, --output", "Output directory, or - for stdout")
+	fmt.Printf("  %-20s %s\n", "--output-format", "dir, tar, tar.gz or zip (default: dir)")
+	fmt.Printf("  %-20s %s\n", "--features", "Add features, e.g. javascript,testing")
+	fmt.Printf("  %-20s %s\n\n", "--without", "Leave out default features, e.g. eslint,git")
 
 	fmt.Printf("%s\n", utils.ColorizeString("INFORMATION OPTIONS", "yellow"))
 	fmt.Printf("  %-20s %s\n", "-l, --list-frameworks", "List supported frameworks")
 	fmt.Printf("  %-20s %s\n", "-t, --list-build-tools", "List supported build tools")
-	fmt.Printf("  %-20s %s\n\n", "-v, --version", "Show version")
+	fmt.Printf("  %-20s %s\n", "-v, --version", "Show version")
+	fmt.Printf("  %-20s %s\n\n", "--explain-plugins", "Explain plugin selection for -f/-b")
 
 	fmt.Printf("%s\n", utils.ColorizeString("PROJECT INITIALIZATION", "yellow"))
 	fmt.Printf("  %-20s %s\n\n", "-i, --init", "Initialize new project (with banner)")
 
+	fmt.Printf("%s\n", utils.ColorizeString("CONFIGURATION OPTIONS", "yellow"))
+	fmt.Printf("  %-20s %s\n", "--config-get", "Print the effective value of a key")
+	fmt.Printf("  %-20s %s\n", "--config-set", "Set key=value in the global config")
+	fmt.Printf("  %-20s %s\n", "--config-list", "List effective config values")
+	fmt.Printf("  %-20s %s\n", "--show-origin", "Show the layer behind each value")
+	fmt.Printf("  %-20s %s\n", "--config-edit", "Edit the global config in $EDITOR")
+	fmt.Printf("  %-20s %s\n\n", "--config-validate", "Validate all config files")
+
+	fmt.Printf("%s\n", utils.ColorizeString("TEMPLATE ANSWERS", "yellow"))
+	fmt.Printf("  %-20s %s\n", "--set", "Answer a question, key=value (repeatable)")
+	fmt.Printf("  %-20s %s\n", "--values", "Read answers from a YAML or JSON file")
+	fmt.Printf("  %-20s %s\n", "--no-input", "Never prompt; list missing answers")
+	fmt.Printf("  %-20s %s\n\n", "", "--set beats --values, which beats prompts")
+
+	fmt.Printf("%s\n", utils.ColorizeString("RECIPES", "yellow"))
+	fmt.Printf("  %-20s %s\n", "--from", "Generate from a recipe without prompts")
+	fmt.Printf("  %-20s %s\n\n", "--save-recipe", "Save settings and answers as a recipe")
+
+	fmt.Printf("%s\n", utils.ColorizeString("UPGRADES", "yellow"))
+	fmt.Printf("  %-20s %s\n", "--upgrade-project", "Three-way merge new templates into a project")
+	fmt.Printf("  %-20s %s\n\n", "", "Needs a clean git worktree unless --force")
+
+	fmt.Printf("%s\n", utils.ColorizeString("EXISTING PROJECTS", "yellow"))
+	fmt.Printf("  %-20s %s\n", "--add", "Add features to an existing project")
+	fmt.Printf("  %-20s %s\n\n", "--project", "Project directory for --add (default: .)")
+
+	fmt.Printf("%s\n", utils.ColorizeString("PLUGINS", "yellow"))
+	fmt.Printf("  %-20s %s\n", "--plugin-install", "Install from a directory, archive or git URL")
+	fmt.Printf("  %-20s %s\n", "--plugin-list", "List installed plugins")
+	fmt.Printf("  %-20s %s\n", "--plugin-remove", "Remove an installed plugin")
+	fmt.Printf("  %-20s %s\n\n", "--plugin-info", "Show a plugin's manifest and source")
+
+	fmt.Printf("%s\n", utils.ColorizeString("SERVER", "yellow"))
+	fmt.Printf("  %-20s %s\n", "--serve", "Serve the generation API, e.g. :8080")
+	fmt.Printf("  %-20s %s\n", "--serve-timeout", "Time limit per request (default: 30s)")
+	fmt.Printf("  %-20s %s\n\n", "--serve-concurrency", "Projects generated at once (default: 4)")
+
 	fmt.Printf("%s\n", utils.ColorizeString("OTHER OPTIONS", "yellow"))
 	fmt.Printf("  %-20s %s\n", "-q, --quiet", "Quiet mode")
 	fmt.Printf("  %-20s %s\n", "-F, --force", "Force overwrite")
+	fmt.Printf("  %-20s %s\n", "--skip-plugin", "Skip a plugin by name (repeatable)")
+	fmt.Printf("  %-20s %s\n", "--dry-run", "Show planned files and diffs without writing")
+	fmt.Printf("  %-20s %s\n", "--seed", "Seed generated UUIDs and secrets")
 	fmt.Printf("  %-20s %s\n", "-u, --update", "Update to latest version")
 	fmt.Printf("  %-20s %s\n\n", "-h, --help", "Show this help")
 
 	fmt.Printf("%s\n", utils.ColorizeString("EXAMPLES", "yellow"))
 	fmt.Printf("  %s\n", utils.ColorizeString("tilokit -i", "green"))
 	fmt.Printf("  %s\n", utils.ColorizeString("tilokit -n my-app -f react -b vite", "green"))
+	fmt.Printf("  %s\n", utils.ColorizeString("tilokit -n my-app -f vue --features javascript --without prettier", "green"))
+	fmt.Printf("  %s\n", utils.ColorizeString("tilokit -n my-app -f react --output-format zip -o - > my-app.zip", "green"))
+	fmt.Printf("  %s\n", utils.ColorizeString("tilokit -n my-api -f django --no-input --set database=sqlite", "green"))
+	fmt.Printf("  %s\n", utils.ColorizeString("tilokit --from api.recipe.yaml -n billing-api", "green"))
+	fmt.Printf("  %s\n", utils.ColorizeString("tilokit --upgrade-project services/billing-api", "green"))
+	fmt.Printf("  %s\n", utils.ColorizeString("tilokit --add docker,ci --project services/web", "green"))
+	fmt.Printf("  %s\n", utils.ColorizeString("tilokit --plugin-install file:///src/tilokit-acme", "green"))
+	fmt.Printf("  %s\n", utils.ColorizeString("tilokit --serve :8080", "green"))
 	fmt.Printf("  %s\n", utils.ColorizeString("tilokit --list-frameworks", "green"))
+	fmt.Printf("  %s\n", utils.ColorizeString("tilokit --config-list --show-origin", "green"))
 	fmt.Printf("  %s\n", utils.ColorizeString("tilokit --version", "green"))
 	fmt.Printf("  %s\n\n", utils.ColorizeString("tilokit --update", "green"))
 
@@ -2,8 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/ti-lo/tilokit/internal/plugins/external"
+	"github.com/ti-lo/tilokit/internal/server"
 	"github.com/ti-lo/tilokit/internal/utils"
 	"github.com/ti-lo/tilokit/pkg/constants"
 )
@@ -15,6 +19,7 @@ type Manager struct {
 	Framework      string
 	BuildTool      string
 	OutputDir      string
+	OutputFormat   string
 	ListFrameworks bool
 	ListBuildTools bool
 	ShowVersion    bool
@@ -22,6 +27,58 @@ type Manager struct {
 	Force          bool
 	Update         bool
 	InitProject    bool
+	ExplainPlugins bool
+	DryRun         bool
+	SkipPlugins    []string
+	Seed           int64
+	Features       []string
+	Without        []string
+
+	// Template answer flags
+	SetValues   []string
+	ValuesFiles []string
+	NoInput     bool
+
+	// Recipe flags
+	FromRecipe string
+	SaveRecipe string
+
+	// UpgradeProject is the directory of a generated project to upgrade
+	UpgradeProject string
+
+	// Existing project flags
+	AddFeatures []string
+	Project     string
+
+	// Server flags
+	Serve            string
+	ServeTimeout     time.Duration
+	ServeConcurrency int
+
+	// Plugin store flags
+	PluginInstall string
+	PluginList    bool
+	PluginRemove  string
+	PluginInfo    string
+
+	// Configuration management flags
+	ConfigGet      string
+	ConfigSet      string
+	ConfigList     bool
+	ShowOrigin     bool
+	ConfigEdit     bool
+	ConfigValidate bool
+
+	// configFlags holds flag values that override configuration keys
+	configFlags map[string]interface{}
+	// toStdout is set when -o - streams the archive to stdout
+	toStdout bool
+	// archiveFile is the archive -o names, e.g. -o out.zip with
+	// --output-format zip
+	archiveFile string
+	// startedPlugins holds the external plugins started for this run
+	startedPlugins []*external.Plugin
+	pluginsStarted bool
 }
 
 // NewManager creates a new CLI manager
@@ -33,7 +90,21 @@ func NewManager() *Manager {
 func (m *Manager) HasAnyFlags(cmd *cobra.Command) bool {
 	return m.ProjectName != "" || m.Framework != "" || m.BuildTool != "" ||
 		m.ListFrameworks || m.ListBuildTools || m.Update || m.Quiet ||
-		m.Force || m.ShowVersion || m.InitProject
+		m.Force || m.ShowVersion || m.InitProject || m.ExplainPlugins || m.DryRun ||
+		m.HasConfigFlags() || m.ShowOrigin || m.hasAnswerFlags() || m.UpgradeProject != "" ||
+		len(m.AddFeatures) > 0 || len(m.Features) > 0 || len(m.Without) > 0 ||
+		len(m.SkipPlugins) > 0 || m.Seed != 0 || m.Serve != "" || m.hasPluginFlags()
+}
+
+// hasPluginFlags checks if a plugin store command was given
+func (m *Manager) hasPluginFlags() bool {
+	return m.PluginInstall != "" || m.PluginList || m.PluginRemove != "" || m.PluginInfo != ""
+}
+
+// hasAnswerFlags checks if template answers, a recipe or --no-input were
+// given
+func (m *Manager) hasAnswerFlags() bool {
+	return len(m.SetValues) > 0 || len(m.ValuesFiles) > 0 || m.NoInput || m.FromRecipe != ""
 }
 
 // HandleCommand processes the main command logic
@@ -43,6 +114,14 @@ func (m *Manager) HandleCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(constants.InvalidCommandMsg, args[0])
 	}
 
+	defer m.stopExternalPlugins()
+
+	// Flags set explicitly form the top configuration layer
+	m.configFlags = make(map[string]interface{})
+	if cmd.Flags().Changed("output") {
+		m.configFlags["defaults.output_dir"] = m.OutputDir
+	}
+
 	// Handle version flag first
 	if m.ShowVersion {
 		return ShowVersionInfo()
@@ -66,8 +145,42 @@ func (m *Manager) HandleCommand(cmd *cobra.Command, args []string) error {
 		return m.ListSupportedBuildTools()
 	}
 
+	if m.ExplainPlugins {
+		return m.ExplainPluginSelection()
+	}
+
+	if m.HasConfigFlags() || m.ShowOrigin {
+		return m.RunConfigCommand()
+	}
+
+	switch {
+	case m.PluginInstall != "":
+		return m.InstallPlugin()
+	case m.PluginRemove != "":
+		return m.RemovePlugin()
+	case m.PluginList:
+		return m.ListInstalledPlugins()
+	case m.PluginInfo != "":
+		return m.ShowPluginInfo()
+	}
+
+	if m.UpgradeProject != "" {
+		return m.RunProjectUpgrade()
+	}
+
+	if len(m.AddFeatures) > 0 {
+		return m.RunAddFeatures()
+	}
+
+	if m.Serve != "" {
+		return m.RunServer()
+	}
+	if m.Project != "" {
+		return errors.New("--project needs --add with the features to add")
+	}
+
 	// If project creation flags provided, run generation without banner
-	if m.ProjectName != "" || m.Framework != "" {
+	if m.ProjectName != "" || m.Framework != "" || m.hasAnswerFlags() {
 		return m.RunGenerate()
 	}
 
@@ -100,20 +213,62 @@ func (m *Manager) SetupFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&m.ProjectName, "name", "n", "", "Project name (required)")
 	cmd.Flags().StringVarP(&m.Framework, "framework", "f", "", "Framework to use (react, vue, svelte, etc.)")
 	cmd.Flags().StringVarP(&m.BuildTool, "build-tool", "b", "", "Build tool to use (vite, webpack, etc.)")
-	cmd.Flags().StringVarP(&m.OutputDir, "output", "o", ".", "Output directory")
+	cmd.Flags().StringVarP(&m.OutputDir, "output", "o", ".", "Output directory, the archive file such as out.zip with --output-format, or - to stream an archive to stdout")
+	cmd.Flags().StringVar(&m.OutputFormat, "output-format", "dir", "Write the project as a dir, tar, tar.gz or zip archive")
 
 	// Information flags
 	cmd.Flags().BoolVarP(&m.ListFrameworks, "list-frameworks", "l", false, "List all supported frameworks")
 	cmd.Flags().BoolVarP(&m.ListBuildTools, "list-build-tools", "t", false, "List all supported build tools")
 	cmd.Flags().BoolVarP(&m.ShowVersion, "version", "v", false, "Show version information")
+	cmd.Flags().BoolVar(&m.ExplainPlugins, "explain-plugins", false, "Explain which plugins are selected for a framework and build tool")
 
 	// Project initialization
 	cmd.Flags().BoolVarP(&m.InitProject, "init", "i", false, "Initialize a new project (with banner)")
 
+	// Configuration management
+	cmd.Flags().StringVar(&m.ConfigGet, "config-get", "", "Print the effective value of a config key, e.g. defaults.output_dir")
+	cmd.Flags().StringVar(&m.ConfigSet, "config-set", "", "Set a config key in the global config file (key=value)")
+	cmd.Flags().BoolVar(&m.ConfigList, "config-list", false, "List all effective config values")
+	cmd.Flags().BoolVar(&m.ShowOrigin, "show-origin", false, "Show which layer supplied each config value")
+	cmd.Flags().BoolVar(&m.ConfigEdit, "config-edit", false, "Open the global config file in $EDITOR")
+	cmd.Flags().BoolVar(&m.ConfigValidate, "config-validate", false, "Validate all config files")
+
+	// Template answers; --set wins over --values, and both win over prompts
+	cmd.Flags().StringArrayVar(&m.SetValues, "set", nil, "Answer a template question, e.g. --set use_celery=false (repeatable)")
+	cmd.Flags().StringArrayVar(&m.ValuesFiles, "values", nil, "Read template answers from a YAML or JSON file (repeatable)")
+	cmd.Flags().BoolVar(&m.NoInput, "no-input", false, "Never prompt; fail listing every required answer that is missing")
+
+	// Recipes
+	cmd.Flags().StringVar(&m.FromRecipe, "from", "", "Generate from a recipe file without prompting")
+	cmd.Flags().StringVar(&m.SaveRecipe, "save-recipe", "", "Save this run's settings and answers as a recipe file")
+
+	// Upgrades
+	cmd.Flags().StringVar(&m.UpgradeProject, "upgrade-project", "", "Merge the current templates into a generated project directory")
+
+	// Existing projects
+	cmd.Flags().StringSliceVar(&m.AddFeatures, "add", nil, "Add features to an existing project, e.g. --add docker,ci")
+	cmd.Flags().StringVar(&m.Project, "project", "", "Existing project directory for --add (default is the current directory)")
+
+	// Plugin store
+	cmd.Flags().StringVar(&m.PluginInstall, "plugin-install", "", "Install a plugin from a directory, executable, archive or git URL")
+	cmd.Flags().BoolVar(&m.PluginList, "plugin-list", false, "List the installed plugins")
+	cmd.Flags().StringVar(&m.PluginRemove, "plugin-remove", "", "Remove an installed plugin by name")
+	cmd.Flags().StringVar(&m.PluginInfo, "plugin-info", "", "Show a plugin's manifest and where it was installed from")
+
+	// Server
+	cmd.Flags().StringVar(&m.Serve, "serve", "", "Serve the generation API over HTTP on an address, e.g. :8080")
+	cmd.Flags().DurationVar(&m.ServeTimeout, "serve-timeout", server.DefaultTimeout, "Time limit for each generation request")
+	cmd.Flags().IntVar(&m.ServeConcurrency, "serve-concurrency", server.DefaultMaxConcurrent, "Number of projects generated at once")
+
 	// Other options
 	cmd.Flags().BoolVarP(&m.Quiet, "quiet", "q", false, "Quiet mode (suppress output)")
-	cmd.Flags().BoolVarP(&m.Force, "force", "F", false, "Force overwrite existing directory")
+	cmd.Flags().BoolVarP(&m.Force, "force", "F", false, "Force overwrite existing directory, or upgrade a project with uncommitted changes")
+	cmd.Flags().BoolVar(&m.DryRun, "dry-run", false, "Show the files that would be generated without writing them")
 	cmd.Flags().BoolVarP(&m.Update, "update", "u", false, "Update TiLoKit to the latest version")
+	cmd.Flags().Int64Var(&m.Seed, "seed", 0, "Seed for generated UUIDs and secrets, for reproducible output")
+	cmd.Flags().StringSliceVar(&m.Features, "features", nil, "Features to add, e.g. --features javascript,testing")
+	cmd.Flags().StringSliceVar(&m.Without, "without", nil, "Default features to leave out, e.g. --without eslint,git")
+	cmd.Flags().StringSliceVar(&m.SkipPlugins, "skip-plugin", nil, "Skip a plugin by name, e.g. git-integration (repeatable)")
 }
 
 // Placeholder methods - these will delegate to existing logic
@@ -168,6 +323,39 @@ func (m *Manager) ListSupportedBuildTools() error {
 	return nil
 }
 
+// ExplainPluginSelection prints why each registered plugin was or wasn't
+// selected for the requested framework and build tool
+func (m *Manager) ExplainPluginSelection() error {
+	if m.Framework == "" {
+		return fmt.Errorf("--explain-plugins requires --framework")
+	}
+	cfg, err := m.loadConfig()
+	if err != nil {
+		return err
+	}
+	if m.BuildTool == "" {
+		m.BuildTool = m.defaultBuildToolFor(cfg, m.Framework)
+	}
+
+	gen, err := m.newGenerator(nil)
+	if err != nil {
+		return err
+	}
+
+	projectConfig := cfg.CreateProjectConfig(m.ProjectName, m.Framework, m.BuildTool, m.OutputDir)
+	projectConfig.ExcludePlugins = m.SkipPlugins
+
+	utils.Info("🔌 Plugin selection for framework %s, build tool %s:", m.Framework, m.BuildTool)
+	for _, decision := range gen.Explain(projectConfig) {
+		mark := utils.ColorizeString("✘", "red")
+		if decision.Selected {
+			mark = utils.ColorizeString("✔", "green")
+		}
+		fmt.Printf("  %s %-24s %s\n", mark, decision.Plugin.Name(), utils.ColorizeString(decision.Reason, "gray"))
+	}
+	return nil
+}
+
 func (m *Manager) RunProjectGeneration() error {
 	return m.RunProjectGenerationProcess()
 }
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ti-lo/tilokit/internal/core/engine"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// planNode is a directory or file in the printed dry-run tree
+type planNode struct {
+	name     string
+	file     *engine.PlannedFile
+	children map[string]*planNode
+}
+
+// ShowPlan prints the file tree a dry run would create, followed by diffs
+// for files that already exist with different content
+func ShowPlan(plan *engine.Plan) error {
+	var newCount, modifiedCount int
+	root := &planNode{name: filepath.Base(plan.ProjectPath), children: map[string]*planNode{}}
+
+	for i := range plan.Files {
+		file := &plan.Files[i]
+		switch file.Status {
+		case engine.FileNew:
+			newCount++
+		case engine.FileModified:
+			modifiedCount++
+		}
+
+		node := root
+		parts := strings.Split(file.Path, "/")
+		for _, part := range parts[:len(parts)-1] {
+			child, exists := node.children[part]
+			if !exists {
+				child = &planNode{name: part, children: map[string]*planNode{}}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.children[parts[len(parts)-1]] = &planNode{name: parts[len(parts)-1], file: file}
+	}
+
+	utils.Info("📋 Dry run: %d files would be written to %s (%d new, %d modified)",
+		len(plan.Files), plan.ProjectPath, newCount, modifiedCount)
+	fmt.Printf("%s/\n", root.name)
+	printPlanNode(root, "")
+
+	for _, skipped := range plan.Skipped {
+		utils.Warning("Skipped %s (%s): %s", skipped.Step, skipped.Plugin, skipped.Reason)
+	}
+
+	for _, file := range plan.Files {
+		if file.Diff != "" {
+			fmt.Println()
+			fmt.Print(colorizeDiff(file.Diff))
+		}
+	}
+
+	return nil
+}
+
+func printPlanNode(node *planNode, indent string) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		child := node.children[name]
+		branch, nextIndent := "├── ", indent+"│   "
+		if i == len(names)-1 {
+			branch, nextIndent = "└── ", indent+"    "
+		}
+
+		if child.file == nil {
+			fmt.Printf("%s%s%s/\n", indent, branch, child.name)
+			printPlanNode(child, nextIndent)
+			continue
+		}
+
+		fmt.Printf("%s%s%s  %s  %s  %s\n", indent, branch, child.name,
+			utils.ColorizeString(formatSize(child.file.Size), "gray"),
+			utils.ColorizeString(child.file.Plugin, "cyan"),
+			statusLabel(child.file.Status))
+	}
+}
+
+func statusLabel(status engine.FileStatus) string {
+	switch status {
+	case engine.FileNew:
+		return utils.ColorizeString("new", "green")
+	case engine.FileModified:
+		return utils.ColorizeString("modified", "yellow")
+	default:
+		return utils.ColorizeString("unchanged", "gray")
+	}
+}
+
+func formatSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f KB", float64(size)/1024)
+}
+
+func colorizeDiff(diff string) string {
+	var out strings.Builder
+	for _, line := range utils.SplitLines(diff) {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			out.WriteString(utils.ColorizeString(line, "white"))
+		case strings.HasPrefix(line, "@@"):
+			out.WriteString(utils.ColorizeString(line, "cyan"))
+		case strings.HasPrefix(line, "+"):
+			out.WriteString(utils.ColorizeString(line, "green"))
+		case strings.HasPrefix(line, "-"):
+			out.WriteString(utils.ColorizeString(line, "red"))
+		default:
+			out.WriteString(line)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ti-lo/tilokit/internal/config"
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/plugins/external"
+	"github.com/ti-lo/tilokit/internal/plugins/store"
+	"github.com/ti-lo/tilokit/internal/utils"
+	"github.com/ti-lo/tilokit/pkg/tilokit"
+)
+
+// externalPlugins starts the plugins in ~/.tilokit/plugins, those
+// installed in the plugin store and those the config lists with a command,
+// once per run, granting each what its config
+// entry lists and passing it the entry's options. A plugin that fails to
+// start or is incompatible is reported and left out; options it doesn't
+// accept are reported too.
+func (m *Manager) externalPlugins() []tilokit.Plugin {
+	if !m.pluginsStarted {
+		m.pluginsStarted = true
+
+		commands, err := external.FindCommands(external.DefaultDir())
+		if err != nil {
+			utils.Warning("Skipping external plugins: %v", err)
+		}
+		installed, errs := store.Open(store.DefaultDir()).Commands()
+		for _, err := range errs {
+			utils.Warning("Skipping installed plugin: %v", err)
+		}
+		commands = append(commands, installed...)
+
+		opts := external.Options{CacheDir: wasmCacheDir()}
+		cfg, cfgErr := config.Load(config.LoadOptions{Flags: m.configFlags})
+		if cfgErr == nil {
+			commands = append(commands, configuredPluginCommands(cfg)...)
+			opts.Grants = func(name string) []string {
+				return cfg.Plugins[name].Grants
+			}
+			opts.PluginOptions = func(name string) map[string]interface{} {
+				return cfg.Plugins[name].Options
+			}
+		}
+
+		m.startedPlugins, errs = external.StartAll(commands, opts)
+		for _, err := range errs {
+			utils.Warning("Skipping external plugin: %v", err)
+		}
+		if cfgErr == nil {
+			for _, plugin := range m.startedPlugins {
+				if err := plugin.Manifest().CheckOptions(cfg.Plugins[plugin.Name()].Options); err != nil {
+					utils.Warning("%v", err)
+				}
+			}
+		}
+	}
+
+	plugins := make([]tilokit.Plugin, len(m.startedPlugins))
+	for i, plugin := range m.startedPlugins {
+		plugins[i] = plugin
+	}
+	return plugins
+}
+
+// stopExternalPlugins stops the plugins externalPlugins started
+func (m *Manager) stopExternalPlugins() {
+	for _, plugin := range m.startedPlugins {
+		plugin.Stop()
+	}
+	m.startedPlugins = nil
+}
+
+// configuredPluginCommands returns the enabled plugins of the config that
+// have a command, in name order. A leading ~/ is the home directory.
+func configuredPluginCommands(cfg *config.Config) []external.Command {
+	names := make([]string, 0, len(cfg.Plugins))
+	for name, plugin := range cfg.Plugins {
+		if plugin.Enabled && plugin.Command != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	commands := make([]external.Command, len(names))
+	for i, name := range names {
+		plugin := cfg.Plugins[name]
+		path := plugin.Command
+		if rest, found := strings.CutPrefix(path, "~/"); found {
+			path = filepath.Join(os.Getenv("HOME"), rest)
+		}
+		commands[i] = external.Command{Path: path, Args: plugin.Args}
+	}
+	return commands
+}
+
+// builtinPluginNames returns the names of the plugins shipped with TiLoKit
+func builtinPluginNames() (map[string]bool, error) {
+	builtin, err := tilokit.New(tilokit.Options{})
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, plugin := range builtin.Plugins() {
+		names[plugin.Name()] = true
+	}
+	return names, nil
+}
+
+// builtinPluginOptions returns the options the config gives built-in
+// plugins, overridden by those of a recipe. External plugins get theirs
+// when they start.
+func builtinPluginOptions(cfg *config.Config, recipe *tilocontext.ProjectConfig) (map[string]map[string]interface{}, error) {
+	names, err := builtinPluginNames()
+	if err != nil {
+		return nil, err
+	}
+	options := make(map[string]map[string]interface{})
+	for name, plugin := range cfg.Plugins {
+		if names[name] && len(plugin.Options) > 0 {
+			options[name] = plugin.Options
+		}
+	}
+	if recipe != nil {
+		for name, values := range recipe.PluginOptions {
+			options[name] = values
+		}
+	}
+	if len(options) == 0 {
+		return nil, nil
+	}
+	return options, nil
+}
+
+// wasmCacheDir is where WebAssembly plugins stay compiled between runs
+func wasmCacheDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".tilokit", "cache", "wasm")
+}
+
+// InstallPlugin installs a plugin into the plugin store from a directory,
+// executable, archive or git repository
+func (m *Manager) InstallPlugin() error {
+	names, err := builtinPluginNames()
+	if err != nil {
+		return err
+	}
+
+	entry, err := store.Open(store.DefaultDir()).Install(m.PluginInstall, store.InstallOptions{
+		Plugin:   external.Options{CacheDir: wasmCacheDir()},
+		Reserved: func(name string) bool { return names[name] },
+	})
+	if err != nil {
+		return err
+	}
+	utils.Success("Installed plugin %s %s", entry.Name, entry.Version)
+	printSupport(entry.Frameworks, entry.BuildTools)
+	return nil
+}
+
+// RemovePlugin removes a plugin from the plugin store
+func (m *Manager) RemovePlugin() error {
+	entry, err := store.Open(store.DefaultDir()).Remove(m.PluginRemove)
+	if err != nil {
+		return err
+	}
+	utils.Success("Removed plugin %s %s", entry.Name, entry.Version)
+	return nil
+}
+
+// ListInstalledPlugins prints the plugin store's catalogue
+func (m *Manager) ListInstalledPlugins() error {
+	entries, err := store.Open(store.DefaultDir()).List()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		utils.Info("No plugins installed. Install one with --plugin-install <path|git-url|archive>")
+		return nil
+	}
+
+	utils.Info("🔌 Installed plugins:")
+	for _, entry := range entries {
+		supported := strings.Join(append(append([]string{}, entry.Frameworks...), entry.BuildTools...), ", ")
+		fmt.Printf("  %-24s %-10s %s\n", entry.Name, entry.Version, utils.ColorizeString(supported, "gray"))
+	}
+	return nil
+}
+
+// ShowPluginInfo prints the manifest of a plugin, built-in, external or
+// installed, and where an installed one came from
+func (m *Manager) ShowPluginInfo() error {
+	gen, err := m.newGenerator(nil)
+	if err != nil {
+		return err
+	}
+	var plugin tilokit.Plugin
+	for _, p := range gen.Plugins() {
+		if p.Name() == m.PluginInfo {
+			plugin = p
+		}
+	}
+	entry, installed, err := store.Open(store.DefaultDir()).Get(m.PluginInfo)
+	if err != nil {
+		return err
+	}
+	if plugin == nil && !installed {
+		return fmt.Errorf("plugin %s not found; see --plugin-list and --explain-plugins", m.PluginInfo)
+	}
+
+	utils.Info("🔌 Plugin %s", m.PluginInfo)
+	if plugin != nil {
+		manifest := tilokit.ManifestOf(plugin)
+		printField("Version", manifest.Version)
+		printField("Description", manifest.Description)
+		printField("Plugin API", fmt.Sprintf("v%d", manifest.APIVersion))
+		printSupport(manifest.Frameworks, manifest.BuildTools)
+		printField("Capabilities", strings.Join(manifest.Capabilities, ", "))
+		for _, option := range manifest.Options {
+			text := string(option.Type)
+			if option.Default != nil {
+				text += fmt.Sprintf(" (default: %v)", option.Default)
+			}
+			if option.Description != "" {
+				text += " " + option.Description
+			}
+			printField("Option "+option.Name, text)
+		}
+	} else {
+		utils.Warning("Plugin %s is installed but didn't start; see the warnings above", entry.Name)
+		printField("Version", entry.Version)
+	}
+	if installed {
+		printField("Source", entry.Source)
+		printField("Installed", entry.Installed.Local().Format("2006-01-02 15:04"))
+		printField("Checksum", entry.Checksum)
+		printField("Location", store.Open(store.DefaultDir()).PluginDir(entry.Name))
+	}
+	return nil
+}
+
+func printSupport(frameworks, buildTools []string) {
+	printField("Frameworks", strings.Join(frameworks, ", "))
+	printField("Build tools", strings.Join(buildTools, ", "))
+}
+
+// printField prints a labelled value, skipping empty ones
+func printField(label, value string) {
+	if value != "" {
+		fmt.Printf("  %-14s %s\n", label+":", value)
+	}
+}
@@ -2,57 +2,171 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/ti-lo/tilokit/internal/config"
-	"github.com/ti-lo/tilokit/internal/core/engine"
-	"github.com/ti-lo/tilokit/internal/core/registry"
-	"github.com/ti-lo/tilokit/internal/plugins/builders"
-	"github.com/ti-lo/tilokit/internal/plugins/frameworks"
-	"github.com/ti-lo/tilokit/internal/plugins/tools"
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/plugins/templates"
 	"github.com/ti-lo/tilokit/internal/utils"
 	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/pkg/tilokit"
 )
 
 // RunProjectGenerationProcess handles the project generation logic
 func (m *Manager) RunProjectGenerationProcess() error {
-	// Load configuration
-	cfg, err := config.LoadConfig()
+	// Load configuration, with explicitly set flags taking precedence
+	cfg, err := config.Load(config.LoadOptions{Flags: m.configFlags})
 	if err != nil {
-		// Only continue with defaults for specific expected errors
-		if os.IsNotExist(err) {
-			utils.Info("No config file found, using defaults")
-			cfg = &config.Config{}
-		} else {
-			return fmt.Errorf("failed to load config: %w", err)
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	m.OutputDir = cfg.Defaults.OutputDir
+	if err := m.resolveOutputFormat(); err != nil {
+		return err
+	}
+
+	// A recipe fills in whatever flags leave out and never prompts
+	var recipe *tilocontext.ProjectConfig
+	if m.FromRecipe != "" {
+		if recipe, err = config.LoadRecipe(m.FromRecipe); err != nil {
+			return err
 		}
+		m.applyRecipe(recipe)
 	}
 
-	// Interactive prompts if values not provided
-	if err := m.promptForMissingValues(cfg); err != nil {
+	// Answers given up front are never prompted for
+	values, err := config.ResolveValues(m.ValuesFiles, m.SetValues)
+	if err != nil {
 		return err
 	}
+	if recipe != nil {
+		for name, value := range recipe.Variables {
+			if _, set := values[name]; !set {
+				values[name] = value
+			}
+		}
+	}
 
-	// Validate inputs
-	if err := m.validateInputs(); err != nil {
+	// Interactive prompts if values not provided; with --no-input, defaults
+	// are used and the rest is collected so it can be reported at once
+	missing, err := m.promptForMissingValues(cfg)
+	if err != nil {
 		return err
 	}
 
+	// Validate inputs
+	if len(missing) == 0 {
+		if err := m.validateInputs(); err != nil {
+			return err
+		}
+	}
+
 	// Create project configuration
-	projectConfig := config.CreateProjectConfig(m.ProjectName, m.Framework, m.BuildTool, m.OutputDir)
+	projectConfig := cfg.CreateProjectConfig(m.ProjectName, m.Framework, m.BuildTool, m.OutputDir)
+	projectConfig.ExcludePlugins = m.SkipPlugins
+	projectConfig.Seed = m.Seed
+	if recipe != nil && recipe.PackageManager != "" {
+		projectConfig.PackageManager = recipe.PackageManager
+	}
+	if projectConfig.PluginOptions, err = builtinPluginOptions(cfg, recipe); err != nil {
+		return err
+	}
+	for name, value := range values {
+		projectConfig.Variables[name] = value
+	}
+
+	// Features decide the language, tooling, git init and dependency install
+	if m.Framework != "" {
+		defaults := featureDefaults(cfg)
+		if recipe != nil {
+			defaults = recipeFeatureDefaults(recipe, m.Framework)
+		}
+		selection, err := features.Builtin().Resolve(m.Framework, m.Features, m.Without, defaults)
+		if err != nil {
+			return err
+		}
+		projectConfig.Features = selection.Features
+		projectConfig.GitInit = selection.Has(features.Git)
+		projectConfig.InstallDeps = selection.Has(features.InstallDeps)
+	}
 
-	// Initialize engine and register plugins
-	eng := engine.New()
-	if err := m.registerPlugins(eng); err != nil {
+	// Questions declared by the framework's template manifest
+	var ask templates.Asker = surveyAsker
+	if m.NoInput {
+		ask = nil
+	}
+	if err := m.askTemplateQuestions(projectConfig, ask); err != nil {
+		if missing, err = appendUnanswered(missing, err); err != nil {
+			return err
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("--no-input: missing required answers: %s", strings.Join(missing, ", "))
+	}
+
+	// Initialize the generator with every plugin; archives are rendered in
+	// memory, which skips git and other side effects
+	var fsys utils.FS
+	if m.writesArchive() {
+		fsys = utils.NewMemoryFS()
+	}
+	gen, err := m.newGenerator(fsys)
+	if err != nil {
 		return err
 	}
 
+	// Questions asked by the selected plugins themselves
+	if err := m.askPluginQuestions(gen, projectConfig, ask); err != nil {
+		if missing, err = appendUnanswered(missing, err); err != nil {
+			return err
+		}
+		return fmt.Errorf("--no-input: missing required answers: %s", strings.Join(missing, ", "))
+	}
+
+	if m.SaveRecipe != "" {
+		if err := config.SaveRecipe(projectConfig, m.SaveRecipe); err != nil {
+			return err
+		}
+		utils.Info("Recipe saved to %s", m.SaveRecipe)
+	}
+
+	// Ctrl-C or SIGTERM cancels the run; the engine rolls back staged output
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Dry run renders in memory and prints the plan instead
+	if m.DryRun {
+		plan, err := gen.Plan(ctx, projectConfig)
+		if errors.Is(err, context.Canceled) {
+			utils.Warning("Dry run cancelled")
+			return err
+		}
+		if err != nil {
+			utils.Error("Dry run failed: %v", err)
+			return err
+		}
+		return ShowPlan(plan)
+	}
+
+	if m.writesArchive() {
+		return m.writeArchive(ctx, gen, fsys, projectConfig)
+	}
+
 	// Execute project generation
-	ctx := context.Background()
-	if err := eng.Execute(ctx, projectConfig); err != nil {
+	if _, err := gen.Generate(ctx, projectConfig); err != nil {
+		if errors.Is(err, context.Canceled) {
+			utils.Warning("Project generation cancelled, no files were written")
+			return err
+		}
 		utils.Error("Project generation failed: %v", err)
 		return err
 	}
@@ -82,7 +196,11 @@ func (m *Manager) RunProjectGenerationProcess() error {
 	return nil
 }
 
-func (m *Manager) promptForMissingValues(cfg *config.Config) error {
+func (m *Manager) promptForMissingValues(cfg *config.Config) ([]string, error) {
+	if m.NoInput {
+		return m.defaultMissingValues(cfg), nil
+	}
+
 	// Project name
 	if m.ProjectName == "" {
 		prompt := &survey.Input{
@@ -90,7 +208,7 @@ func (m *Manager) promptForMissingValues(cfg *config.Config) error {
 			Help:    "Enter the name for your new project",
 		}
 		if err := survey.AskOne(prompt, &m.ProjectName, survey.WithValidator(survey.Required)); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -100,10 +218,10 @@ func (m *Manager) promptForMissingValues(cfg *config.Config) error {
 		prompt := &survey.Select{
 			Message: "🚀 Choose framework:",
 			Options: supportedFrameworks,
-			Default: cfg.DefaultFramework,
+			Default: cfg.Defaults.Framework,
 		}
 		if err := survey.AskOne(prompt, &m.Framework); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -114,16 +232,13 @@ func (m *Manager) promptForMissingValues(cfg *config.Config) error {
 			prompt := &survey.Select{
 				Message: "🔧 Choose build tool:",
 				Options: supportedBuildTools,
-				Default: supportedBuildTools[0],
+				Default: m.defaultBuildToolFor(cfg, m.Framework),
 			}
 			if err := survey.AskOne(prompt, &m.BuildTool); err != nil {
-				return err
+				return nil, err
 			}
-		} else if len(supportedBuildTools) == 1 {
-			m.BuildTool = supportedBuildTools[0]
 		} else {
-			// Use framework-appropriate default
-			m.BuildTool = m.getDefaultBuildTool(m.Framework)
+			m.BuildTool = m.defaultBuildToolFor(cfg, m.Framework)
 		}
 	}
 
@@ -132,7 +247,100 @@ func (m *Manager) promptForMissingValues(cfg *config.Config) error {
 		m.OutputDir = "."
 	}
 
-	return nil
+	return nil, nil
+}
+
+// defaultMissingValues fills in what --no-input can't ask for from the
+// configuration and returns the values that have no default
+func (m *Manager) defaultMissingValues(cfg *config.Config) []string {
+	var missing []string
+	if m.ProjectName == "" {
+		missing = append(missing, "name (--name)")
+	}
+	if m.Framework == "" {
+		m.Framework = cfg.Defaults.Framework
+	}
+	if m.Framework == "" {
+		missing = append(missing, "framework (--framework)")
+	} else if m.BuildTool == "" {
+		m.BuildTool = m.defaultBuildToolFor(cfg, m.Framework)
+	}
+	if m.OutputDir == "" {
+		m.OutputDir = "."
+	}
+	return missing
+}
+
+// defaultBuildToolFor picks the build tool offered first for a framework:
+// the configured one if the framework supports it
+func (m *Manager) defaultBuildToolFor(cfg *config.Config, framework string) string {
+	supportedBuildTools := m.getBuildToolsForFramework(framework)
+	switch {
+	case len(supportedBuildTools) > 1:
+		if configured := cfg.DefaultBuildToolFor(framework); slices.Contains(supportedBuildTools, configured) {
+			return configured
+		}
+		return supportedBuildTools[0]
+	case len(supportedBuildTools) == 1:
+		return supportedBuildTools[0]
+	default:
+		// Use framework-appropriate default
+		return m.getDefaultBuildTool(framework)
+	}
+}
+
+// featureDefaults returns the configured feature defaults. The git and
+// install-deps features default to the git_init and install_deps settings.
+func featureDefaults(cfg *config.Config) map[string]bool {
+	defaults := map[string]bool{
+		features.Git:         cfg.Defaults.GitInit,
+		features.InstallDeps: cfg.Defaults.InstallDeps,
+	}
+	for name, enabled := range cfg.Features {
+		defaults[name] = enabled
+	}
+	return defaults
+}
+
+// recipeFeatureDefaults makes a recipe's features the defaults, so
+// --features and --without still adjust them. A recipe without a features
+// list keeps the built-in defaults; git and install-deps always follow
+// git_init and install_deps.
+func recipeFeatureDefaults(recipe *tilocontext.ProjectConfig, framework string) map[string]bool {
+	defaults := make(map[string]bool)
+	if recipe.Features != nil {
+		for _, feature := range features.Builtin().Available(framework) {
+			defaults[feature.Name] = utils.Contains(recipe.Features, feature.Name)
+		}
+	}
+	defaults[features.Git] = recipe.GitInit
+	defaults[features.InstallDeps] = recipe.InstallDeps
+	return defaults
+}
+
+// applyRecipe fills the settings flags didn't give from a recipe
+func (m *Manager) applyRecipe(recipe *tilocontext.ProjectConfig) {
+	m.NoInput = true
+	if m.ProjectName == "" {
+		m.ProjectName = recipe.ProjectName
+	}
+	if m.Framework == "" {
+		m.Framework = recipe.Framework
+	}
+	if m.BuildTool == "" {
+		m.BuildTool = recipe.BuildTool
+	}
+	if _, set := m.configFlags["defaults.output_dir"]; !set && recipe.OutputDir != "" {
+		m.OutputDir = recipe.OutputDir
+	}
+	if m.Seed == 0 {
+		m.Seed = recipe.Seed
+	}
+	for _, plugin := range recipe.ExcludePlugins {
+		if !utils.Contains(m.SkipPlugins, plugin) {
+			m.SkipPlugins = append(m.SkipPlugins, plugin)
+		}
+	}
 }
 
 func (m *Manager) validateInputs() error {
@@ -146,87 +354,15 @@ func (m *Manager) validateInputs() error {
 		projectPath = filepath.Join(m.OutputDir, m.ProjectName)
 	}
 
-	if utils.DirExists(projectPath) && !m.Force {
+	// A dry run diffs against an existing directory instead of refusing it,
+	// and an archive never touches it
+	if utils.DirExists(projectPath) && !m.Force && !m.DryRun && !m.writesArchive() {
 		return fmt.Errorf("directory '%s' already exists. Use --force to overwrite", projectPath)
 	}
 
 	return nil
 }
 
-func (m *Manager) registerPlugins(eng *engine.Engine) error {
-	// Register plugins with error handling
-	plugins := []registry.Plugin{
-		// JavaScript/TypeScript Frameworks
-		frameworks.NewReactPlugin(),
-		frameworks.NewVuePlugin(),
-		// More JS frameworks can be added here
-
-		// Backend Frameworks
-		// Python
-		frameworks.NewPythonDjangoPlugin(),
-		frameworks.NewPythonFlaskPlugin(),
-		frameworks.NewPythonFastAPIPlugin(),
-
-		// PHP
-		frameworks.NewPHPLaravelPlugin(),
-		frameworks.NewPHPSymfonyPlugin(),
-
-		// Java
-		frameworks.NewJavaSpringBootPlugin(),
-		frameworks.NewJavaQuarkusPlugin(),
-
-		// Go
-		frameworks.NewGoGinPlugin(),
-		frameworks.NewGoEchoPlugin(),
-		frameworks.NewGoFiberPlugin(),
-
-		// Rust
-		frameworks.NewRustActixPlugin(),
-		frameworks.NewRustRocketPlugin(),
-		frameworks.NewRustAxumPlugin(),
-
-		// C#
-		frameworks.NewCSharpASPNetCorePlugin(),
-		frameworks.NewCSharpBlazorPlugin(),
-
-		// Ruby
-		frameworks.NewRubyRailsPlugin(),
-		frameworks.NewRubySinatraPlugin(),
-
-		// Node.js
-		frameworks.NewNodeExpressPlugin(),
-		frameworks.NewNodeNestJSPlugin(),
-		frameworks.NewNodeFastifyPlugin(),
-
-		// Mobile Frameworks
-		frameworks.NewReactNativePlugin(),
-		frameworks.NewFlutterPlugin(),
-		frameworks.NewIonicPlugin(),
-
-		// Desktop Frameworks
-		frameworks.NewElectronPlugin(),
-		frameworks.NewTauriPlugin(),
-		frameworks.NewWailsPlugin(),
-
-		// Build Tools
-		builders.NewVitePlugin(),
-		builders.NewWebpackPlugin(),
-		builders.NewRollupPlugin(),
-
-		// Tools
-		tools.NewGitPlugin(),
-	}
-
-	// Register all plugins with error handling
-	for _, plugin := range plugins {
-		if err := eng.RegisterPlugin(plugin); err != nil {
-			return fmt.Errorf("failed to register plugin %s: %w", plugin.Name(), err)
-		}
-	}
-
-	return nil
-}
-
 func (m *Manager) getBuildToolsForFramework(framework string) []string {
 	buildToolMap := map[string][]string{
 		"react":   {"vite", "webpack", "rollup"},
@@ -237,10 +373,8 @@ func (m *Manager) getBuildToolsForFramework(framework string) []string {
 		"nuxt":    {"nuxt"},
 	}
 
-	if tools, exists := buildToolMap[framework]; exists {
-		return tools
-	}
-	return []string{"vite"}
+	// Other frameworks have a single build tool, see getDefaultBuildTool
+	return buildToolMap[framework]
 }
 
 func (m *Manager) getDefaultBuildTool(framework string) string {
@@ -264,3 +398,13 @@ func (m *Manager) getDefaultBuildTool(framework string) string {
 	}
 	return "vite" // fallback for JS frameworks
 }
+
+// newGenerator returns a generator with the built-in and external plugins,
+// writing projects to fsys or to disk when fsys is nil
+func (m *Manager) newGenerator(fsys utils.FS) (*tilokit.Generator, error) {
+	return tilokit.New(tilokit.Options{
+		FS:            fsys,
+		Plugins:       m.externalPlugins(),
+		TemplateCache: templates.DefaultRevisionDir(),
+	})
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/AlecAivazis/survey/v2"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/plugins/templates"
+	"github.com/ti-lo/tilokit/pkg/tilokit"
+	shipped "github.com/ti-lo/tilokit/templates"
+)
+
+// askTemplateQuestions asks the questions declared by the framework's
+// template manifest and stores the answers as project variables
+func (m *Manager) askTemplateQuestions(projectConfig *tilocontext.ProjectConfig, ask templates.Asker) error {
+	id, found := templates.FindShippedTemplate(projectConfig.Framework)
+	if !found {
+		return nil
+	}
+	projectConfig.Template = id
+
+	manifest, err := templates.LoadManifest(shipped.FS, id)
+	if err != nil {
+		return err
+	}
+	if len(manifest.Questions) == 0 {
+		return nil
+	}
+
+	// Answers are resolved against the same variables templates will see
+	ctx := tilocontext.NewExecutionContext(projectConfig)
+	answers, err := templates.NewTemplateEngine().Answer(manifest.Questions, ctx, ask)
+	if err != nil {
+		return err
+	}
+
+	if projectConfig.Variables == nil {
+		projectConfig.Variables = make(map[string]interface{})
+	}
+	for name, value := range answers {
+		projectConfig.Variables[name] = value
+	}
+	return nil
+}
+
+// askPluginQuestions asks the questions of the plugins selected for the
+// project and stores the answers as project variables
+func (m *Manager) askPluginQuestions(gen *tilokit.Generator, projectConfig *tilocontext.ProjectConfig, ask templates.Asker) error {
+	pluginQuestions, err := gen.Questions(projectConfig)
+	if err != nil || len(pluginQuestions) == 0 {
+		return err
+	}
+
+	questions := make([]templates.Question, len(pluginQuestions))
+	for i, q := range pluginQuestions {
+		questions[i] = templates.Question{
+			Name:     q.Name,
+			Type:     templates.QuestionType(q.Type),
+			Message:  q.Message,
+			Help:     q.Help,
+			Default:  q.Default,
+			Choices:  q.Choices,
+			Required: q.Required,
+		}
+		if questions[i].Type == "" {
+			questions[i].Type = templates.QuestionString
+		}
+	}
+
+	ctx := tilocontext.NewExecutionContext(projectConfig)
+	answers, err := templates.NewTemplateEngine().Answer(questions, ctx, ask)
+	if projectConfig.Variables == nil {
+		projectConfig.Variables = make(map[string]interface{})
+	}
+	for name, value := range answers {
+		projectConfig.Variables[name] = value
+	}
+	return err
+}
+
+// appendUnanswered adds the questions a MissingAnswersError lists to
+// missing, returning any other error
+func appendUnanswered(missing []string, err error) ([]string, error) {
+	var unanswered *templates.MissingAnswersError
+	if !errors.As(err, &unanswered) {
+		return missing, err
+	}
+	for _, q := range unanswered.Questions {
+		missing = append(missing, fmt.Sprintf("%s (--set %s=...)", q.Name, q.Name))
+	}
+	return missing, nil
+}
+
+// surveyAsker asks a manifest question with the matching survey prompt
+func surveyAsker(q templates.Question, def interface{}) (interface{}, error) {
+	message := q.Prompt()
+
+	switch q.Type {
+	case templates.QuestionBool:
+		answer := false
+		if b, ok := def.(bool); ok {
+			answer = b
+		}
+		prompt := &survey.Confirm{Message: message, Help: q.Help, Default: answer}
+		err := survey.AskOne(prompt, &answer)
+		return answer, err
+
+	case templates.QuestionChoice:
+		var answer string
+		prompt := &survey.Select{Message: message, Help: q.Help, Options: q.Choices}
+		if def != nil {
+			prompt.Default = fmt.Sprint(def)
+		}
+		err := survey.AskOne(prompt, &answer)
+		return answer, err
+
+	case templates.QuestionMultiChoice:
+		var answer []string
+		prompt := &survey.MultiSelect{Message: message, Help: q.Help, Options: q.Choices}
+		if def != nil {
+			prompt.Default = def
+		}
+		err := survey.AskOne(prompt, &answer)
+		return answer, err
+
+	default:
+		var answer string
+		prompt := &survey.Input{Message: message, Help: q.Help}
+		if def != nil {
+			prompt.Default = fmt.Sprint(def)
+		}
+		err := survey.AskOne(prompt, &answer, survey.WithValidator(questionValidator(q)))
+		return answer, err
+	}
+}
+
+// questionValidator checks typed input while the user is still at the prompt
+func questionValidator(q templates.Question) survey.Validator {
+	var pattern *regexp.Regexp
+	if q.Validate != "" {
+		pattern = regexp.MustCompile(q.Validate)
+	}
+
+	return func(answer interface{}) error {
+		value, _ := answer.(string)
+		if q.Required && value == "" {
+			return fmt.Errorf("a value is required")
+		}
+		if q.Type == templates.QuestionInt {
+			if _, err := strconv.Atoi(value); err != nil {
+				return fmt.Errorf("enter a whole number")
+			}
+		}
+		if pattern != nil && !pattern.MatchString(value) {
+			return fmt.Errorf("must match %s", q.Validate)
+		}
+		return nil
+	}
+}
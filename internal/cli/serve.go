@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ti-lo/tilokit/internal/config"
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/server"
+	"github.com/ti-lo/tilokit/internal/utils"
+	"github.com/ti-lo/tilokit/pkg/tilokit"
+)
+
+// RunServer serves the generation API on the --serve address until
+// interrupted
+func (m *Manager) RunServer() error {
+	cfg, err := config.Load(config.LoadOptions{Flags: m.configFlags})
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	srv, err := server.New(server.Options{
+		Generator: tilokit.Options{Plugins: m.externalPlugins()},
+		Defaults: func() *tilocontext.ProjectConfig {
+			return &tilocontext.ProjectConfig{
+				GitInit:     cfg.Defaults.GitInit,
+				InstallDeps: cfg.Defaults.InstallDeps,
+			}
+		},
+		Complete: func(projectConfig *tilocontext.ProjectConfig) error {
+			return m.completeProjectConfig(cfg, projectConfig)
+		},
+		Timeout:       m.ServeTimeout,
+		MaxConcurrent: m.ServeConcurrency,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	utils.Info("🌐 Serving the generation API on %s", m.Serve)
+	if err := srv.ListenAndServe(ctx, m.Serve); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// completeProjectConfig fills in a generation request the way --from fills
+// in a recipe: the build tool and package manager, the features and the
+// template answers are defaulted from the configuration, and nothing is
+// prompted for
+func (m *Manager) completeProjectConfig(cfg *config.Config, projectConfig *tilocontext.ProjectConfig) error {
+	if projectConfig.Version == 0 {
+		projectConfig.Version = config.RecipeVersion
+	}
+	if err := config.ValidateRecipe(projectConfig); err != nil {
+		return err
+	}
+
+	if projectConfig.BuildTool == "" {
+		projectConfig.BuildTool = m.defaultBuildToolFor(cfg, projectConfig.Framework)
+	}
+	if projectConfig.PackageManager == "" {
+		defaults := cfg.CreateProjectConfig(projectConfig.ProjectName, projectConfig.Framework, projectConfig.BuildTool, projectConfig.OutputDir)
+		projectConfig.PackageManager = defaults.PackageManager
+	}
+
+	// The request's features, if given, replace the configured ones
+	defaults := featureDefaults(cfg)
+	for name, enabled := range recipeFeatureDefaults(projectConfig, projectConfig.Framework) {
+		defaults[name] = enabled
+	}
+	selection, err := features.Builtin().Resolve(projectConfig.Framework, nil, nil, defaults)
+	if err != nil {
+		return err
+	}
+	projectConfig.Features = selection.Features
+	projectConfig.GitInit = selection.Has(features.Git)
+	projectConfig.InstallDeps = selection.Has(features.InstallDeps)
+
+	return m.askTemplateQuestions(projectConfig, nil)
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ti-lo/tilokit/internal/core/upgrade"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// RunProjectUpgrade merges the current templates into a generated project
+func (m *Manager) RunProjectUpgrade() error {
+	utils.SetQuiet(m.Quiet)
+
+	gen, err := m.newGenerator(nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := gen.Upgrade(ctx, m.UpgradeProject, m.Force)
+	if err != nil {
+		utils.Error("Project upgrade failed: %v", err)
+		return err
+	}
+
+	ShowUpgradeResult(result)
+	return nil
+}
+
+// ShowUpgradeResult prints the files an upgrade touched and what to do about
+// conflicts
+func ShowUpgradeResult(result *upgrade.Result) {
+	clean := result.Count(upgrade.StatusClean)
+	merged := result.Count(upgrade.StatusMerged)
+	conflicted := result.Count(upgrade.StatusConflicted)
+
+	if len(result.Files) == 0 {
+		utils.Success("%s is up to date with TiLoKit %s", result.Dir, result.ToVersion)
+		return
+	}
+
+	utils.Info("⬆️  Upgraded %s from TiLoKit %s to %s: %d clean, %d merged, %d conflicted",
+		result.Dir, result.FromVersion, result.ToVersion, clean, merged, conflicted)
+	for _, file := range result.Files {
+		color := "green"
+		switch file.Status {
+		case upgrade.StatusMerged:
+			color = "cyan"
+		case upgrade.StatusConflicted:
+			color = "red"
+		}
+		line := fmt.Sprintf("  %s %s", utils.ColorizeString(fmt.Sprintf("%-10s", file.Status), color), file.Path)
+		if file.Note != "" {
+			line += " " + utils.ColorizeString("("+file.Note+")", "gray")
+		}
+		fmt.Println(line)
+	}
+
+	if conflicted > 0 {
+		utils.Warning("Resolve the conflicts, then review and commit the upgrade")
+		return
+	}
+	utils.Success("Review the changes and commit the upgrade")
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ti-lo/tilokit/internal/core/patch"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// Entry is a single effective configuration value
+type Entry struct {
+	Key    string
+	Value  interface{}
+	Origin Origin
+}
+
+// Get returns the effective value of a dotted key. Keys naming a section
+// return the whole section as a map.
+func (c *Config) Get(key string) (interface{}, error) {
+	tree, err := toMap(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{} = tree
+	for _, part := range strings.Split(key, ".") {
+		section, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unknown config key %q", key)
+		}
+		if value, ok = section[part]; !ok {
+			return nil, fmt.Errorf("unknown config key %q", key)
+		}
+	}
+	return value, nil
+}
+
+// Entries returns every effective value with the layer that set it, sorted
+// by key
+func (c *Config) Entries() ([]Entry, error) {
+	tree, err := toMap(c)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves := flatten(tree, "")
+	entries := make([]Entry, 0, len(leaves))
+	for _, key := range sortedKeys(leaves) {
+		entries = append(entries, Entry{Key: key, Value: leaves[key], Origin: c.Origin(key)})
+	}
+	return entries, nil
+}
+
+// SetValue sets a dotted key in the config file at path, editing the file's
+// YAML node tree so every other setting keeps its order and comments. The
+// value is parsed as a YAML scalar and the result is validated against the
+// schema before anything is saved.
+func SetValue(path, key, raw string) error {
+	if key == "" || strings.Contains(key, "..") || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+		return fmt.Errorf("invalid config key %q", key)
+	}
+
+	content := ""
+	if utils.FileExists(path) {
+		existing, err := utils.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read config file %s", path)
+		}
+		if _, err := parseLayer(path, existing); err != nil {
+			return err
+		}
+		content = existing
+	}
+
+	// The key becomes nested objects ending in the value, merged over the
+	// file's own value
+	parts := strings.Split(key, ".")
+	var value interface{} = parseScalar(raw)
+	for i := len(parts) - 1; i >= 0; i-- {
+		value = patch.Object{{Key: parts[i], Value: value}}
+	}
+	doc, err := patch.Parse(path, content)
+	if err != nil {
+		return err
+	}
+	if _, err := doc.Merge(value.(patch.Object), patch.Override); err != nil {
+		return err
+	}
+	data, err := doc.String()
+	if err != nil {
+		return err
+	}
+
+	if _, err := parseLayer(path, data); err != nil {
+		var fileErr *FileError
+		if errors.As(err, &fileErr) {
+			// Line numbers refer to the file as it would be saved, not to
+			// anything the user wrote, so only the problem itself is reported
+			return fmt.Errorf("cannot set %s: %s", key, lineNumber.ReplaceAllString(strings.Join(fileErr.Problems, "; "), "$1"))
+		}
+		return err
+	}
+
+	if err := utils.WriteFile(path, data); err != nil {
+		return errors.Wrap(err, "failed to write config file")
+	}
+	return nil
+}
+
+// lineNumber matches the position prefix of a schema problem
+var lineNumber = regexp.MustCompile(`(^|; )line \d+: `)
+
+// FormatValue renders a config value for display. Scalars print as-is and
+// sections print as YAML.
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return strings.TrimRight(string(data), "\n")
+	default:
+		return fmt.Sprint(v)
+	}
+}
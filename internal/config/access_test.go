@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetValueKeepsExistingSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tilokit.yaml")
+	writeConfig(t, path, "defaults:\n  package_manager: yarn\n")
+
+	if err := SetValue(path, "defaults.git_init", "false"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := SetValue(path, "frameworks.django.python_version", "3.12"); err != nil {
+		t.Fatalf("Expected framework options to be settable, got: %v", err)
+	}
+
+	cfg, err := Load(LoadOptions{GlobalFile: path, WorkDir: t.TempDir(), Environ: []string{}})
+	if err != nil {
+		t.Fatalf("Expected saved config to load, got: %v", err)
+	}
+	if cfg.Defaults.PackageManager != "yarn" {
+		t.Errorf("Expected package_manager to be kept, got %s", cfg.Defaults.PackageManager)
+	}
+	if cfg.Defaults.GitInit {
+		t.Error("Expected git_init to be parsed as boolean false")
+	}
+	if got := cfg.Frameworks["django"].Options["python_version"]; got != 3.12 {
+		t.Errorf("Expected python_version 3.12, got %v", got)
+	}
+}
+
+func TestSetValueKeepsCommentsAndOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tilokit.yaml")
+	writeConfig(t, path, `# My TiLoKit settings
+logging:
+  level: info # chatty while debugging
+defaults:
+  # where projects go
+  output_dir: ~/src
+  git_init: true
+`)
+
+	if err := SetValue(path, "defaults.git_init", "false"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := SetValue(path, "frameworks.react.default_build_tool", "webpack"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# My TiLoKit settings
+logging:
+  level: info # chatty while debugging
+defaults:
+  # where projects go
+  output_dir: ~/src
+  git_init: false
+frameworks:
+  react:
+    default_build_tool: webpack
+`
+	if string(data) != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, data)
+	}
+}
+
+func TestSetValueRejectsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tilokit.yaml")
+
+	err := SetValue(path, "defaults.colour", "blue")
+	if err == nil || !strings.Contains(err.Error(), `unknown key "defaults.colour"`) {
+		t.Fatalf("Expected unknown key error, got: %v", err)
+	}
+	if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+		t.Error("Expected no file to be written for an invalid key")
+	}
+}
+
+func TestGetAndEntries(t *testing.T) {
+	cfg, err := Load(LoadOptions{
+		GlobalFile: filepath.Join(t.TempDir(), "missing.yaml"),
+		WorkDir:    t.TempDir(),
+		Environ:    []string{"TILOKIT_LOGGING_LEVEL=debug"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := cfg.Get("logging.level")
+	if err != nil || value != "debug" {
+		t.Fatalf("Expected logging.level debug, got %v (%v)", value, err)
+	}
+	if _, err := cfg.Get("logging.colour"); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+
+	entries, err := cfg.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Key == "logging.level" {
+			if entry.Origin.Source != SourceEnv || entry.Origin.Location != "TILOKIT_LOGGING_LEVEL" {
+				t.Errorf("Unexpected origin for logging.level: %s", entry.Origin)
+			}
+			return
+		}
+	}
+	t.Error("Expected logging.level among entries")
+}
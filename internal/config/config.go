@@ -1,37 +1,129 @@
 package config
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 
 	"github.com/pkg/errors"
-	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/utils"
 )
 
-// Config holds the application configuration
+// Config holds the application configuration. Its layout mirrors the shipped
+// config/tilokit.yaml.
 type Config struct {
-	DefaultFramework      string            `mapstructure:"default_framework"`
-	DefaultBuildTool      string            `mapstructure:"default_build_tool"`
-	DefaultPackageManager string            `mapstructure:"default_package_manager"`
-	DefaultOutputDir      string            `mapstructure:"default_output_dir"`
-	Templates             map[string]string `mapstructure:"templates"`
-	Plugins               []string          `mapstructure:"plugins"`
-	Features              map[string]bool   `mapstructure:"features"`
+	Defaults    Defaults                   `yaml:"defaults"`
+	Features    map[string]bool            `yaml:"features"`
+	Frameworks  map[string]FrameworkConfig `yaml:"frameworks"`
+	BuildTools  map[string]BuildToolConfig `yaml:"build_tools"`
+	Plugins     map[string]PluginConfig    `yaml:"plugins"`
+	Templates   TemplatesConfig            `yaml:"templates"`
+	Logging     LoggingConfig              `yaml:"logging"`
+	Development DevelopmentConfig          `yaml:"development"`
+
+	// origins records which layer supplied each effective value
+	origins map[string]Origin
+	// files lists the config files that were loaded, lowest precedence first
+	files []string
+}
+
+// Defaults holds settings applied to every generated project
+type Defaults struct {
+	Framework       string `yaml:"framework"`
+	BuildTool       string `yaml:"build_tool"`
+	PackageManager  string `yaml:"package_manager"`
+	OutputDir       string `yaml:"output_dir"`
+	GitInit         bool   `yaml:"git_init"`
+	InstallDeps     bool   `yaml:"install_deps"`
+	CreateReadme    bool   `yaml:"create_readme"`
+	CreateGitignore bool   `yaml:"create_gitignore"`
+}
+
+// FrameworkConfig holds per-framework settings. Language-specific settings
+// such as python_version are kept in Options.
+type FrameworkConfig struct {
+	DefaultBuildTool string                 `yaml:"default_build_tool,omitempty"`
+	TypeScript       bool                   `yaml:"typescript"`
+	Testing          string                 `yaml:"testing,omitempty"`
+	Linting          bool                   `yaml:"linting"`
+	Formatting       bool                   `yaml:"formatting"`
+	Options          map[string]interface{} `yaml:",inline"`
 }
 
-// LoadConfig loads configuration from file and environment
-// Currently returns default config as file loading is disabled
+// BuildToolConfig holds per-build-tool settings
+type BuildToolConfig struct {
+	ConfigFile       string `yaml:"config_file,omitempty"`
+	DevCommand       string `yaml:"dev_command,omitempty"`
+	BuildCommand     string `yaml:"build_command,omitempty"`
+	RequirementsFile string `yaml:"requirements_file,omitempty"`
+	DevRequirements  string `yaml:"dev_requirements,omitempty"`
+}
+
+// PluginConfig holds per-plugin settings. Plugin-specific settings such as
+// default_branch are kept in Options.
+type PluginConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Command, when set, runs the plugin as an external process; see
+	// docs/PLUGINS.md
+	Command string   `yaml:"command,omitempty"`
+	Args    []string `yaml:"args,omitempty"`
+	// Grants lists what an external plugin may do beyond writing project
+	// files, such as "commands"
+	Grants  []string               `yaml:"grants,omitempty"`
+	Options map[string]interface{} `yaml:",inline"`
+}
+
+// TemplatesConfig holds template lookup settings
+type TemplatesConfig struct {
+	BasePath      string `yaml:"base_path"`
+	FileExtension string `yaml:"file_extension"`
+}
+
+// LoggingConfig holds logging settings
+type LoggingConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// DevelopmentConfig holds settings for generated development setups
+type DevelopmentConfig struct {
+	HotReload  bool `yaml:"hot_reload"`
+	SourceMaps bool `yaml:"source_maps"`
+	Debug      bool `yaml:"debug"`
+}
+
+// LoadConfig loads configuration from the built-in defaults, the global user
+// config, the nearest project config and TILOKIT_* environment variables
 func LoadConfig() (*Config, error) {
-	// For now, skip config file loading to avoid unmarshal errors
-	// Just return default config
-	logrus.Debug("Using default configuration (config file loading disabled)")
-	return getDefaultConfig(), nil
+	return Load(LoadOptions{})
+}
+
+// DefaultBuildToolFor returns the configured default build tool for a
+// framework, falling back to the built-in mapping
+func (c *Config) DefaultBuildToolFor(framework string) string {
+	if fw, exists := c.Frameworks[framework]; exists && fw.DefaultBuildTool != "" {
+		return fw.DefaultBuildTool
+	}
+	return getDefaultBuildTool(framework)
+}
+
+// DefaultPackageManagerFor returns the package manager for a build tool:
+// defaults.package_manager for the JavaScript build tools when it is set,
+// otherwise the built-in mapping
+func (c *Config) DefaultPackageManagerFor(buildTool string) string {
+	if _, javascript := packageManagers[buildTool]; javascript && c.Defaults.PackageManager != "" {
+		return c.Defaults.PackageManager
+	}
+	return getDefaultPackageManager(buildTool)
 }
 
-// CreateProjectConfig creates a project configuration from CLI inputs
-func CreateProjectConfig(projectName, framework, buildTool, outputDir string) *tilocontext.ProjectConfig {
+// CreateProjectConfig creates a project configuration from CLI inputs,
+// taking the build tool and package manager the inputs leave open from the
+// configuration
+func (c *Config) CreateProjectConfig(projectName, framework, buildTool, outputDir string) *tilocontext.ProjectConfig {
 	config := &tilocontext.ProjectConfig{
 		ProjectName: projectName,
 		Framework:   framework,
@@ -48,51 +140,92 @@ func CreateProjectConfig(projectName, framework, buildTool, outputDir string) *t
 	}
 
 	if config.BuildTool == "" {
-		config.BuildTool = getDefaultBuildTool(framework)
+		config.BuildTool = c.DefaultBuildToolFor(framework)
 	}
 
 	// Set package manager based on build tool
-	config.PackageManager = getDefaultPackageManager(config.BuildTool)
+	config.PackageManager = c.DefaultPackageManagerFor(config.BuildTool)
 
 	return config
 }
 
-// SaveConfig saves the current configuration to file
+// GlobalConfigPath returns the path of the user's global config file
+func GlobalConfigPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".tilokit", "tilokit.yaml")
+}
+
+// SaveConfig saves the current configuration to the global config file
 func SaveConfig(config *Config) error {
-	configDir := filepath.Join(os.Getenv("HOME"), ".tilokit")
-	// Use more restrictive permissions (0750 instead of 0755)
-	if err := os.MkdirAll(configDir, 0750); err != nil {
-		return errors.Wrap(err, "failed to create config directory")
-	}
+	return SaveConfigAs(config, GlobalConfigPath())
+}
 
-	configFile := filepath.Join(configDir, "tilokit.yaml")
+// SaveConfigAs saves the configuration to the given file
+func SaveConfigAs(config *Config, path string) error {
+	data, err := marshalYAML(config)
+	if err != nil {
+		return err
+	}
 
-	viper.Set("default_framework", config.DefaultFramework)
-	viper.Set("default_build_tool", config.DefaultBuildTool)
-	viper.Set("default_package_manager", config.DefaultPackageManager)
-	viper.Set("default_output_dir", config.DefaultOutputDir)
-	viper.Set("templates", config.Templates)
-	viper.Set("plugins", config.Plugins)
-	viper.Set("features", config.Features)
+	if err := utils.WriteFile(path, string(data)); err != nil {
+		return errors.Wrap(err, "failed to write config file")
+	}
+	return nil
+}
 
-	return viper.WriteConfigAs(configFile)
+// marshalYAML encodes v with the two-space indentation used by the shipped
+// config file
+func marshalYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, errors.Wrap(err, "failed to encode config")
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to encode config")
+	}
+	return buf.Bytes(), nil
 }
 
 func getDefaultConfig() *Config {
 	return &Config{
-		DefaultFramework:      "react",
-		DefaultBuildTool:      "vite",
-		DefaultPackageManager: "npm",
-		DefaultOutputDir:      ".",
-		Templates:             map[string]string{},
-		Plugins:               []string{},
+		Defaults: Defaults{
+			Framework:       "react",
+			BuildTool:       "vite",
+			PackageManager:  "npm",
+			OutputDir:       ".",
+			GitInit:         true,
+			InstallDeps:     true,
+			CreateReadme:    true,
+			CreateGitignore: true,
+		},
 		Features: map[string]bool{
-			"typescript":   true,
-			"eslint":       true,
-			"prettier":     true,
-			"testing":      true,
-			"git_init":     true,
-			"install_deps": true,
+			"typescript": true,
+			"eslint":     true,
+			"prettier":   true,
+			"testing":    true,
+		},
+		Frameworks: map[string]FrameworkConfig{
+			"react": {DefaultBuildTool: "vite", TypeScript: true, Testing: "vitest", Linting: true, Formatting: true},
+			"vue":   {DefaultBuildTool: "vite", TypeScript: true, Testing: "vitest", Linting: true, Formatting: true},
+		},
+		BuildTools: map[string]BuildToolConfig{
+			"vite": {ConfigFile: "vite.config.js", DevCommand: "dev", BuildCommand: "build"},
+		},
+		Plugins: map[string]PluginConfig{
+			"git": {Enabled: true},
+		},
+		Templates: TemplatesConfig{
+			BasePath:      "templates",
+			FileExtension: ".tmpl",
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Development: DevelopmentConfig{
+			HotReload:  true,
+			SourceMaps: true,
 		},
 	}
 }
@@ -114,17 +247,19 @@ func getDefaultBuildTool(framework string) string {
 	return "vite"
 }
 
-func getDefaultPackageManager(buildTool string) string {
-	packageManagers := map[string]string{
-		"vite":        "npm",
-		"webpack":     "npm",
-		"rollup":      "npm",
-		"angular-cli": "npm",
-		"next":        "npm",
-		"nuxt":        "npm",
-		"gatsby":      "npm",
-	}
+// packageManagers maps the JavaScript build tools to their default package
+// manager
+var packageManagers = map[string]string{
+	"vite":        "npm",
+	"webpack":     "npm",
+	"rollup":      "npm",
+	"angular-cli": "npm",
+	"next":        "npm",
+	"nuxt":        "npm",
+	"gatsby":      "npm",
+}
 
+func getDefaultPackageManager(buildTool string) string {
 	if pm, exists := packageManagers[buildTool]; exists {
 		return pm
 	}
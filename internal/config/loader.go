@@ -0,0 +1,453 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// ProjectConfigName is the file name of project-local configuration, looked
+// up from the working directory towards the filesystem root
+const ProjectConfigName = ".tilokit.yaml"
+
+// envPrefix prefixes environment variables that override config keys, e.g.
+// TILOKIT_DEFAULTS_OUTPUT_DIR for defaults.output_dir
+const envPrefix = "TILOKIT_"
+
+// Source identifies the configuration layer a value came from
+type Source string
+
+// Configuration layers, from lowest to highest precedence
+const (
+	SourceDefault Source = "default"
+	SourceGlobal  Source = "global"
+	SourceProject Source = "project"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Origin describes where an effective configuration value was set
+type Origin struct {
+	Source Source
+	// Location is the file or environment variable that set the value
+	Location string
+}
+
+func (o Origin) String() string {
+	if o.Location == "" {
+		return string(o.Source)
+	}
+	return fmt.Sprintf("%s (%s)", o.Source, o.Location)
+}
+
+// LoadOptions controls where Load looks for each configuration layer. Zero
+// values select the standard locations.
+type LoadOptions struct {
+	// GlobalFile defaults to ~/.tilokit/tilokit.yaml
+	GlobalFile string
+	// WorkDir is where the project config lookup starts; defaults to the
+	// current directory
+	WorkDir string
+	// Environ defaults to os.Environ()
+	Environ []string
+	// Flags holds values set on the command line, keyed by dotted config
+	// key such as "defaults.output_dir"
+	Flags map[string]interface{}
+}
+
+// FileError reports every problem found in a configuration file
+type FileError struct {
+	File     string
+	Problems []string
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("invalid config file %s:\n  %s", e.File, strings.Join(e.Problems, "\n  "))
+}
+
+// Load builds the effective configuration. Built-in defaults are overridden
+// by the global user config, then the nearest project config, then TILOKIT_*
+// environment variables, then command-line flags.
+//
+// The layers are merged on YAML node trees rather than with viper, which
+// can't tell which layer set a value and reports neither unknown keys nor
+// the line of a value of the wrong type.
+func Load(opts LoadOptions) (*Config, error) {
+	if opts.GlobalFile == "" {
+		opts.GlobalFile = GlobalConfigPath()
+	}
+	if opts.WorkDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to determine working directory")
+		}
+		opts.WorkDir = wd
+	}
+	if opts.Environ == nil {
+		opts.Environ = os.Environ()
+	}
+
+	merged, err := toMap(getDefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	origins := make(map[string]Origin)
+	for key := range flatten(merged, "") {
+		origins[key] = Origin{Source: SourceDefault}
+	}
+
+	var files []string
+	if utils.FileExists(opts.GlobalFile) {
+		layer, err := ReadLayer(opts.GlobalFile)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, opts.GlobalFile)
+		mergeLayer(merged, layer, "", Origin{Source: SourceGlobal, Location: opts.GlobalFile}, origins)
+	}
+
+	if projectFile := FindProjectConfig(opts.WorkDir); projectFile != "" {
+		layer, err := ReadLayer(projectFile)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, projectFile)
+		mergeLayer(merged, layer, "", Origin{Source: SourceProject, Location: projectFile}, origins)
+	}
+
+	for _, override := range envOverrides(opts.Environ, merged) {
+		layer := make(map[string]interface{})
+		setPath(layer, override.key, override.value)
+		mergeLayer(merged, layer, "", Origin{Source: SourceEnv, Location: override.name}, origins)
+	}
+
+	for _, key := range sortedKeys(opts.Flags) {
+		layer := make(map[string]interface{})
+		setPath(layer, key, opts.Flags[key])
+		mergeLayer(merged, layer, "", Origin{Source: SourceFlag}, origins)
+	}
+
+	cfg, err := fromMap(merged)
+	if err != nil {
+		return nil, err
+	}
+	cfg.origins = origins
+	cfg.files = files
+
+	logLoadedLayers(origins)
+	return cfg, nil
+}
+
+// Origin reports which layer set the effective value of a dotted key such as
+// "defaults.output_dir". Unknown keys report the default source.
+func (c *Config) Origin(key string) Origin {
+	if origin, exists := c.origins[key]; exists {
+		return origin
+	}
+	return Origin{Source: SourceDefault}
+}
+
+// Files returns the config files that were loaded, lowest precedence first
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// FindProjectConfig walks up from dir and returns the first project config
+// file found, or an empty string
+func FindProjectConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		candidate := filepath.Join(dir, ProjectConfigName)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ReadLayer reads a configuration file and validates it against the config
+// schema, reporting unknown keys and type mismatches with line numbers
+func ReadLayer(path string) (map[string]interface{}, error) {
+	content, err := utils.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read config file %s", path)
+	}
+	return parseLayer(path, content)
+}
+
+func parseLayer(path, content string) (map[string]interface{}, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		return nil, &FileError{File: path, Problems: []string{err.Error()}}
+	}
+	if len(document.Content) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	root := document.Content[0]
+
+	var problems []string
+	checkNode(root, reflect.TypeOf(Config{}), "", &problems)
+	if len(problems) == 0 {
+		var typed Config
+		if err := root.Decode(&typed); err != nil {
+			problems = append(problems, decodeProblems(err)...)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &FileError{File: path, Problems: problems}
+	}
+
+	layer := make(map[string]interface{})
+	if err := root.Decode(&layer); err != nil {
+		return nil, &FileError{File: path, Problems: decodeProblems(err)}
+	}
+	return layer, nil
+}
+
+// checkNode compares a YAML node against the Go type it decodes into and
+// records unknown keys. Type mismatches are left to the decoder.
+func checkNode(node *yaml.Node, t reflect.Type, path string, problems *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			*problems = append(*problems, fmt.Sprintf("line %d: %s must be a mapping", node.Line, displayPath(path)))
+			return
+		}
+
+		fields, inline := schemaFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			key := joinPath(path, keyNode.Value)
+
+			field, known := fields[keyNode.Value]
+			if !known {
+				if !inline {
+					*problems = append(*problems, fmt.Sprintf("line %d: unknown key %q (valid keys: %s)",
+						keyNode.Line, key, strings.Join(sortedKeys(fields), ", ")))
+				}
+				continue
+			}
+			checkNode(valueNode, field.Type, key, problems)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkNode(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value), problems)
+		}
+	}
+}
+
+// schemaFields maps YAML keys to struct fields and reports whether the
+// struct accepts arbitrary extra keys through an inline map
+func schemaFields(t reflect.Type) (map[string]reflect.StructField, bool) {
+	fields := make(map[string]reflect.StructField)
+	inline := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if strings.Contains(opts, "inline") {
+			inline = true
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields, inline
+}
+
+func decodeProblems(err error) []string {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		return typeErr.Errors
+	}
+	return []string{err.Error()}
+}
+
+// envOverride is a TILOKIT_* variable matched to a config key
+type envOverride struct {
+	name  string
+	key   string
+	value interface{}
+}
+
+// envOverrides matches TILOKIT_* variables against the keys known so far.
+// Values are parsed as YAML scalars so booleans and numbers keep their type.
+func envOverrides(environ []string, merged map[string]interface{}) []envOverride {
+	keysByEnv := make(map[string]string)
+	for key := range flatten(merged, "") {
+		keysByEnv[envName(key)] = key
+	}
+
+	var overrides []envOverride
+	for _, entry := range environ {
+		name, raw, found := strings.Cut(entry, "=")
+		if !found || !strings.HasPrefix(name, envPrefix) {
+			continue
+		}
+		key, known := keysByEnv[name]
+		if !known {
+			continue
+		}
+		overrides = append(overrides, envOverride{name: name, key: key, value: parseScalar(raw)})
+	}
+
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].name < overrides[j].name
+	})
+	return overrides
+}
+
+func envName(key string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// parseScalar interprets a string the way YAML would, so "true" becomes a
+// bool and "3" an int, falling back to the raw string
+func parseScalar(raw string) interface{} {
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(raw), &value); err != nil || value == nil {
+		return raw
+	}
+	return value
+}
+
+// mergeLayer deep-merges a layer into merged and records the origin of
+// every value the layer sets
+func mergeLayer(merged, layer map[string]interface{}, prefix string, origin Origin, origins map[string]Origin) {
+	for key, value := range layer {
+		path := joinPath(prefix, key)
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			existing, ok := merged[key].(map[string]interface{})
+			if !ok {
+				existing = make(map[string]interface{})
+				merged[key] = existing
+			}
+			mergeLayer(existing, nested, path, origin, origins)
+			continue
+		}
+
+		merged[key] = value
+		origins[path] = origin
+	}
+}
+
+// flatten returns the leaf values of a nested map keyed by dotted path
+func flatten(tree map[string]interface{}, prefix string) map[string]interface{} {
+	leaves := make(map[string]interface{})
+	for key, value := range tree {
+		path := joinPath(prefix, key)
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			for leafKey, leafValue := range flatten(nested, path) {
+				leaves[leafKey] = leafValue
+			}
+			continue
+		}
+		leaves[path] = value
+	}
+	return leaves
+}
+
+// setPath sets a dotted key in a nested map, creating intermediate maps
+func setPath(tree map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := tree[part].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			tree[part] = next
+		}
+		tree = next
+	}
+	tree[parts[len(parts)-1]] = value
+}
+
+func toMap(cfg *Config) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode config")
+	}
+	tree := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, errors.Wrap(err, "failed to decode config")
+	}
+	return tree, nil
+}
+
+func fromMap(tree map[string]interface{}) (*Config, error) {
+	data, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode merged config")
+	}
+	cfg := &Config{}
+	if _, err := parseLayer("(merged configuration)", string(data)); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, errors.Wrap(err, "failed to decode merged config")
+	}
+	return cfg, nil
+}
+
+func logLoadedLayers(origins map[string]Origin) {
+	seen := make(map[string]bool)
+	for _, origin := range origins {
+		if origin.Source == SourceDefault || seen[origin.String()] {
+			continue
+		}
+		seen[origin.String()] = true
+		logrus.Debugf("Loaded configuration from %s", origin)
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "the document"
+	}
+	return path
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadShippedConfig(t *testing.T) {
+	shipped, err := filepath.Abs(filepath.Join("..", "..", "config", "tilokit.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(LoadOptions{
+		GlobalFile: shipped,
+		WorkDir:    t.TempDir(),
+		Environ:    []string{},
+	})
+	if err != nil {
+		t.Fatalf("Expected shipped config to load, got: %v", err)
+	}
+
+	if got := cfg.DefaultBuildToolFor("django"); got != "pip" {
+		t.Errorf("Expected django build tool pip, got %s", got)
+	}
+	if got := cfg.Frameworks["django"].Options["python_version"]; got != "3.11" {
+		t.Errorf("Expected python_version 3.11, got %v", got)
+	}
+	if got := cfg.Plugins["git"].Options["default_branch"]; got != "main" {
+		t.Errorf("Expected git default_branch main, got %v", got)
+	}
+}
+
+func TestLoadExternalPluginCommand(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "tilokit.yaml")
+	writeConfig(t, global, "plugins:\n  acme:\n    enabled: true\n    command: ~/bin/tilokit-acme\n    args: [--verbose]\n")
+
+	cfg, err := Load(LoadOptions{GlobalFile: global, WorkDir: dir, Environ: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acme := cfg.Plugins["acme"]
+	if acme.Command != "~/bin/tilokit-acme" || len(acme.Args) != 1 || acme.Args[0] != "--verbose" {
+		t.Errorf("Unexpected plugin config %+v", acme)
+	}
+	if len(acme.Options) != 0 {
+		t.Errorf("Expected command and args not to be options, got %v", acme.Options)
+	}
+}
+
+func TestCreateProjectConfigUsesConfiguredDefaults(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "tilokit.yaml")
+	writeConfig(t, global, "defaults:\n  package_manager: pnpm\nframeworks:\n  react:\n    default_build_tool: webpack\n")
+
+	cfg, err := Load(LoadOptions{GlobalFile: global, WorkDir: dir, Environ: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := cfg.CreateProjectConfig("web", "react", "", "")
+	if project.BuildTool != "webpack" || project.PackageManager != "pnpm" {
+		t.Errorf("Expected webpack with pnpm, got %s with %s", project.BuildTool, project.PackageManager)
+	}
+	if project := cfg.CreateProjectConfig("web", "vue", "rollup", ""); project.BuildTool != "rollup" || project.PackageManager != "pnpm" {
+		t.Errorf("Expected the requested build tool with pnpm, got %s with %s", project.BuildTool, project.PackageManager)
+	}
+	if project := cfg.CreateProjectConfig("api", "django", "pip", ""); project.PackageManager == "pnpm" {
+		t.Error("Expected the JavaScript package manager not to apply to pip")
+	}
+}
+
+func TestLoadLayerPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "home", "tilokit.yaml")
+	writeConfig(t, global, "defaults:\n  output_dir: global\n  package_manager: yarn\n  framework: vue\n")
+	writeConfig(t, filepath.Join(dir, "repo", ProjectConfigName), "defaults:\n  output_dir: project\n  package_manager: pnpm\n")
+
+	// The project config is found from a nested working directory
+	workDir := filepath.Join(dir, "repo", "apps", "web")
+	if err := os.MkdirAll(workDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(LoadOptions{
+		GlobalFile: global,
+		WorkDir:    workDir,
+		Environ:    []string{"TILOKIT_DEFAULTS_OUTPUT_DIR=env", "TILOKIT_DEFAULTS_GIT_INIT=false", "TILOKIT_ENV=ci"},
+		Flags:      map[string]interface{}{"defaults.output_dir": "flag"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cfg.Defaults.OutputDir != "flag" {
+		t.Errorf("Expected flag to win for output_dir, got %s", cfg.Defaults.OutputDir)
+	}
+	if cfg.Defaults.PackageManager != "pnpm" {
+		t.Errorf("Expected project to win for package_manager, got %s", cfg.Defaults.PackageManager)
+	}
+	if cfg.Defaults.Framework != "vue" {
+		t.Errorf("Expected global framework vue, got %s", cfg.Defaults.Framework)
+	}
+	if cfg.Defaults.GitInit {
+		t.Error("Expected TILOKIT_DEFAULTS_GIT_INIT=false to disable git_init")
+	}
+	if cfg.Defaults.BuildTool != "vite" {
+		t.Errorf("Expected default build tool vite, got %s", cfg.Defaults.BuildTool)
+	}
+
+	origins := map[string]Source{
+		"defaults.output_dir":      SourceFlag,
+		"defaults.git_init":        SourceEnv,
+		"defaults.package_manager": SourceProject,
+		"defaults.framework":       SourceGlobal,
+		"defaults.build_tool":      SourceDefault,
+	}
+	for key, want := range origins {
+		if got := cfg.Origin(key).Source; got != want {
+			t.Errorf("Expected %s to come from %s, got %s", key, want, got)
+		}
+	}
+}
+
+func TestLoadReportsUnknownKeysWithLines(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "tilokit.yaml")
+	writeConfig(t, global, strings.Join([]string{
+		"defaults:",
+		"  output_dir: out",
+		"  framwork: react",
+		"frameworks:",
+		"  react:",
+		"    custom_option: kept",
+		"logging:",
+		"  colour: true",
+		"",
+	}, "\n"))
+
+	_, err := Load(LoadOptions{GlobalFile: global, WorkDir: dir, Environ: []string{}})
+
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) {
+		t.Fatalf("Expected a FileError, got: %v", err)
+	}
+	if len(fileErr.Problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %d: %v", len(fileErr.Problems), fileErr.Problems)
+	}
+	if !strings.HasPrefix(fileErr.Problems[0], `line 3: unknown key "defaults.framwork"`) {
+		t.Errorf("Unexpected first problem: %s", fileErr.Problems[0])
+	}
+	if !strings.HasPrefix(fileErr.Problems[1], `line 8: unknown key "logging.colour"`) {
+		t.Errorf("Unexpected second problem: %s", fileErr.Problems[1])
+	}
+}
+
+func TestLoadReportsTypeMismatches(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "tilokit.yaml")
+	writeConfig(t, global, "defaults:\n  git_init: maybe\n")
+
+	_, err := Load(LoadOptions{GlobalFile: global, WorkDir: dir, Environ: []string{}})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("Expected a line-numbered type error, got: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// RecipeVersion is the recipe format written by this version of TiLoKit.
+// Recipes with a newer version are rejected rather than half understood.
+const RecipeVersion = 1
+
+// LoadRecipe reads and validates a recipe, a ProjectConfig saved as YAML.
+// Unknown keys are errors, so typos don't silently change the project.
+func LoadRecipe(path string) (*tilocontext.ProjectConfig, error) {
+	content, err := utils.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read recipe %s", path)
+	}
+
+	recipe, err := ParseRecipe([]byte(content))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid recipe %s", path)
+	}
+	return recipe, nil
+}
+
+// ParseRecipe decodes and validates a recipe
+func ParseRecipe(data []byte) (*tilocontext.ProjectConfig, error) {
+	recipe := &tilocontext.ProjectConfig{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(recipe); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("recipe is empty")
+		}
+		return nil, err
+	}
+
+	if err := ValidateRecipe(recipe); err != nil {
+		return nil, err
+	}
+	return recipe, nil
+}
+
+// ValidateRecipe checks a recipe's version and contents. The project name
+// may be left out and given with --name instead.
+func ValidateRecipe(recipe *tilocontext.ProjectConfig) error {
+	switch {
+	case recipe.Version == 0:
+		return errors.Errorf("version is required, the current version is %d", RecipeVersion)
+	case recipe.Version > RecipeVersion:
+		return errors.Errorf("version %d is newer than this TiLoKit supports (%d), please update", recipe.Version, RecipeVersion)
+	case recipe.Version < 0:
+		return errors.Errorf("invalid version %d", recipe.Version)
+	}
+
+	var problems []string
+	if recipe.ProjectName != "" {
+		if err := utils.ValidateProjectName(recipe.ProjectName); err != nil {
+			problems = append(problems, "project_name: "+err.Error())
+		}
+	}
+	if recipe.Framework == "" {
+		problems = append(problems, "framework is required")
+	} else if recipe.Features != nil {
+		if err := features.Builtin().Validate(recipe.Framework, recipe.Features); err != nil {
+			problems = append(problems, "features: "+err.Error())
+		}
+	}
+	for name := range recipe.Variables {
+		if name == "" {
+			problems = append(problems, "variables: empty variable name")
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// SaveRecipe writes a project configuration as a recipe
+func SaveRecipe(recipe *tilocontext.ProjectConfig, path string) error {
+	saved := *recipe
+	saved.Version = RecipeVersion
+
+	data, err := marshalYAML(&saved)
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFile(path, string(data)); err != nil {
+		return errors.Wrapf(err, "failed to write recipe %s", path)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+)
+
+func TestRecipeRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.recipe.yaml")
+	recipe := &tilocontext.ProjectConfig{
+		ProjectName:    "billing-api",
+		Framework:      "vue",
+		BuildTool:      "vite",
+		PackageManager: "pnpm",
+		OutputDir:      "services",
+		Features:       []string{"javascript", "eslint", "git"},
+		Variables:      map[string]interface{}{"port": 8080, "use_docker": true},
+		GitInit:        true,
+		ExcludePlugins: []string{"vite-builder"},
+		Seed:           42,
+	}
+
+	if err := SaveRecipe(recipe, path); err != nil {
+		t.Fatalf("Expected recipe to save, got: %v", err)
+	}
+	loaded, err := LoadRecipe(path)
+	if err != nil {
+		t.Fatalf("Expected saved recipe to load, got: %v", err)
+	}
+
+	recipe.Version = RecipeVersion
+	if !reflect.DeepEqual(loaded, recipe) {
+		t.Errorf("Expected %+v, got %+v", recipe, loaded)
+	}
+}
+
+func TestParseRecipeRejectsInvalidRecipes(t *testing.T) {
+	tests := []struct {
+		name    string
+		recipe  string
+		message string
+	}{
+		{"empty", "", "empty"},
+		{"no version", "framework: react\n", "version is required"},
+		{"newer version", "version: 99\nframework: react\n", "newer"},
+		{"unknown key", "version: 1\nframework: react\nframwork: vue\n", "framwork"},
+		{"no framework", "version: 1\nproject_name: app\n", "framework is required"},
+		{"bad name", "version: 1\nproject_name: \"bad name!\"\nframework: react\n", "project_name"},
+		{"unknown feature", "version: 1\nframework: react\nfeatures: [graphql]\n", "unknown feature graphql"},
+		{"conflicting features", "version: 1\nframework: react\nfeatures: [typescript, javascript]\n", "conflict"},
+		{"feature for another framework", "version: 1\nframework: django\nfeatures: [eslint]\n", "not available"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRecipe([]byte(tt.recipe))
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got: %v", tt.message, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// Values holds template answers supplied without prompting, keyed by
+// variable name. Precedence, highest first, is: --set, --values files in
+// the order given, interactive prompts, then template defaults.
+type Values map[string]interface{}
+
+// LoadValuesFile reads answers from a YAML or JSON file. The file must hold
+// a single mapping of variable names to values.
+func LoadValuesFile(path string) (Values, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return nil, errors.Errorf("values file %s must be .yaml, .yml or .json", path)
+	}
+
+	content, err := utils.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read values file %s", path)
+	}
+
+	// JSON is a subset of YAML, and decoding it as YAML keeps whole numbers
+	// as ints rather than float64. Decoding into a plain map keeps nested
+	// mappings plain too, which Merge and templates expect.
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(content), &values); err != nil {
+		return nil, errors.Wrapf(err, "invalid values file %s", path)
+	}
+	return Values(values), nil
+}
+
+// ParseSet parses a key=value pair from --set. The value is typed like a
+// YAML scalar, so true, 8080 and 1.5 become a bool, int and float, and
+// [a, b] becomes a list; quote a value to keep it a string, e.g. port='"80"'.
+// A dotted key such as db.host sets a nested value.
+func ParseSet(pair string) (string, interface{}, error) {
+	key, raw, found := strings.Cut(pair, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" {
+		return "", nil, errors.Errorf("invalid --set %q, expected key=value", pair)
+	}
+	for _, part := range strings.Split(key, ".") {
+		if part == "" {
+			return "", nil, errors.Errorf("invalid --set key %q", key)
+		}
+	}
+	if raw == "" {
+		return key, "", nil
+	}
+	return key, parseScalar(raw), nil
+}
+
+// Set stores a value under a possibly dotted key
+func (v Values) Set(key string, value interface{}) {
+	setPath(v, key, value)
+}
+
+// Merge copies other over v; nested mappings are merged key by key
+func (v Values) Merge(other Values) {
+	for key, value := range other {
+		nested, isMap := value.(map[string]interface{})
+		existing, wasMap := v[key].(map[string]interface{})
+		if isMap && wasMap {
+			Values(existing).Merge(nested)
+			continue
+		}
+		v[key] = value
+	}
+}
+
+// ResolveValues combines --values files and --set pairs into one set of
+// answers, with later files and --set taking precedence
+func ResolveValues(files, sets []string) (Values, error) {
+	values := make(Values)
+	for _, file := range files {
+		fileValues, err := LoadValuesFile(file)
+		if err != nil {
+			return nil, err
+		}
+		values.Merge(fileValues)
+	}
+
+	for _, pair := range sets {
+		key, value, err := ParseSet(pair)
+		if err != nil {
+			return nil, err
+		}
+		values.Set(key, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSetTypesValues(t *testing.T) {
+	tests := []struct {
+		pair    string
+		key     string
+		want    interface{}
+		wantErr bool
+	}{
+		{pair: "use_celery=false", key: "use_celery", want: false},
+		{pair: "port=8080", key: "port", want: 8080},
+		{pair: "ratio=0.5", key: "ratio", want: 0.5},
+		{pair: "extras=[redis, celery]", key: "extras", want: []interface{}{"redis", "celery"}},
+		{pair: `port="8080"`, key: "port", want: "8080"},
+		{pair: "database=sqlite", key: "database", want: "sqlite"},
+		{pair: "greeting=a=b", key: "greeting", want: "a=b"},
+		{pair: "empty=", key: "empty", want: ""},
+		{pair: "db.host=localhost", key: "db.host", want: "localhost"},
+		{pair: "novalue", wantErr: true},
+		{pair: "=value", wantErr: true},
+		{pair: "db..host=x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		key, value, err := ParseSet(tt.pair)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSet(%q) error = %v, wantErr %v", tt.pair, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if key != tt.key || !reflect.DeepEqual(value, tt.want) {
+			t.Errorf("ParseSet(%q) = %q, %#v; want %q, %#v", tt.pair, key, value, tt.key, tt.want)
+		}
+	}
+}
+
+func TestResolveValuesPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	writeConfig(t, base, "database: postgresql\nuse_celery: true\ndb:\n  host: db\n  port: 5432\n")
+	override := filepath.Join(dir, "override.json")
+	writeConfig(t, override, `{"use_celery": false, "db": {"port": 6543}}`)
+
+	values, err := ResolveValues([]string{base, override}, []string{"database=sqlite", "db.host=localhost"})
+	if err != nil {
+		t.Fatalf("Expected values to resolve, got: %v", err)
+	}
+
+	want := Values{
+		"database":   "sqlite",
+		"use_celery": false,
+		"db":         map[string]interface{}{"host": "localhost", "port": 6543},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Expected %#v, got %#v", want, values)
+	}
+}
+
+func TestLoadValuesFileRejectsUnknownFormats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answers.toml")
+	writeConfig(t, path, "database = \"sqlite\"\n")
+
+	if _, err := LoadValuesFile(path); err == nil {
+		t.Error("Expected a .toml values file to be rejected")
+	}
+}
@@ -0,0 +1,207 @@
+// Package archive streams a generated project as a tar, gzipped tar or zip
+// archive. Entries are written in path order with normalized modes and a
+// fixed modification time, so the same project always produces the same
+// archive. Archives such as plugin packages are extracted with Extract.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// Format is how a generated project is written
+type Format string
+
+const (
+	// Dir writes the project to a directory
+	Dir   Format = "dir"
+	Tar   Format = "tar"
+	TarGz Format = "tar.gz"
+	Zip   Format = "zip"
+)
+
+// Formats lists the supported formats
+var Formats = []Format{Dir, Tar, TarGz, Zip}
+
+// ParseFormat parses a format name; tgz is accepted for tar.gz
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "dir":
+		return Dir, nil
+	case "tar":
+		return Tar, nil
+	case "tar.gz", "tgz":
+		return TarGz, nil
+	case "zip":
+		return Zip, nil
+	}
+	return "", errors.Errorf("unknown output format %q, expected dir, tar, tar.gz or zip", name)
+}
+
+// Extension returns the file name extension of an archive format
+func (f Format) Extension() string {
+	if f == Dir {
+		return ""
+	}
+	return "." + string(f)
+}
+
+const (
+	dirMode  fs.FileMode = 0755
+	fileMode fs.FileMode = 0644
+	execMode fs.FileMode = 0755
+)
+
+// entry is a file or directory to archive
+type entry struct {
+	// name is the path inside the archive, with a trailing slash for
+	// directories
+	name string
+	path string
+	dir  bool
+	mode fs.FileMode
+}
+
+// Write streams the tree under root on fsys to w. Every entry is placed under
+// prefix, normally the project name, and stamped with modTime. Files are
+// 0644, or 0755 if any execute bit is set on fsys; directories are 0755.
+func Write(w io.Writer, format Format, fsys utils.FS, root, prefix string, modTime time.Time) error {
+	entries, err := collect(fsys, root, prefix)
+	if err != nil {
+		return err
+	}
+	modTime = modTime.UTC().Truncate(time.Second)
+
+	switch format {
+	case Tar:
+		return writeTar(w, fsys, entries, modTime)
+	case TarGz:
+		gz := gzip.NewWriter(w)
+		// A fixed header keeps the output reproducible
+		gz.ModTime = modTime
+		if err := writeTar(gz, fsys, entries, modTime); err != nil {
+			return err
+		}
+		return gz.Close()
+	case Zip:
+		return writeZip(w, fsys, entries, modTime)
+	}
+	return errors.Errorf("%s is not an archive format", format)
+}
+
+// collect lists the entries under root, sorted by archive path
+func collect(fsys utils.FS, root, prefix string) ([]entry, error) {
+	prefix = strings.Trim(filepath.ToSlash(prefix), "/")
+	var entries []entry
+
+	err := afero.Walk(fsys, root, func(filePath string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
+		if name == "." || name == "" {
+			return nil
+		}
+
+		e := entry{name: name, path: filePath, mode: fileMode}
+		switch {
+		case info.IsDir():
+			e.name += "/"
+			e.dir = true
+			e.mode = dirMode
+		case !info.Mode().IsRegular():
+			return errors.Errorf("%s is not a regular file", rel)
+		case info.Mode()&0111 != 0:
+			e.mode = execMode
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", root)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries, nil
+}
+
+func writeTar(w io.Writer, fsys utils.FS, entries []entry, modTime time.Time) error {
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:    e.name,
+			Mode:    int64(e.mode),
+			ModTime: modTime,
+			Format:  tar.FormatPAX,
+		}
+		var content []byte
+		if e.dir {
+			header.Typeflag = tar.TypeDir
+		} else {
+			var err error
+			if content, err = afero.ReadFile(fsys, e.path); err != nil {
+				return errors.Wrapf(err, "failed to read %s", e.name)
+			}
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(len(content))
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return errors.Wrapf(err, "failed to write %s", e.name)
+		}
+		if _, err := tw.Write(content); err != nil {
+			return errors.Wrapf(err, "failed to write %s", e.name)
+		}
+	}
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, fsys utils.FS, entries []entry, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		header := &zip.FileHeader{
+			Name:     e.name,
+			Modified: modTime,
+			Method:   zip.Deflate,
+		}
+		mode := e.mode
+		if e.dir {
+			header.Method = zip.Store
+			mode |= fs.ModeDir
+		}
+		header.SetMode(mode)
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write %s", e.name)
+		}
+		if e.dir {
+			continue
+		}
+		content, err := afero.ReadFile(fsys, e.path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", e.name)
+		}
+		if _, err := fw.Write(content); err != nil {
+			return errors.Wrapf(err, "failed to write %s", e.name)
+		}
+	}
+	return zw.Close()
+}
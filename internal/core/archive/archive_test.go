@@ -0,0 +1,124 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+func projectFS(t *testing.T) utils.FS {
+	t.Helper()
+	fsys := utils.NewMemoryFS()
+	for path, content := range map[string]string{
+		"out/web/src/main.ts":   "console.log('hi')\n",
+		"out/web/package.json":  "{}\n",
+		"out/web/scripts/build": "#!/bin/sh\n",
+		"out/web/README.md":     "# web\n",
+	} {
+		if err := utils.WriteFileFS(fsys, path, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fsys.Chmod("out/web/scripts/build", 0700); err != nil {
+		t.Fatal(err)
+	}
+	return fsys
+}
+
+var modTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+var wantEntries = []string{
+	"web/ 755",
+	"web/README.md 644",
+	"web/package.json 644",
+	"web/scripts/ 755",
+	"web/scripts/build 755",
+	"web/src/ 755",
+	"web/src/main.ts 644",
+}
+
+func write(t *testing.T, format Format) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, format, projectFS(t), "out/web", "web", modTime); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func readTar(t *testing.T, r io.Reader) []string {
+	t.Helper()
+	var entries []string
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !header.ModTime.Equal(modTime) {
+			t.Errorf("Expected %s to be stamped %v, got %v", header.Name, modTime, header.ModTime)
+		}
+		entries = append(entries, fmt.Sprintf("%s %o", header.Name, fs.FileMode(header.Mode).Perm()))
+	}
+}
+
+func TestWriteTar(t *testing.T) {
+	entries := readTar(t, bytes.NewReader(write(t, Tar)))
+	if !reflect.DeepEqual(entries, wantEntries) {
+		t.Errorf("Expected entries %v, got %v", wantEntries, entries)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(write(t, TarGz)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := readTar(t, gz); !reflect.DeepEqual(entries, wantEntries) {
+		t.Errorf("Expected gzipped entries %v, got %v", wantEntries, entries)
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	data := write(t, Zip)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []string
+	for _, file := range zr.File {
+		entries = append(entries, fmt.Sprintf("%s %o", file.Name, file.Mode().Perm()))
+	}
+	if !reflect.DeepEqual(entries, wantEntries) {
+		t.Errorf("Expected entries %v, got %v", wantEntries, entries)
+	}
+}
+
+func TestWriteIsDeterministic(t *testing.T) {
+	for _, format := range []Format{Tar, TarGz, Zip} {
+		if !bytes.Equal(write(t, format), write(t, format)) {
+			t.Errorf("Expected the same %s archive twice", format)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"": Dir, "tar": Tar, "tgz": TarGz, "tar.gz": TarGz, "ZIP": Zip} {
+		if got, err := ParseFormat(name); err != nil || got != want {
+			t.Errorf("Expected %q to be %s, got %s (%v)", name, want, got, err)
+		}
+	}
+	if _, err := ParseFormat("rar"); err == nil {
+		t.Error("Expected rar to be rejected")
+	}
+}
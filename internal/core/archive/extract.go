@@ -0,0 +1,158 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FormatOf returns the archive format of a file from its name, accepting
+// .tgz for tar.gz
+func FormatOf(name string) (Format, bool) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return TarGz, true
+	case strings.HasSuffix(lower, ".tar"):
+		return Tar, true
+	case strings.HasSuffix(lower, ".zip"):
+		return Zip, true
+	}
+	return "", false
+}
+
+// Modes of extracted entries, which only their owner's tools read
+const (
+	extractDirMode  fs.FileMode = 0750
+	extractFileMode fs.FileMode = 0600
+	extractExecMode fs.FileMode = 0750
+)
+
+// Extract unpacks the archive at src into dir. Only files and directories
+// are extracted; files keep their execute bit. An entry that would land
+// outside dir fails the extraction, as do links.
+func Extract(src string, format Format, dir string) error {
+	switch format {
+	case Tar, TarGz:
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		var r io.Reader = f
+		if format == TarGz {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return errors.Wrap(err, "invalid gzip archive")
+			}
+			defer gz.Close()
+			r = gz
+		}
+		return extractTar(r, dir)
+	case Zip:
+		zr, err := zip.OpenReader(src)
+		if err != nil {
+			return errors.Wrap(err, "invalid zip archive")
+		}
+		defer zr.Close()
+		return extractZip(zr, dir)
+	}
+	return errors.Errorf("cannot extract %s archives", format)
+}
+
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "invalid tar archive")
+		}
+
+		target, err := entryPath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, extractDirMode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractFile(target, tr, fs.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader:
+		default:
+			return errors.Errorf("archive entry %s is not a file or directory", header.Name)
+		}
+	}
+}
+
+func extractZip(zr *zip.ReadCloser, dir string) error {
+	for _, file := range zr.File {
+		target, err := entryPath(dir, file.Name)
+		if err != nil {
+			return err
+		}
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, extractDirMode); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := file.Open()
+			if err != nil {
+				return err
+			}
+			err = extractFile(target, rc, mode)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			return errors.Errorf("archive entry %s is not a file or directory", file.Name)
+		}
+	}
+	return nil
+}
+
+// entryPath returns where an archive entry is extracted to
+func entryPath(dir, name string) (string, error) {
+	clean := path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "./"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", errors.Errorf("archive entry %s is outside the archive", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+func extractFile(target string, r io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), extractDirMode); err != nil {
+		return err
+	}
+	perm := extractFileMode
+	if mode&0111 != 0 {
+		perm = extractExecMode
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,58 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractRoundTrip(t *testing.T) {
+	for _, format := range []Format{Tar, TarGz, Zip} {
+		src := filepath.Join(t.TempDir(), "web"+format.Extension())
+		if err := os.WriteFile(src, write(t, format), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := FormatOf(src); !ok || got != format {
+			t.Errorf("Expected %s to be detected as %s, got %s", src, format, got)
+		}
+
+		dir := t.TempDir()
+		if err := Extract(src, format, dir); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "web", "src", "main.ts"))
+		if err != nil || string(data) != "console.log('hi')\n" {
+			t.Errorf("%s: unexpected main.ts %q (%v)", format, data, err)
+		}
+		info, err := os.Stat(filepath.Join(dir, "web", "scripts", "build"))
+		if err != nil || info.Mode().Perm()&0100 == 0 {
+			t.Errorf("%s: expected build to stay executable, got %v (%v)", format, info.Mode(), err)
+		}
+	}
+}
+
+func TestExtractRefusesEscapingEntries(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0644, Size: 1, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte("x"))
+	tw.Close()
+
+	root := t.TempDir()
+	src := filepath.Join(root, "evil.tar")
+	if err := os.WriteFile(src, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "out")
+	if err := Extract(src, Tar, dir); err == nil || !strings.Contains(err.Error(), "outside the archive") {
+		t.Errorf("Expected the entry to be refused, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "evil")); !os.IsNotExist(err) {
+		t.Error("Expected nothing written outside the directory")
+	}
+}
@@ -1,54 +1,123 @@
 package tilocontext
 
 import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
+
+	"github.com/ti-lo/tilokit/internal/utils"
 )
 
-// ProjectConfig holds the configuration for project generation
+// ProjectConfig holds the configuration for project generation. Saved as
+// YAML it is a recipe that can be generated again with --from; as JSON it
+// is the body of a generation request to the HTTP server.
 type ProjectConfig struct {
-	ProjectName   string            `yaml:"project_name" mapstructure:"project_name"`
-	Framework     string            `yaml:"framework" mapstructure:"framework"`
-	BuildTool     string            `yaml:"build_tool" mapstructure:"build_tool"`
-	PackageManager string           `yaml:"package_manager" mapstructure:"package_manager"`
-	OutputDir     string            `yaml:"output_dir" mapstructure:"output_dir"`
-	Template      string            `yaml:"template" mapstructure:"template"`
-	Features      []string          `yaml:"features" mapstructure:"features"`
-	Variables     map[string]interface{} `yaml:"variables" mapstructure:"variables"`
-	GitInit       bool              `yaml:"git_init" mapstructure:"git_init"`
-	InstallDeps   bool              `yaml:"install_deps" mapstructure:"install_deps"`
-}
-
-// ExecutionContext provides runtime context for plugin execution
+	// Version is the recipe format version, see config.RecipeVersion
+	Version        int                    `yaml:"version,omitempty" json:"version,omitempty"`
+	ProjectName    string                 `yaml:"project_name" json:"project_name"`
+	Framework      string                 `yaml:"framework" json:"framework"`
+	BuildTool      string                 `yaml:"build_tool" json:"build_tool"`
+	PackageManager string                 `yaml:"package_manager" json:"package_manager"`
+	OutputDir      string                 `yaml:"output_dir" json:"output_dir"`
+	Template       string                 `yaml:"template" json:"template"`
+	Features       []string               `yaml:"features" json:"features"`
+	Variables      map[string]interface{} `yaml:"variables" json:"variables"`
+	GitInit        bool                   `yaml:"git_init" json:"git_init"`
+	InstallDeps    bool                   `yaml:"install_deps" json:"install_deps"`
+	ExcludePlugins []string               `yaml:"exclude_plugins" json:"exclude_plugins"`
+	// PluginOptions holds the options of built-in plugins by plugin name,
+	// checked against the options each plugin's manifest declares
+	PluginOptions map[string]map[string]interface{} `yaml:"plugin_options,omitempty" json:"plugin_options,omitempty"`
+	// Seed makes generated UUIDs and secrets reproducible when non-zero
+	Seed int64 `yaml:"seed,omitempty" json:"seed,omitempty"`
+}
+
+// ExecutionContext provides runtime context for plugin execution. It embeds
+// the run's context.Context, so hooks can pass it to anything that takes a
+// context and should return ctx.Err() once it's done.
 type ExecutionContext struct {
-	Config        *ProjectConfig
-	ProjectPath   string
-	TempDir       string
-	StartTime     time.Time
-	Variables     map[string]interface{}
-	Metadata      map[string]interface{}
+	context.Context
+	Config *ProjectConfig
+	// ProjectPath is where plugins write. While generation runs it points
+	// at a staging directory that replaces TargetPath only on success.
+	ProjectPath string
+	// TargetPath is the final location of the generated project
+	TargetPath string
+	TempDir    string
+	StartTime  time.Time
+	Variables  map[string]interface{}
+	Metadata   map[string]interface{}
+	// FS is the filesystem plugins write through
+	FS utils.FS
+	// Virtual is set when FS is not the real project directory, e.g. during
+	// a dry run. Plugins must skip side effects that need real files, such
+	// as running git or package managers, and record them with SkipStep.
+	Virtual bool
+	// TemplateFuncs holds functions plugins add to every rendered template
+	TemplateFuncs map[string]interface{}
+	// Templates replaces shipped template trees by name, e.g. with the
+	// revisions a lockfile recorded; other templates render as shipped
+	Templates map[string]fs.FS
+	// Augment is set when features are added to a project that already
+	// exists: ProjectPath holds the project, only the plugins providing
+	// AddedFeatures run, and they must merge into existing files rather
+	// than replace them. Config.Features lists every feature of the project.
+	Augment       bool
+	AddedFeatures []string
+	// OnFile and OnSkip, when set, are called after a file is written and
+	// after a step is skipped
+	OnFile func(FileRecord)
+	OnSkip func(SkippedStep)
+
+	random        io.Reader
+	mutex         sync.Mutex
+	currentPlugin string
+	// options maps plugins to their option values, defaults included
+	options map[string]map[string]interface{}
+	files   map[string]FileRecord
+	// keys maps patched files to the plugin that set each key
+	keys       map[string]map[string]string
+	patchMutex sync.Mutex
+	skipped    []SkippedStep
+	templates  []TemplateRecord
 }
 
 // NewExecutionContext creates a new execution context
 func NewExecutionContext(config *ProjectConfig) *ExecutionContext {
 	projectPath := filepath.Join(config.OutputDir, config.ProjectName)
-	
+
 	ctx := &ExecutionContext{
-		Config:      config,
-		ProjectPath: projectPath,
-		StartTime:   time.Now(),
-		Variables:   make(map[string]interface{}),
-		Metadata:    make(map[string]interface{}),
+		Context:       context.Background(),
+		Config:        config,
+		ProjectPath:   projectPath,
+		TargetPath:    projectPath,
+		StartTime:     time.Now(),
+		Variables:     make(map[string]interface{}),
+		Metadata:      make(map[string]interface{}),
+		FS:            utils.NewOSFS(),
+		TemplateFuncs: make(map[string]interface{}),
+		random:        newRandom(config.Seed),
+		files:         make(map[string]FileRecord),
+		keys:          make(map[string]map[string]string),
+		options:       make(map[string]map[string]interface{}),
 	}
 
 	// Set default variables
 	ctx.Variables["project_name"] = config.ProjectName
+	// module_name is the project name as an identifier, e.g. for Python packages
+	ctx.Variables["module_name"] = utils.ToSnakeCase(config.ProjectName)
 	ctx.Variables["framework"] = config.Framework
 	ctx.Variables["build_tool"] = config.BuildTool
 	ctx.Variables["package_manager"] = config.PackageManager
 	ctx.Variables["timestamp"] = ctx.StartTime.Format("2006-01-02 15:04:05")
-	
+
 	// Merge user variables
 	for k, v := range config.Variables {
 		ctx.Variables[k] = v
@@ -57,6 +126,21 @@ func NewExecutionContext(config *ProjectConfig) *ExecutionContext {
 	return ctx
 }
 
+// Random returns the run's source of random bytes. It is deterministic when
+// the project config sets a seed and cryptographically secure otherwise.
+func (ctx *ExecutionContext) Random() io.Reader {
+	return ctx.random
+}
+
+func newRandom(seed int64) io.Reader {
+	if seed == 0 {
+		return crand.Reader
+	}
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], uint64(seed))
+	return rand.NewChaCha8(key)
+}
+
 // SetVariable sets a variable in the execution context
 func (ctx *ExecutionContext) SetVariable(key string, value interface{}) {
 	ctx.Variables[key] = value
@@ -68,6 +152,47 @@ func (ctx *ExecutionContext) GetVariable(key string) (interface{}, bool) {
 	return value, exists
 }
 
+// SetOptions sets the option values of a plugin, defaults included
+func (ctx *ExecutionContext) SetOptions(plugin string, values map[string]interface{}) {
+	ctx.options[plugin] = values
+}
+
+// Option returns an option of the plugin whose hook is running
+func (ctx *ExecutionContext) Option(name string) (interface{}, bool) {
+	value, exists := ctx.options[ctx.CurrentPlugin()][name]
+	return value, exists
+}
+
+// StringOption returns a string option of the plugin whose hook is running,
+// or "" when it has none
+func (ctx *ExecutionContext) StringOption(name string) string {
+	value, _ := ctx.Option(name)
+	s, _ := value.(string)
+	return s
+}
+
+// BoolOption returns a bool option of the plugin whose hook is running
+func (ctx *ExecutionContext) BoolOption(name string) bool {
+	value, _ := ctx.Option(name)
+	b, _ := value.(bool)
+	return b
+}
+
+// IntOption returns an int option of the plugin whose hook is running,
+// decoded from YAML or JSON
+func (ctx *ExecutionContext) IntOption(name string) int {
+	value, _ := ctx.Option(name)
+	switch n := value.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
 // SetMetadata sets metadata in the execution context
 func (ctx *ExecutionContext) SetMetadata(key string, value interface{}) {
 	ctx.Metadata[key] = value
@@ -81,13 +206,18 @@ func (ctx *ExecutionContext) GetMetadata(key string) (interface{}, bool) {
 
 // EnsureProjectDir creates the project directory if it doesn't exist
 func (ctx *ExecutionContext) EnsureProjectDir() error {
-	// Use more restrictive permissions (0750 instead of 0755)
-	return os.MkdirAll(ctx.ProjectPath, 0750)
+	return utils.EnsureDirFS(ctx.FS, ctx.ProjectPath)
 }
 
-// CreateTempDir creates a temporary directory for processing
+// CreateTempDir creates a temporary directory for processing. It lives next
+// to TargetPath so the staged project can be moved into place with a rename.
 func (ctx *ExecutionContext) CreateTempDir() error {
-	tempDir, err := os.MkdirTemp("", "tilokit-*")
+	parent := filepath.Dir(ctx.TargetPath)
+	if err := os.MkdirAll(parent, 0750); err != nil {
+		return err
+	}
+
+	tempDir, err := os.MkdirTemp(parent, ".tilokit-*")
 	if err != nil {
 		return err
 	}
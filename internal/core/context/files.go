@@ -0,0 +1,247 @@
+package tilocontext
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/ti-lo/tilokit/internal/core/patch"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// FileRecord describes a file written during generation
+type FileRecord struct {
+	// Path is relative to the project root and uses forward slashes
+	Path   string
+	Plugin string
+	Size   int64
+}
+
+// SkippedStep records a side effect a plugin chose not to perform, such as
+// initializing git while rendering to an in-memory filesystem
+type SkippedStep struct {
+	Plugin string
+	Step   string
+	Reason string
+}
+
+// TemplateRecord identifies a template tree rendered during generation
+type TemplateRecord struct {
+	Name string
+	// Revision is a content hash of the template tree
+	Revision string
+}
+
+// SetCurrentPlugin sets the plugin that file writes are attributed to
+func (ctx *ExecutionContext) SetCurrentPlugin(name string) {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	ctx.currentPlugin = name
+}
+
+// CurrentPlugin returns the plugin whose hook is running
+func (ctx *ExecutionContext) CurrentPlugin() string {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	return ctx.currentPlugin
+}
+
+// Path resolves a path relative to the project root. Absolute paths are
+// returned unchanged.
+func (ctx *ExecutionContext) Path(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(ctx.ProjectPath, path)
+}
+
+// WriteFile writes a project file through the execution filesystem and
+// records which plugin wrote it
+func (ctx *ExecutionContext) WriteFile(path, content string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fullPath := ctx.Path(path)
+	if err := utils.WriteFileFS(ctx.FS, fullPath, content); err != nil {
+		return err
+	}
+
+	relPath, err := ctx.relPath(path)
+	if err != nil {
+		return err
+	}
+
+	ctx.mutex.Lock()
+	record := FileRecord{
+		Path:   relPath,
+		Plugin: ctx.currentPlugin,
+		Size:   int64(len(content)),
+	}
+	ctx.files[relPath] = record
+	ctx.mutex.Unlock()
+
+	if ctx.OnFile != nil {
+		ctx.OnFile(record)
+	}
+	return nil
+}
+
+// PatchFile merges p into a structured project file such as package.json,
+// creating the file if it doesn't exist. Keys that already have a different
+// value are resolved with policy; every key p sets is attributed to the
+// current plugin, and a conflict names the plugin that set the key first.
+func (ctx *ExecutionContext) PatchFile(path string, p patch.Object, policy patch.Policy) error {
+	ctx.patchMutex.Lock()
+	defer ctx.patchMutex.Unlock()
+
+	relPath, err := ctx.relPath(path)
+	if err != nil {
+		return err
+	}
+	content := ""
+	if ctx.FileExists(path) {
+		if content, err = ctx.ReadFile(path); err != nil {
+			return errors.Wrapf(err, "failed to read %s", relPath)
+		}
+	}
+
+	doc, err := patch.Parse(relPath, content)
+	if err != nil {
+		return err
+	}
+	changed, err := doc.Merge(p, policy)
+	var conflict *patch.ConflictError
+	if errors.As(err, &conflict) {
+		conflict.Owner = ctx.KeyOwner(relPath, conflict.Key)
+		conflict.Plugin = ctx.CurrentPlugin()
+		return conflict
+	}
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 && content != "" {
+		return nil
+	}
+
+	updated, err := doc.String()
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode %s", relPath)
+	}
+	if err := ctx.WriteFile(path, updated); err != nil {
+		return err
+	}
+
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	if ctx.keys[relPath] == nil {
+		ctx.keys[relPath] = make(map[string]string)
+	}
+	for _, key := range changed {
+		ctx.keys[relPath][key] = ctx.currentPlugin
+	}
+	return nil
+}
+
+// KeyOwner returns the plugin that set a key of a patched file, or the
+// plugin that wrote the file when the key wasn't patched
+func (ctx *ExecutionContext) KeyOwner(path, key string) string {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	if owner, ok := ctx.keys[path][key]; ok {
+		return owner
+	}
+	return ctx.files[path].Plugin
+}
+
+// relPath returns the slash-separated path of a project file relative to
+// the project root
+func (ctx *ExecutionContext) relPath(path string) (string, error) {
+	relPath, err := filepath.Rel(ctx.ProjectPath, ctx.Path(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relPath), nil
+}
+
+// EnsureDir creates a project directory through the execution filesystem
+func (ctx *ExecutionContext) EnsureDir(path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return utils.EnsureDirFS(ctx.FS, ctx.Path(path))
+}
+
+// MakeExecutable marks a project file as executable, e.g. a script copied
+// from a template
+func (ctx *ExecutionContext) MakeExecutable(path string) error {
+	return ctx.FS.Chmod(ctx.Path(path), 0700)
+}
+
+// ReadFile reads a project file through the execution filesystem
+func (ctx *ExecutionContext) ReadFile(path string) (string, error) {
+	return utils.ReadFileFS(ctx.FS, ctx.Path(path))
+}
+
+// FileExists checks if a project file exists on the execution filesystem
+func (ctx *ExecutionContext) FileExists(path string) bool {
+	return utils.FileExistsFS(ctx.FS, ctx.Path(path))
+}
+
+// WrittenFiles returns every file written so far, sorted by path
+func (ctx *ExecutionContext) WrittenFiles() []FileRecord {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+
+	records := make([]FileRecord, 0, len(ctx.files))
+	for _, record := range ctx.files {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Path < records[j].Path
+	})
+	return records
+}
+
+// SkipStep records that the current plugin skipped a side effect
+func (ctx *ExecutionContext) SkipStep(step, reason string) {
+	ctx.mutex.Lock()
+	skipped := SkippedStep{
+		Plugin: ctx.currentPlugin,
+		Step:   step,
+		Reason: reason,
+	}
+	ctx.skipped = append(ctx.skipped, skipped)
+	ctx.mutex.Unlock()
+
+	if ctx.OnSkip != nil {
+		ctx.OnSkip(skipped)
+	}
+}
+
+// SkippedSteps returns the side effects skipped during generation
+func (ctx *ExecutionContext) SkippedSteps() []SkippedStep {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	return append([]SkippedStep(nil), ctx.skipped...)
+}
+
+// RecordTemplate records that a template tree was rendered
+func (ctx *ExecutionContext) RecordTemplate(name, revision string) {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	for _, record := range ctx.templates {
+		if record.Name == name && record.Revision == revision {
+			return
+		}
+	}
+	ctx.templates = append(ctx.templates, TemplateRecord{Name: name, Revision: revision})
+}
+
+// RenderedTemplates returns the template trees rendered so far
+func (ctx *ExecutionContext) RenderedTemplates() []TemplateRecord {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+	return append([]TemplateRecord(nil), ctx.templates...)
+}
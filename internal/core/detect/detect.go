@@ -0,0 +1,245 @@
+// Package detect works out the framework, build tool and features of a
+// project that already exists, so features can be added to it.
+package detect
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/engine"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// Project is what was found out about an existing project
+type Project struct {
+	Config tilocontext.ProjectConfig
+	// Source is the file the framework was read from, such as the
+	// lockfile or package.json
+	Source string
+}
+
+// marker maps a dependency found in a manifest to a framework
+type marker struct {
+	dependency string
+	framework  string
+}
+
+// javascriptMarkers are checked in order, so meta-frameworks win over the
+// libraries they build on
+var javascriptMarkers = []marker{
+	{"next", "next"},
+	{"nuxt", "nuxt"},
+	{"@angular/core", "angular"},
+	{"react-native", "react-native"},
+	{"@ionic/core", "ionic"},
+	{"electron", "electron"},
+	{"@nestjs/core", "nestjs"},
+	{"fastify", "fastify"},
+	{"express", "express"},
+	{"react", "react"},
+	{"vue", "vue"},
+	{"svelte", "svelte"},
+}
+
+// manifestDetector reads the framework and build tool from one manifest
+type manifestDetector struct {
+	file   string
+	detect func(dir, content string) (framework, buildTool string)
+}
+
+var detectors = []manifestDetector{
+	{"package.json", detectJavaScript},
+	{"go.mod", detectGo},
+	{"pyproject.toml", detectPython},
+	{"requirements.txt", detectPython},
+	{"Pipfile", detectPython},
+	{"composer.json", detectContains("composer", marker{"laravel/framework", "laravel"}, marker{"symfony/framework-bundle", "symfony"})},
+	{"pom.xml", detectContains("maven", marker{"spring-boot", "spring-boot"}, marker{"quarkus", "quarkus"})},
+	{"build.gradle", detectContains("gradle", marker{"spring-boot", "spring-boot"}, marker{"quarkus", "quarkus"})},
+	{"build.gradle.kts", detectContains("gradle", marker{"spring-boot", "spring-boot"}, marker{"quarkus", "quarkus"})},
+	{"Cargo.toml", detectContains("cargo", marker{"actix-web", "actix"}, marker{"rocket", "rocket"}, marker{"axum", "axum"})},
+	{"Gemfile", detectContains("bundler", marker{"rails", "rails"}, marker{"sinatra", "sinatra"})},
+	{"pubspec.yaml", detectContains("flutter-cli", marker{"flutter", "flutter"})},
+}
+
+// Detect inspects the project in dir. A TiLoKit lockfile is trusted first;
+// otherwise the framework and build tool come from the project's manifests
+// and the features from the files they add.
+func Detect(dir string) (*Project, error) {
+	if !utils.DirExists(dir) {
+		return nil, errors.Errorf("project directory %s does not exist", dir)
+	}
+
+	if lock, err := engine.ReadLock(dir); err == nil {
+		return &Project{Config: lock.Config, Source: engine.LockPath}, nil
+	} else if utils.FileExists(filepath.Join(dir, filepath.FromSlash(engine.LockPath))) {
+		return nil, err
+	}
+
+	for _, detector := range detectors {
+		content, err := utils.ReadFile(filepath.Join(dir, detector.file))
+		if err != nil {
+			continue
+		}
+		framework, buildTool := detector.detect(dir, content)
+		if framework == "" {
+			continue
+		}
+		project := &Project{
+			Config: tilocontext.ProjectConfig{
+				ProjectName: filepath.Base(dir),
+				Framework:   framework,
+				BuildTool:   buildTool,
+			},
+			Source: detector.file,
+		}
+		project.Config.Features = detectFeatures(dir, framework, detector.file, content)
+		return project, nil
+	}
+
+	return nil, errors.Errorf("could not detect the framework of %s; expected a TiLoKit lockfile or a manifest such as package.json, go.mod or pyproject.toml", dir)
+}
+
+// detectFeatures lists the built-in features whose files are present
+func detectFeatures(dir, framework, manifestFile, manifest string) []string {
+	exists := func(patterns ...string) bool {
+		for _, pattern := range patterns {
+			if matches, _ := filepath.Glob(filepath.Join(dir, pattern)); len(matches) > 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	found := map[string]bool{
+		features.Git:    exists(".git"),
+		features.Docker: exists("Dockerfile"),
+		features.CI:     exists(".github/workflows/*.yml", ".github/workflows/*.yaml"),
+	}
+	if manifestFile == "package.json" {
+		found[features.TypeScript] = exists("tsconfig.json")
+		found[features.JavaScript] = !found[features.TypeScript]
+		found[features.ESLint] = exists(".eslintrc*", "eslint.config.*")
+		found[features.Prettier] = exists(".prettierrc*", "prettier.config.*")
+		found[features.Testing] = strings.Contains(manifest, `"vitest"`) || strings.Contains(manifest, `"jest"`)
+	} else {
+		// pytest and cargo test keep their tests in tests/
+		found[features.Testing] = exists("pytest.ini", "conftest.py", "tests/test_*.py", "tests/*.rs")
+	}
+
+	var names []string
+	for _, feature := range features.Builtin().Available(framework) {
+		if found[feature.Name] {
+			names = append(names, feature.Name)
+		}
+	}
+	return names
+}
+
+func detectJavaScript(dir, content string) (string, string) {
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal([]byte(content), &pkg); err != nil {
+		return "", ""
+	}
+	has := func(name string) bool {
+		_, inDeps := pkg.Dependencies[name]
+		_, inDevDeps := pkg.DevDependencies[name]
+		return inDeps || inDevDeps
+	}
+
+	framework := ""
+	for _, m := range javascriptMarkers {
+		if has(m.dependency) {
+			framework = m.framework
+			break
+		}
+	}
+
+	switch {
+	case framework == "":
+		return "", ""
+	case framework == "next" || framework == "nuxt":
+		return framework, framework
+	case framework == "angular":
+		return framework, "angular-cli"
+	}
+	for _, bundler := range []string{"vite", "webpack", "rollup", "parcel"} {
+		if has(bundler) {
+			return framework, bundler
+		}
+	}
+	// Projects without a bundler are built by their package manager
+	switch {
+	case utils.FileExists(filepath.Join(dir, "pnpm-lock.yaml")):
+		return framework, "pnpm"
+	case utils.FileExists(filepath.Join(dir, "yarn.lock")):
+		return framework, "yarn"
+	}
+	return framework, "npm"
+}
+
+var goMarkers = []marker{
+	{"github.com/wailsapp/wails", "wails"},
+	{"github.com/gin-gonic/gin", "gin"},
+	{"github.com/labstack/echo", "echo"},
+	{"github.com/gofiber/fiber", "fiber"},
+}
+
+func detectGo(dir, content string) (string, string) {
+	for _, m := range goMarkers {
+		if regexp.MustCompile(`(?m)^\s*(require\s+)?` + regexp.QuoteMeta(m.dependency) + `(/v\d+)?\s`).MatchString(content) {
+			return m.framework, "go-modules"
+		}
+	}
+	return "", ""
+}
+
+func detectPython(dir, content string) (string, string) {
+	lower := strings.ToLower(content)
+	framework := ""
+	for _, name := range []string{"django", "fastapi", "flask"} {
+		if regexp.MustCompile(`(^|[\s"'\[])` + name + `\b`).MatchString(lower) {
+			framework = name
+			break
+		}
+	}
+	if framework == "" {
+		return "", ""
+	}
+
+	switch {
+	case utils.FileExists(filepath.Join(dir, "Pipfile")):
+		return framework, "pipenv"
+	case utils.FileExists(filepath.Join(dir, "pyproject.toml")) && fileContains(filepath.Join(dir, "pyproject.toml"), "[tool.poetry"):
+		return framework, "poetry"
+	}
+	return framework, "pip"
+}
+
+// detectContains returns a detector matching the first marker that occurs
+// anywhere in the manifest
+func detectContains(buildTool string, markers ...marker) func(dir, content string) (string, string) {
+	return func(dir, content string) (string, string) {
+		for _, m := range markers {
+			if strings.Contains(content, m.dependency) {
+				return m.framework, buildTool
+			}
+		}
+		return "", ""
+	}
+}
+
+func fileContains(path, text string) bool {
+	data, err := os.ReadFile(path) // #nosec G304 - path is a manifest inside the project
+	return err == nil && strings.Contains(string(data), text)
+}
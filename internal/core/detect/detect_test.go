@@ -0,0 +1,136 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "project")
+	for path, content := range files {
+		fullPath := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDetectFromManifests(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		framework string
+		buildTool string
+		features  []string
+	}{
+		{
+			name: "react with vite",
+			files: map[string]string{
+				"package.json":   `{"dependencies": {"react": "^18.2.0"}, "devDependencies": {"vite": "^5.0.0", "vitest": "^1.0.0"}}`,
+				"tsconfig.json":  "{}",
+				".eslintrc.cjs":  "module.exports = {}",
+				".git/HEAD":      "ref: refs/heads/main\n",
+				"src/App.tsx":    "",
+				"Dockerfile":     "FROM node:20\n",
+				".prettierrc.js": "",
+			},
+			framework: "react",
+			buildTool: "vite",
+			features:  []string{"typescript", "eslint", "prettier", "testing", "docker", "git"},
+		},
+		{
+			name:      "express with yarn",
+			files:     map[string]string{"package.json": `{"dependencies": {"express": "^4.18.0"}}`, "yarn.lock": ""},
+			framework: "express",
+			buildTool: "yarn",
+		},
+		{
+			name:      "gin",
+			files:     map[string]string{"go.mod": "module example.com/api\n\nrequire (\n\tgithub.com/gin-gonic/gin v1.9.1\n)\n"},
+			framework: "gin",
+			buildTool: "go-modules",
+		},
+		{
+			name: "gin with docker",
+			files: map[string]string{
+				"go.mod":                   "module example.com/api\n\nrequire github.com/gin-gonic/gin v1.9.1\n",
+				"Dockerfile":               "FROM golang:1.21\n",
+				".github/workflows/ci.yml": "name: CI\n",
+			},
+			framework: "gin",
+			buildTool: "go-modules",
+			features:  []string{"docker", "ci"},
+		},
+		{
+			name:      "django with poetry",
+			files:     map[string]string{"pyproject.toml": "[tool.poetry.dependencies]\npython = \"^3.11\"\nDjango = \"^4.2\"\n"},
+			framework: "django",
+			buildTool: "poetry",
+		},
+		{
+			name:      "fastapi with pip",
+			files:     map[string]string{"requirements.txt": "fastapi>=0.110\nuvicorn\n"},
+			framework: "fastapi",
+			buildTool: "pip",
+		},
+		{
+			name:      "flask with pytest",
+			files:     map[string]string{"requirements.txt": "flask\n", "tests/test_app.py": ""},
+			framework: "flask",
+			buildTool: "pip",
+			features:  []string{"testing"},
+		},
+		{
+			name:      "spring boot",
+			files:     map[string]string{"pom.xml": "<project><parent><artifactId>spring-boot-starter-parent</artifactId></parent></project>"},
+			framework: "spring-boot",
+			buildTool: "maven",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, err := Detect(writeFiles(t, tt.files))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if project.Config.Framework != tt.framework || project.Config.BuildTool != tt.buildTool {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.framework, tt.buildTool, project.Config.Framework, project.Config.BuildTool)
+			}
+			if project.Config.ProjectName != "project" {
+				t.Errorf("Expected the directory name as project name, got %q", project.Config.ProjectName)
+			}
+			if !reflect.DeepEqual(project.Config.Features, tt.features) {
+				t.Errorf("Expected features %v, got %v", tt.features, project.Config.Features)
+			}
+		})
+	}
+}
+
+func TestDetectPrefersLockfile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		".tilokit/lock.yaml": "version: 1\nconfig:\n  project_name: shop\n  framework: vue\n  build_tool: vite\n  features: [javascript]\n",
+		"package.json":       `{"dependencies": {"react": "^18.2.0"}}`,
+	})
+
+	project, err := Detect(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Source != ".tilokit/lock.yaml" || project.Config.Framework != "vue" || project.Config.ProjectName != "shop" {
+		t.Errorf("Expected the lockfile to win, got %+v", project)
+	}
+}
+
+func TestDetectUnknownProject(t *testing.T) {
+	if _, err := Detect(writeFiles(t, map[string]string{"README.md": "# notes\n"})); err == nil {
+		t.Error("Expected an error for a directory without a known manifest")
+	}
+}
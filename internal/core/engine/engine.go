@@ -4,18 +4,22 @@ package engine
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
 	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/internal/utils"
 )
 
 // Engine represents the core execution engine for TiLoKit
 type Engine struct {
 	registry *registry.PluginRegistry
 	logger   *logrus.Logger
+	onEvent  func(Event)
 }
 
 // New creates a new Engine instance
@@ -31,28 +35,234 @@ func (e *Engine) RegisterPlugin(plugin registry.Plugin) error {
 	return e.registry.Register(plugin)
 }
 
+// Registry returns the registry of the engine's plugins
+func (e *Engine) Registry() *registry.PluginRegistry {
+	return e.registry
+}
+
+// ExplainPlugins reports why each registered plugin would or wouldn't be
+// selected for the given configuration
+func (e *Engine) ExplainPlugins(config *tilocontext.ProjectConfig) []registry.Decision {
+	return e.registry.Explain(config.Framework, config.BuildTool, config.ExcludePlugins)
+}
+
 // Execute runs the project generation process
 func (e *Engine) Execute(ctx context.Context, config *tilocontext.ProjectConfig) error {
 	e.logger.Info("Starting project generation...")
 
+	if _, err := e.Generate(ctx, config, nil); err != nil {
+		return err
+	}
+
+	e.logger.Info("Project generation completed successfully!")
+	return nil
+}
+
+// Output describes a generated project
+type Output struct {
+	// FS holds the project, the host filesystem unless one was given
+	FS utils.FS
+	// Root is the project directory on FS
+	Root    string
+	Files   []tilocontext.FileRecord
+	Skipped []tilocontext.SkippedStep
+	// Templates lists the template trees that were rendered
+	Templates []tilocontext.TemplateRecord
+	// StartTime is when generation started
+	StartTime time.Time
+}
+
+// Generate runs the project generation process and describes its result.
+// With a nil fsys the project is staged on disk and moved into its target
+// directory only on success. Any other filesystem receives the project
+// directly and is treated as virtual: plugins skip side effects such as
+// git init and report them in Output.Skipped.
+func (e *Engine) Generate(ctx context.Context, config *tilocontext.ProjectConfig, fsys utils.FS) (*Output, error) {
+	return e.generate(ctx, config, fsys, nil)
+}
+
+// generate is Generate with the shipped template trees named in templates
+// replaced
+func (e *Engine) generate(ctx context.Context, config *tilocontext.ProjectConfig, fsys utils.FS, templates map[string]fs.FS) (*Output, error) {
+	execCtx, plugins, err := e.prepare(ctx, config, nil)
+	if err != nil {
+		return nil, err
+	}
+	execCtx.Templates = templates
+
+	if fsys == nil {
+		err = e.run(execCtx, plugins)
+	} else {
+		execCtx.FS = fsys
+		execCtx.Virtual = true
+		err = e.runLifecycle(execCtx, plugins)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Output{
+		FS:        execCtx.FS,
+		Root:      execCtx.TargetPath,
+		Files:     execCtx.WrittenFiles(),
+		Skipped:   execCtx.SkippedSteps(),
+		Templates: execCtx.RenderedTemplates(),
+		StartTime: execCtx.StartTime,
+	}, nil
+}
+
+// Questions returns the questions the plugins selected for the
+// configuration ask, in schedule order, leaving out those whose variables
+// are already set
+func (e *Engine) Questions(config *tilocontext.ProjectConfig) ([]registry.Question, error) {
+	plugins, err := e.registry.LoadPlugins(config.Framework, config.BuildTool, config.ExcludePlugins...)
+	if err != nil {
+		return nil, &ConfigError{Err: errors.Wrap(err, "failed to load plugins")}
+	}
+	plugins, err = registry.Schedule(plugins)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to schedule plugins")
+	}
+
+	var questions []registry.Question
+	for _, plugin := range plugins {
+		prompter, ok := plugin.(registry.Prompter)
+		if !ok {
+			continue
+		}
+		for _, q := range prompter.Questions(config) {
+			if _, set := config.Variables[q.Name]; !set {
+				questions = append(questions, q)
+			}
+		}
+	}
+	return questions, nil
+}
+
+// Augment adds features to the existing project at the configuration's
+// target path. Config.Features lists every feature the project will have;
+// only the plugins providing one of the added features run, in augment
+// mode, against a staged copy of the project.
+func (e *Engine) Augment(ctx context.Context, config *tilocontext.ProjectConfig, added []string) error {
+	if len(added) == 0 {
+		return errors.New("no features to add")
+	}
+
+	execCtx, plugins, err := e.prepare(ctx, config, added)
+	if err != nil {
+		return err
+	}
+	return e.run(execCtx, plugins)
+}
+
+// run executes the lifecycle in a transaction and moves the result into
+// the target directory
+func (e *Engine) run(execCtx *tilocontext.ExecutionContext, plugins []registry.Plugin) error {
+	// Stage all output so a failure leaves the target directory untouched
+	tx, err := beginTransaction(execCtx)
+	if err != nil {
+		return err
+	}
+
+	if err := e.runLifecycle(execCtx, plugins); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	if err := tx.commit(); err != nil {
+		tx.rollback()
+		return errors.Wrap(err, "failed to commit generated project")
+	}
+	return nil
+}
+
+// prepare validates the configuration and returns a fresh execution context
+// together with the scheduled plugins for it. With added features the
+// context is in augment mode and only their plugins are loaded.
+func (e *Engine) prepare(ctx context.Context, config *tilocontext.ProjectConfig, added []string) (*tilocontext.ExecutionContext, []registry.Plugin, error) {
 	// Create execution context
 	execCtx := tilocontext.NewExecutionContext(config)
+	execCtx.Context = ctx
+	execCtx.Augment = len(added) > 0
+	execCtx.AddedFeatures = added
 
 	// Validate configuration
 	if err := e.validateConfig(config); err != nil {
-		return errors.Wrap(err, "configuration validation failed")
+		return nil, nil, &ConfigError{Err: errors.Wrap(err, "configuration validation failed")}
+	}
+	if e.onEvent != nil {
+		execCtx.OnFile = func(record tilocontext.FileRecord) {
+			e.emit(Event{Kind: EventFile, Plugin: record.Plugin, Path: record.Path})
+		}
+		execCtx.OnSkip = func(step tilocontext.SkippedStep) {
+			e.emit(Event{Kind: EventSkip, Plugin: step.Plugin, Step: step.Step, Reason: step.Reason})
+		}
 	}
 
 	// Load required plugins
-	plugins, err := e.registry.LoadPlugins(config.Framework, config.BuildTool)
+	var plugins []registry.Plugin
+	var err error
+	if execCtx.Augment {
+		plugins, err = e.registry.LoadFeaturePlugins(config.Framework, config.BuildTool, added, config.ExcludePlugins...)
+	} else {
+		plugins, err = e.registry.LoadPlugins(config.Framework, config.BuildTool, config.ExcludePlugins...)
+	}
 	if err != nil {
-		return errors.Wrap(err, "failed to load plugins")
+		return nil, nil, &ConfigError{Err: errors.Wrap(err, "failed to load plugins")}
+	}
+
+	// Order plugins by their declared dependencies
+	plugins, err = registry.Schedule(plugins)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to schedule plugins")
+	}
+
+	// Check the options given to each plugin, let plugins reject the
+	// configuration before anything is written, and answer the questions
+	// nobody was asked with their defaults
+	for _, plugin := range plugins {
+		manifest := registry.ManifestOf(plugin)
+		values := config.PluginOptions[plugin.Name()]
+		if err := manifest.CheckOptions(values); err != nil {
+			return nil, nil, &ConfigError{Err: err}
+		}
+		execCtx.SetOptions(plugin.Name(), manifest.OptionValues(values))
+
+		if prompter, ok := plugin.(registry.Prompter); ok {
+			for _, q := range prompter.Questions(config) {
+				if _, set := execCtx.GetVariable(q.Name); set {
+					continue
+				}
+				if q.Required && q.Default == nil {
+					return nil, nil, &ConfigError{Err: errors.Errorf("plugin %s needs a value for %s", plugin.Name(), q.Name)}
+				}
+				execCtx.SetVariable(q.Name, q.Default)
+			}
+		}
+		if validator, ok := plugin.(registry.Validator); ok {
+			if err := validator.Validate(config); err != nil {
+				return nil, nil, &ConfigError{Err: errors.Wrapf(err, "plugin %s rejected the configuration", plugin.Name())}
+			}
+		}
 	}
 
+	// Collect template functions contributed by the selected plugins
+	for _, plugin := range plugins {
+		if provider, ok := plugin.(registry.TemplateFuncProvider); ok {
+			for name, fn := range provider.TemplateFuncs() {
+				execCtx.TemplateFuncs[name] = fn
+			}
+		}
+	}
+
+	return execCtx, plugins, nil
+}
+
+func (e *Engine) runLifecycle(execCtx *tilocontext.ExecutionContext, plugins []registry.Plugin) error {
 	// Execute lifecycle hooks
 	for _, plugin := range plugins {
-		if err := plugin.PreGenerate(execCtx); err != nil {
-			return errors.Wrapf(err, "pre-generate hook failed for plugin %s", plugin.Name())
+		if err := e.runHook(execCtx, plugin, PhasePreGenerate, plugin.PreGenerate); err != nil {
+			return err
 		}
 	}
 
@@ -61,14 +271,36 @@ func (e *Engine) Execute(ctx context.Context, config *tilocontext.ProjectConfig)
 		return errors.Wrap(err, "project generation failed")
 	}
 
-	// Execute post-generation hooks
+	// Execute post-generation hooks. The lockfile is refreshed before each
+	// one, so a hook committing the project sees what earlier hooks wrote,
+	// and once more for what the last hook wrote.
 	for _, plugin := range plugins {
-		if err := plugin.PostGenerate(execCtx); err != nil {
-			return errors.Wrapf(err, "post-generate hook failed for plugin %s", plugin.Name())
+		if err := writeLock(execCtx, plugins); err != nil {
+			return errors.Wrap(err, "failed to write lockfile")
+		}
+		if err := e.runHook(execCtx, plugin, PhasePostGenerate, plugin.PostGenerate); err != nil {
+			return err
 		}
 	}
+	if err := writeLock(execCtx, plugins); err != nil {
+		return errors.Wrap(err, "failed to write lockfile")
+	}
 
-	e.logger.Info("Project generation completed successfully!")
+	execCtx.SetCurrentPlugin("")
+	return errors.Wrap(execCtx.Err(), "generation cancelled")
+}
+
+// runHook runs one hook of a plugin, attributing what it writes to the
+// plugin
+func (e *Engine) runHook(execCtx *tilocontext.ExecutionContext, plugin registry.Plugin, phase string, hook func(*tilocontext.ExecutionContext) error) error {
+	if err := execCtx.Err(); err != nil {
+		return errors.Wrap(err, "generation cancelled")
+	}
+	execCtx.SetCurrentPlugin(plugin.Name())
+	e.emit(Event{Kind: EventHook, Phase: phase, Plugin: plugin.Name()})
+	if err := hook(execCtx); err != nil {
+		return &PluginError{Plugin: plugin.Name(), Phase: phase, Err: err}
+	}
 	return nil
 }
 
@@ -84,8 +316,12 @@ func (e *Engine) validateConfig(config *tilocontext.ProjectConfig) error {
 
 func (e *Engine) generateProject(ctx *tilocontext.ExecutionContext, plugins []registry.Plugin) error {
 	for _, plugin := range plugins {
-		if err := plugin.Generate(ctx); err != nil {
-			return errors.Wrapf(err, "generation failed for plugin %s", plugin.Name())
+		phase, hook := PhaseGenerate, plugin.Generate
+		if augmenter, ok := plugin.(registry.Augmenter); ok && ctx.Augment {
+			phase, hook = PhaseAugment, augmenter.Augment
+		}
+		if err := e.runHook(ctx, plugin, phase, hook); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -1,9 +1,15 @@
 package engine
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 func TestEngineNew(t *testing.T) {
@@ -36,3 +42,488 @@ func (p *MockPlugin) SupportedBuildTools() []string                        { ret
 func (p *MockPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error  { return nil }
 func (p *MockPlugin) Generate(ctx *tilocontext.ExecutionContext) error     { return nil }
 func (p *MockPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error { return nil }
+
+func TestExecuteCommitsStagedProject(t *testing.T) {
+	outputDir := t.TempDir()
+	engine := New()
+	if err := engine.RegisterPlugin(&writerPlugin{files: map[string]string{"hello.txt": "hello"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: outputDir}
+	if err := engine.Execute(context.Background(), config); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "app", "hello.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("Expected generated file in target, got %q (%v)", data, err)
+	}
+	assertNoStagingLeft(t, outputDir)
+}
+
+func TestExecuteRollsBackOnFailure(t *testing.T) {
+	outputDir := t.TempDir()
+	target := filepath.Join(outputDir, "app")
+	if err := os.MkdirAll(target, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "keep.txt"), []byte("original"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := New()
+	plugin := &writerPlugin{
+		files:   map[string]string{"keep.txt": "changed", "new.txt": "new"},
+		failErr: errors.New("boom"),
+	}
+	if err := engine.RegisterPlugin(plugin); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: outputDir}
+	if err := engine.Execute(context.Background(), config); err == nil {
+		t.Fatal("Expected generation error, got nil")
+	}
+
+	data, err := os.ReadFile(filepath.Join(target, "keep.txt"))
+	if err != nil || string(data) != "original" {
+		t.Fatalf("Expected existing file to be untouched, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("Expected new file to be rolled back, got: %v", err)
+	}
+	assertNoStagingLeft(t, outputDir)
+}
+
+func assertNoStagingLeft(t *testing.T, outputDir string) {
+	t.Helper()
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "app" {
+			t.Fatalf("Expected staging directory to be removed, found %s", entry.Name())
+		}
+	}
+}
+
+// writerPlugin writes files during Generate and optionally fails afterwards
+type writerPlugin struct {
+	MockPlugin
+	files   map[string]string
+	failErr error
+}
+
+func (p *writerPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	for path, content := range p.files {
+		if err := ctx.WriteFile(path, content); err != nil {
+			return err
+		}
+	}
+	return p.failErr
+}
+
+func TestPlanDoesNotTouchDisk(t *testing.T) {
+	outputDir := t.TempDir()
+	target := filepath.Join(outputDir, "app")
+	if err := os.MkdirAll(target, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "keep.txt"), []byte("original\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := New()
+	plugin := &writerPlugin{files: map[string]string{"keep.txt": "changed\n", "new.txt": "new\n"}}
+	if err := engine.RegisterPlugin(plugin); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: outputDir}
+	plan, err := engine.Plan(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(plan.Files) != 3 {
+		t.Fatalf("Expected 2 planned files and the lockfile, got %d", len(plan.Files))
+	}
+	lock, keep, created := plan.Files[0], plan.Files[1], plan.Files[2]
+	if lock.Path != LockPath || lock.Status != FileNew {
+		t.Fatalf("Expected a new lockfile, got %+v", lock)
+	}
+	if keep.Status != FileModified || keep.Plugin != "mock" || keep.Diff == "" {
+		t.Fatalf("Expected keep.txt to be modified by mock with a diff, got %+v", keep)
+	}
+	if created.Status != FileNew {
+		t.Fatalf("Expected new.txt to be new, got %s", created.Status)
+	}
+
+	data, err := os.ReadFile(filepath.Join(target, "keep.txt"))
+	if err != nil || string(data) != "original\n" {
+		t.Fatalf("Expected disk to be untouched, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("Expected new.txt not to be written, got: %v", err)
+	}
+}
+
+func TestExecuteStopsOnCancellation(t *testing.T) {
+	for _, phase := range []string{"pre", "generate", "post"} {
+		t.Run(phase, func(t *testing.T) {
+			outputDir := t.TempDir()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			engine := New()
+			first := &cancelPlugin{name: "a-first", phase: phase, cancel: cancel}
+			second := &cancelPlugin{name: "b-second"}
+			for _, plugin := range []*cancelPlugin{first, second} {
+				if err := engine.RegisterPlugin(plugin); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: outputDir}
+			err := engine.Execute(ctx, config)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expected context.Canceled, got: %v", err)
+			}
+
+			if _, err := os.Stat(filepath.Join(outputDir, "app")); !os.IsNotExist(err) {
+				t.Fatalf("Expected no project directory after cancellation, got: %v", err)
+			}
+			entries, err := os.ReadDir(outputDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Fatalf("Expected staging directory to be removed, found %s", entries[0].Name())
+			}
+			if second.ran[phase] {
+				t.Fatalf("Expected second plugin not to run its %s hook after cancellation", phase)
+			}
+		})
+	}
+}
+
+func TestExecuteRejectsCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	engine := New()
+	plugin := &cancelPlugin{name: "mock"}
+	if err := engine.RegisterPlugin(plugin); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: t.TempDir()}
+	if err := engine.Execute(ctx, config); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+	if len(plugin.ran) != 0 {
+		t.Fatalf("Expected no hooks to run, got %v", plugin.ran)
+	}
+}
+
+// cancelPlugin cancels the run during one lifecycle phase, then tries to
+// keep writing so cancellation inside a hook is exercised too
+type cancelPlugin struct {
+	MockPlugin
+	name   string
+	phase  string
+	cancel context.CancelFunc
+	ran    map[string]bool
+}
+
+func (p *cancelPlugin) Name() string { return p.name }
+
+func (p *cancelPlugin) run(ctx *tilocontext.ExecutionContext, phase string) error {
+	if p.ran == nil {
+		p.ran = make(map[string]bool)
+	}
+	p.ran[phase] = true
+
+	if err := ctx.WriteFile(phase+"-before.txt", phase); err != nil {
+		return err
+	}
+	if p.phase == phase {
+		p.cancel()
+		return ctx.WriteFile(phase+"-after.txt", phase)
+	}
+	return nil
+}
+
+func (p *cancelPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
+	return p.run(ctx, "pre")
+}
+
+func (p *cancelPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	return p.run(ctx, "generate")
+}
+
+func (p *cancelPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
+	return p.run(ctx, "post")
+}
+
+type funcPlugin struct {
+	MockPlugin
+	seen map[string]interface{}
+}
+
+func (p *funcPlugin) TemplateFuncs() map[string]interface{} {
+	return map[string]interface{}{"shout": strings.ToUpper}
+}
+
+func (p *funcPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	p.seen = ctx.TemplateFuncs
+	return nil
+}
+
+func TestExecuteCollectsTemplateFuncs(t *testing.T) {
+	engine := New()
+	plugin := &funcPlugin{}
+	if err := engine.RegisterPlugin(plugin); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: t.TempDir()}
+	if err := engine.Execute(context.Background(), config); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, exists := plugin.seen["shout"]; !exists {
+		t.Fatalf("Expected plugin template funcs on the execution context, got %v", plugin.seen)
+	}
+}
+
+func TestExecuteWritesLock(t *testing.T) {
+	outputDir := t.TempDir()
+	engine := New()
+	if err := engine.RegisterPlugin(&writerPlugin{files: map[string]string{"hello.txt": "hello", "src/main.go": "package main\n"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &tilocontext.ProjectConfig{
+		ProjectName: "app",
+		Framework:   "mock",
+		BuildTool:   "mock",
+		OutputDir:   outputDir,
+		Features:    []string{"git"},
+		Variables:   map[string]interface{}{"database": "sqlite"},
+		Seed:        7,
+	}
+	if err := engine.Execute(context.Background(), config); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	lock, err := ReadLock(filepath.Join(outputDir, "app"))
+	if err != nil {
+		t.Fatalf("Expected lockfile to be readable, got: %v", err)
+	}
+	if lock.Version != LockVersion || lock.TiLoKitVersion == "" || lock.GeneratedAt.IsZero() {
+		t.Errorf("Expected version, TiLoKit version and time to be recorded, got %+v", lock)
+	}
+	if lock.Config.OutputDir != "" || lock.Config.Framework != "mock" || lock.Config.Seed != 7 {
+		t.Errorf("Expected generation inputs without the output directory, got %+v", lock.Config)
+	}
+	if lock.Config.Variables["database"] != "sqlite" || len(lock.Config.Features) != 1 {
+		t.Errorf("Expected answers and features to be recorded, got %+v", lock.Config)
+	}
+	if len(lock.Plugins) != 1 || lock.Plugins[0] != (LockedPlugin{Name: "mock", Version: "1.0.0"}) {
+		t.Errorf("Expected the mock plugin to be recorded, got %+v", lock.Plugins)
+	}
+
+	if len(lock.Files) != 2 {
+		t.Fatalf("Expected 2 locked files, got %+v", lock.Files)
+	}
+	file, ok := lock.File("src/main.go")
+	if !ok || file.Plugin != "mock" || file.SHA256 != HashContent("package main\n") {
+		t.Errorf("Expected src/main.go with its content hash, got %+v", file)
+	}
+	if _, ok := lock.File(LockPath); ok {
+		t.Error("Expected the lockfile not to list itself")
+	}
+}
+
+// postWriterPlugin writes a file after generation and records whether
+// the lockfile was already in place
+type postWriterPlugin struct {
+	MockPlugin
+	sawLock bool
+}
+
+func (p *postWriterPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
+	p.sawLock = ctx.FileExists(LockPath)
+	return ctx.WriteFile("post.txt", "written after generation")
+}
+
+func TestExecuteLocksPostGenerateOutput(t *testing.T) {
+	outputDir := t.TempDir()
+	engine := New()
+	plugin := &postWriterPlugin{}
+	if err := engine.RegisterPlugin(plugin); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: outputDir}
+	if err := engine.Execute(context.Background(), config); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !plugin.sawLock {
+		t.Error("Expected the lockfile to exist before post-generate hooks")
+	}
+	lock, err := ReadLock(filepath.Join(outputDir, "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file, ok := lock.File("post.txt"); !ok || file.SHA256 != HashContent("written after generation") {
+		t.Errorf("Expected post.txt to be locked, got %+v", lock.Files)
+	}
+}
+
+func TestParseLockRejectsNewerVersions(t *testing.T) {
+	if _, err := ParseLock([]byte("version: 99\n")); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected a newer lockfile to be rejected, got: %v", err)
+	}
+	if _, err := ParseLock([]byte("files: []\n")); err == nil {
+		t.Error("Expected a lockfile without a version to be rejected")
+	}
+}
+
+// featurePlugin adds a feature's files to an existing project
+type featurePlugin struct {
+	writerPlugin
+	augmented bool
+}
+
+func (p *featurePlugin) Name() string       { return "feature" }
+func (p *featurePlugin) Features() []string { return []string{"docker"} }
+
+func (p *featurePlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	p.augmented = ctx.Augment
+	return p.writerPlugin.Generate(ctx)
+}
+
+func TestAugmentKeepsExistingProject(t *testing.T) {
+	outputDir := t.TempDir()
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: outputDir}
+
+	engine := New()
+	writer := &writerPlugin{files: map[string]string{"main.go": "package main\n"}}
+	feature := &featurePlugin{writerPlugin: writerPlugin{files: map[string]string{"Dockerfile": "FROM scratch\n"}}}
+	if err := engine.RegisterPlugin(writer); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Execute(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RegisterPlugin(feature); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(outputDir, "app")
+	if err := os.WriteFile(filepath.Join(target, "main.go"), []byte("package main // edited\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := engine.Augment(context.Background(), config, []string{"docker"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !feature.augmented {
+		t.Error("Expected the feature plugin to run in augment mode")
+	}
+	data, err := os.ReadFile(filepath.Join(target, "main.go"))
+	if err != nil || string(data) != "package main // edited\n" {
+		t.Errorf("Expected existing files to be kept, got %q (%v)", data, err)
+	}
+
+	lock, err := ReadLock(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lock.File("main.go"); !ok {
+		t.Error("Expected the lockfile to keep the files generated before")
+	}
+	if file, ok := lock.File("Dockerfile"); !ok || file.Plugin != "feature" {
+		t.Errorf("Expected the added file to be locked, got %+v", file)
+	}
+	if len(lock.Plugins) != 2 {
+		t.Errorf("Expected both plugins to be recorded, got %+v", lock.Plugins)
+	}
+
+	if err := engine.Augment(context.Background(), config, []string{"ci"}); err == nil || !strings.Contains(err.Error(), "no plugin can add feature ci") {
+		t.Errorf("Expected a feature without a plugin to be rejected, got: %v", err)
+	}
+}
+
+// capablePlugin implements the optional Validator, Prompter and Augmenter
+// interfaces
+type capablePlugin struct {
+	featurePlugin
+	ran  []string
+	seen interface{}
+}
+
+func (p *capablePlugin) Validate(config *tilocontext.ProjectConfig) error {
+	if config.ProjectName == "forbidden" {
+		return errors.New("that name is taken")
+	}
+	return nil
+}
+
+func (p *capablePlugin) Questions(config *tilocontext.ProjectConfig) []registry.Question {
+	return []registry.Question{{Name: "region", Type: registry.OptionString, Default: "eu-west-1"}}
+}
+
+func (p *capablePlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	p.ran = append(p.ran, PhaseGenerate)
+	p.seen, _ = ctx.GetVariable("region")
+	return nil
+}
+
+func (p *capablePlugin) Augment(ctx *tilocontext.ExecutionContext) error {
+	p.ran = append(p.ran, PhaseAugment)
+	return nil
+}
+
+func TestCapabilityInterfaces(t *testing.T) {
+	engine := New()
+	plugin := &capablePlugin{}
+	if err := engine.RegisterPlugin(plugin); err != nil {
+		t.Fatal(err)
+	}
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: t.TempDir()}
+
+	questions, err := engine.Questions(config)
+	if err != nil || len(questions) != 1 || questions[0].Name != "region" {
+		t.Fatalf("Expected the plugin's question, got %v (%v)", questions, err)
+	}
+	config.Variables = map[string]interface{}{"region": "us-east-1"}
+	if questions, _ := engine.Questions(config); len(questions) != 0 {
+		t.Errorf("Expected answered questions to be left out, got %v", questions)
+	}
+	config.Variables = nil
+
+	if err := engine.Execute(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	if plugin.seen != "eu-west-1" {
+		t.Errorf("Expected the unasked question to get its default, got %v", plugin.seen)
+	}
+	if err := engine.Augment(context.Background(), config, []string{"docker"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(plugin.ran) != 2 || plugin.ran[1] != PhaseAugment {
+		t.Errorf("Expected Augment to replace Generate when adding features, got %v", plugin.ran)
+	}
+
+	config.ProjectName = "forbidden"
+	var configErr *ConfigError
+	err = engine.Execute(context.Background(), config)
+	if !errors.As(err, &configErr) || !strings.Contains(err.Error(), "plugin feature rejected the configuration: that name is taken") {
+		t.Errorf("Expected the validator to reject the configuration, got %v", err)
+	}
+}
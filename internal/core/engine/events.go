@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"fmt"
+)
+
+// Lifecycle phases, in the order they run
+const (
+	PhasePreGenerate  = "pre-generate"
+	PhaseGenerate     = "generate"
+	PhasePostGenerate = "post-generate"
+)
+
+// PhaseAugment replaces PhaseGenerate when an Augmenter adds features
+const PhaseAugment = "augment"
+
+// EventKind identifies what happened during a run
+type EventKind string
+
+const (
+	// EventHook is sent before a plugin hook runs, with Phase and Plugin
+	EventHook EventKind = "hook"
+	// EventFile is sent after a file is written, with Plugin and Path
+	EventFile EventKind = "file"
+	// EventSkip is sent when a plugin skips a side effect, with Plugin,
+	// Step and Reason
+	EventSkip EventKind = "skip"
+)
+
+// Event reports progress of a run
+type Event struct {
+	Kind   EventKind
+	Phase  string
+	Plugin string
+	// Path is relative to the project root and uses forward slashes
+	Path   string
+	Step   string
+	Reason string
+}
+
+// OnEvent sets a function called with the events of later runs, from the
+// goroutine running the plugins. Nil stops reporting.
+func (e *Engine) OnEvent(fn func(Event)) {
+	e.onEvent = fn
+}
+
+func (e *Engine) emit(event Event) {
+	if e.onEvent != nil {
+		e.onEvent(event)
+	}
+}
+
+// PluginError is returned when a plugin hook fails
+type PluginError struct {
+	Plugin string
+	Phase  string
+	Err    error
+}
+
+func (e *PluginError) Error() string {
+	if e.Phase == PhaseGenerate {
+		return fmt.Sprintf("generation failed for plugin %s: %v", e.Plugin, e.Err)
+	}
+	return fmt.Sprintf("%s hook failed for plugin %s: %v", e.Phase, e.Plugin, e.Err)
+}
+
+func (e *PluginError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError is returned when a project configuration can't be generated,
+// e.g. because it has no name or no plugin supports its framework
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
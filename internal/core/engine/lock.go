@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/internal/utils"
+	"github.com/ti-lo/tilokit/pkg/constants"
+)
+
+// LockPath is where the lockfile is written, relative to the project root
+const LockPath = ".tilokit/lock.yaml"
+
+// LockVersion is the lockfile format written by this version of TiLoKit
+const LockVersion = 1
+
+// lockPlugin is the name lockfile writes are attributed to
+const lockPlugin = "tilokit"
+
+// Lock records how a project was generated: the inputs of the run and a
+// content hash of every file it wrote
+type Lock struct {
+	Version        int       `yaml:"version"`
+	TiLoKitVersion string    `yaml:"tilokit_version"`
+	GeneratedAt    time.Time `yaml:"generated_at"`
+	// Config holds the generation inputs, including resolved features and
+	// template answers. It can be generated again as a recipe.
+	Config    tilocontext.ProjectConfig `yaml:"config"`
+	Plugins   []LockedPlugin            `yaml:"plugins"`
+	Templates []LockedTemplate          `yaml:"templates,omitempty"`
+	Files     []LockedFile              `yaml:"files"`
+}
+
+// LockedPlugin is a plugin that took part in generation
+type LockedPlugin struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+// LockedTemplate is a template tree rendered during generation
+type LockedTemplate struct {
+	Name     string `yaml:"name"`
+	Revision string `yaml:"revision"`
+}
+
+// LockedFile is a generated file and the hash of its generated content
+type LockedFile struct {
+	Path   string `yaml:"path"`
+	Plugin string `yaml:"plugin"`
+	SHA256 string `yaml:"sha256"`
+}
+
+// File returns the locked file at path, if any
+func (l *Lock) File(path string) (LockedFile, bool) {
+	for _, file := range l.Files {
+		if file.Path == path {
+			return file, true
+		}
+	}
+	return LockedFile{}, false
+}
+
+// HashContent returns the hash recorded in the lockfile for content
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadLock reads the lockfile of the project in dir
+func ReadLock(dir string) (*Lock, error) {
+	content, err := utils.ReadFile(filepath.Join(dir, filepath.FromSlash(LockPath)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", LockPath)
+	}
+	return ParseLock([]byte(content))
+}
+
+// ParseLock decodes a lockfile
+func ParseLock(data []byte) (*Lock, error) {
+	lock := &Lock{}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", LockPath)
+	}
+	switch {
+	case lock.Version == 0:
+		return nil, errors.Errorf("invalid %s: version is required", LockPath)
+	case lock.Version > LockVersion:
+		return nil, errors.Errorf("%s version %d is newer than this TiLoKit supports (%d), please update", LockPath, lock.Version, LockVersion)
+	}
+	return lock, nil
+}
+
+// buildLock describes the run so far. The lockfile itself is not listed.
+func buildLock(ctx *tilocontext.ExecutionContext, plugins []registry.Plugin) (*Lock, error) {
+	lock := &Lock{
+		Version:        LockVersion,
+		TiLoKitVersion: constants.Version,
+		GeneratedAt:    ctx.StartTime.UTC().Truncate(time.Second),
+		Config:         *ctx.Config,
+	}
+	// Where the project was generated says nothing about the project
+	lock.Config.OutputDir = ""
+	lock.Config.Version = 0
+
+	// Adding features extends the project's existing lockfile
+	if ctx.Augment {
+		if content, err := ctx.ReadFile(LockPath); err == nil {
+			previous, err := ParseLock([]byte(content))
+			if err != nil {
+				return nil, err
+			}
+			lock.Plugins = previous.Plugins
+			lock.Templates = previous.Templates
+			lock.Files = previous.Files
+		}
+	}
+
+	for _, plugin := range plugins {
+		lock.Plugins = upsert(lock.Plugins, LockedPlugin{Name: plugin.Name(), Version: plugin.Version()},
+			func(p LockedPlugin) string { return p.Name })
+	}
+	for _, record := range ctx.RenderedTemplates() {
+		lock.Templates = upsert(lock.Templates, LockedTemplate{Name: record.Name, Revision: record.Revision},
+			func(t LockedTemplate) string { return t.Name })
+	}
+	for _, record := range ctx.WrittenFiles() {
+		if record.Path == LockPath {
+			continue
+		}
+		content, err := ctx.ReadFile(record.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to hash %s", record.Path)
+		}
+		lock.Files = upsert(lock.Files, LockedFile{
+			Path:   record.Path,
+			Plugin: record.Plugin,
+			SHA256: HashContent(content),
+		}, func(f LockedFile) string { return f.Path })
+	}
+	sort.Slice(lock.Files, func(i, j int) bool {
+		return lock.Files[i].Path < lock.Files[j].Path
+	})
+	return lock, nil
+}
+
+// upsert replaces the entry with item's key, or appends item
+func upsert[T any](entries []T, item T, key func(T) string) []T {
+	for i, entry := range entries {
+		if key(entry) == key(item) {
+			entries[i] = item
+			return entries
+		}
+	}
+	return append(entries, item)
+}
+
+// writeLock writes the lockfile into the project, unless it is already up
+// to date
+func writeLock(ctx *tilocontext.ExecutionContext, plugins []registry.Plugin) error {
+	lock, err := buildLock(ctx, plugins)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(lock); err != nil {
+		return errors.Wrapf(err, "failed to encode %s", LockPath)
+	}
+	if err := encoder.Close(); err != nil {
+		return errors.Wrapf(err, "failed to encode %s", LockPath)
+	}
+
+	if current, err := ctx.ReadFile(LockPath); err == nil && current == buf.String() {
+		return nil
+	}
+	ctx.SetCurrentPlugin(lockPlugin)
+	defer ctx.SetCurrentPlugin("")
+	return ctx.WriteFile(LockPath, buf.String())
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// FileStatus describes how a planned file relates to what is on disk
+type FileStatus string
+
+const (
+	// FileNew is a file that doesn't exist in the target directory yet
+	FileNew FileStatus = "new"
+	// FileModified is a file whose content on disk would change
+	FileModified FileStatus = "modified"
+	// FileUnchanged is a file whose content on disk already matches
+	FileUnchanged FileStatus = "unchanged"
+)
+
+// PlannedFile is a file a generation run would write
+type PlannedFile struct {
+	Path   string
+	Size   int64
+	Plugin string
+	Status FileStatus
+	// Diff is a unified diff against the file on disk for modified files
+	Diff string
+}
+
+// Plan describes the outcome of a dry run
+type Plan struct {
+	ProjectPath string
+	Files       []PlannedFile
+	Skipped     []tilocontext.SkippedStep
+}
+
+// Plan runs the whole plugin pipeline against an in-memory filesystem layered
+// over the target directory and reports what Execute would write, without
+// touching the disk.
+func (e *Engine) Plan(ctx context.Context, config *tilocontext.ProjectConfig) (*Plan, error) {
+	execCtx, plugins, err := e.prepare(ctx, config, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	disk := utils.NewOSFS()
+	execCtx.FS = utils.NewOverlayFS(disk)
+	execCtx.Virtual = true
+
+	if err := e.runLifecycle(execCtx, plugins); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		ProjectPath: execCtx.TargetPath,
+		Skipped:     execCtx.SkippedSteps(),
+	}
+
+	for _, record := range execCtx.WrittenFiles() {
+		planned := PlannedFile{
+			Path:   record.Path,
+			Size:   record.Size,
+			Plugin: record.Plugin,
+			Status: FileNew,
+		}
+
+		fullPath := execCtx.Path(record.Path)
+		if existing, err := utils.ReadFileFS(disk, fullPath); err == nil {
+			content, err := execCtx.ReadFile(record.Path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read planned file %s", record.Path)
+			}
+			planned.Status = FileUnchanged
+			if content != existing {
+				planned.Status = FileModified
+				planned.Diff = utils.UnifiedDiff("a/"+record.Path, "b/"+record.Path, existing, content)
+			}
+		}
+
+		plan.Files = append(plan.Files, planned)
+	}
+
+	return plan, nil
+}
+
+// RenderFS runs the whole plugin pipeline against an empty in-memory
+// filesystem and returns it, e.g. to stream the project as an archive.
+// Plugins skip their side effects and report them in Skipped.
+func (e *Engine) RenderFS(ctx context.Context, config *tilocontext.ProjectConfig) (*Output, error) {
+	return e.Generate(ctx, config, utils.NewMemoryFS())
+}
+
+// Render runs the whole plugin pipeline against an empty in-memory
+// filesystem and returns the content of every file written, keyed by path
+// relative to the project root. The lockfile is included.
+func (e *Engine) Render(ctx context.Context, config *tilocontext.ProjectConfig) (map[string]string, error) {
+	return e.RenderTemplates(ctx, config, nil)
+}
+
+// RenderTemplates is Render with the shipped template trees named in
+// templates replaced, e.g. by the revisions a lockfile recorded
+func (e *Engine) RenderTemplates(ctx context.Context, config *tilocontext.ProjectConfig, templates map[string]fs.FS) (map[string]string, error) {
+	output, err := e.generate(ctx, config, utils.NewMemoryFS(), templates)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string)
+	for _, record := range output.Files {
+		content, err := utils.ReadFileFS(output.FS, filepath.Join(output.Root, filepath.FromSlash(record.Path)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read rendered file %s", record.Path)
+		}
+		files[record.Path] = content
+	}
+	return files, nil
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// transaction stages generated output in the execution context's temp
+// directory and moves it into the target directory only on commit, so a
+// failed run never leaves a half-written project behind.
+type transaction struct {
+	ctx     *tilocontext.ExecutionContext
+	staging string
+	backup  string
+}
+
+// beginTransaction creates the staging directory and points ProjectPath at
+// it. An existing target is copied into staging first, so plugins see the
+// same tree they would have written over with --force.
+func beginTransaction(ctx *tilocontext.ExecutionContext) (*transaction, error) {
+	if err := ctx.CreateTempDir(); err != nil {
+		return nil, errors.Wrap(err, "failed to create staging directory")
+	}
+
+	tx := &transaction{
+		ctx:     ctx,
+		staging: filepath.Join(ctx.TempDir, "project"),
+		backup:  filepath.Join(ctx.TempDir, "previous"),
+	}
+
+	if utils.DirExists(ctx.TargetPath) {
+		if err := utils.CopyDir(ctx.TargetPath, tx.staging); err != nil {
+			tx.rollback()
+			return nil, errors.Wrap(err, "failed to stage existing project directory")
+		}
+	} else if err := utils.EnsureDir(tx.staging); err != nil {
+		tx.rollback()
+		return nil, errors.Wrap(err, "failed to create staging directory")
+	}
+
+	ctx.ProjectPath = tx.staging
+	return tx, nil
+}
+
+// commit swaps the staged project into place. If the swap fails the
+// previous target is restored. Once the project is in place the run has
+// succeeded, so a leftover temp directory is only reported.
+func (tx *transaction) commit() error {
+	target := tx.ctx.TargetPath
+	hadTarget := utils.DirExists(target)
+
+	if hadTarget {
+		if err := os.Rename(target, tx.backup); err != nil {
+			return errors.Wrap(err, "failed to move existing project directory aside")
+		}
+	}
+
+	if err := os.Rename(tx.staging, target); err != nil {
+		if hadTarget {
+			if restoreErr := os.Rename(tx.backup, target); restoreErr != nil {
+				return errors.Wrapf(err, "failed to move project into place (restoring %s also failed: %v)", target, restoreErr)
+			}
+		}
+		return errors.Wrap(err, "failed to move project into place")
+	}
+
+	tx.ctx.ProjectPath = target
+	if err := tx.ctx.Cleanup(); err != nil {
+		utils.Warning("Failed to remove temp directory %s: %v", tx.ctx.TempDir, err)
+	}
+	return nil
+}
+
+// rollback discards everything staged so far, leaving the target untouched
+func (tx *transaction) rollback() {
+	tx.ctx.ProjectPath = tx.ctx.TargetPath
+	// #nosec G104 - best-effort cleanup, the original error is reported
+	_ = tx.ctx.Cleanup()
+}
@@ -0,0 +1,94 @@
+package features
+
+// Names of the built-in features
+const (
+	TypeScript  = "typescript"
+	JavaScript  = "javascript"
+	ESLint      = "eslint"
+	Prettier    = "prettier"
+	Testing     = "testing"
+	Docker      = "docker"
+	CI          = "ci"
+	Git         = "git"
+	InstallDeps = "install-deps"
+)
+
+// javascriptFrameworks are the frameworks whose plugins generate from the
+// JavaScript features
+var javascriptFrameworks = []string{"react", "vue"}
+
+// Frameworks the testing, Docker and CI features apply to: the JavaScript
+// ones and the server stacks, see stacks.go
+var (
+	testingFrameworks = []string{"react", "vue", pythonStack, rustStack}
+	serverFrameworks  = []string{"react", "vue", goStack, pythonStack, rustStack, phpStack, javaStack, rubyStack}
+)
+
+// Builtin returns a registry holding the features TiLoKit ships with
+func Builtin() *Registry {
+	r := NewRegistry()
+	for _, feature := range []Feature{
+		{
+			Name:        TypeScript,
+			Description: "Write the project in TypeScript",
+			Frameworks:  javascriptFrameworks,
+			Conflicts:   []string{JavaScript},
+			Default:     true,
+			Contribute:  typescriptFiles,
+		},
+		{
+			Name:        JavaScript,
+			Description: "Write the project in plain JavaScript",
+			Frameworks:  javascriptFrameworks,
+		},
+		{
+			Name:        ESLint,
+			Description: "Lint with ESLint",
+			Frameworks:  javascriptFrameworks,
+			Default:     true,
+			Contribute:  eslintFiles,
+		},
+		{
+			Name:        Prettier,
+			Description: "Format with Prettier",
+			Frameworks:  javascriptFrameworks,
+			Default:     true,
+			Contribute:  prettierFiles,
+		},
+		{
+			Name:        Testing,
+			Description: "Unit tests with Vitest, pytest or cargo test",
+			Frameworks:  testingFrameworks,
+			Default:     true,
+			Contribute:  testingFiles,
+		},
+		{
+			Name:        Docker,
+			Description: "Build and serve the app from a Docker image",
+			Frameworks:  serverFrameworks,
+			Contribute:  dockerFiles,
+		},
+		{
+			Name:        CI,
+			Description: "Lint, test and build on GitHub Actions",
+			Frameworks:  serverFrameworks,
+			Contribute:  ciFiles,
+		},
+		{
+			Name:        Git,
+			Description: "Initialize a git repository with an initial commit",
+			Default:     true,
+		},
+		{
+			Name:        InstallDeps,
+			Description: "Install dependencies after generation",
+			Default:     true,
+		},
+	} {
+		// The built-in set is fixed, so an error here is a programming error
+		if err := r.Register(feature); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
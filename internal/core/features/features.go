@@ -0,0 +1,391 @@
+package features
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ti-lo/tilokit/internal/core/patch"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// Feature is an optional part of a generated project, such as TypeScript
+// or linting, that users select with --features and --without
+type Feature struct {
+	Name        string
+	Description string
+	// Frameworks the feature applies to, as plugin patterns like "react",
+	// "js:*" or "*"
+	Frameworks []string
+	// Requires lists features that are selected along with this one
+	Requires []string
+	// Conflicts lists features that can't be selected with this one
+	Conflicts []string
+	// Default features are selected unless configured off or removed with
+	// --without
+	Default bool
+	// Contribute returns the files and packages the feature adds for a
+	// selection; nil means it only changes how plugins generate
+	Contribute func(s Selection) Contribution
+}
+
+// Selection is the resolved set of features for one project
+type Selection struct {
+	Framework string
+	Features  []string
+	// BuildTool and Module, the project name as an identifier, let features
+	// fit the files they add to the project; either may be empty
+	BuildTool string
+	Module    string
+}
+
+// Has reports whether a feature is selected
+func (s Selection) Has(name string) bool {
+	return utils.Contains(s.Features, name)
+}
+
+// Contribution is what features add to a generated project. Dependencies
+// and scripts apply to package.json, in order; files are written as-is,
+// while patches are merged key by key into structured files such as
+// tsconfig.json.
+type Contribution struct {
+	Files           map[string]string
+	Patches         map[string]patch.Object
+	Dependencies    patch.Object
+	DevDependencies patch.Object
+	Scripts         patch.Object
+}
+
+// Merge adds other to c; other wins where both set the same key, and its
+// patches follow those of c
+func (c *Contribution) Merge(other Contribution) {
+	c.Files = mergeMap(c.Files, other.Files)
+	c.Dependencies = c.Dependencies.With(other.Dependencies...)
+	c.DevDependencies = c.DevDependencies.With(other.DevDependencies...)
+	c.Scripts = c.Scripts.With(other.Scripts...)
+	for path, fields := range other.Patches {
+		if c.Patches == nil {
+			c.Patches = make(map[string]patch.Object)
+		}
+		c.Patches[path] = append(c.Patches[path], fields...)
+	}
+}
+
+func mergeMap(dst, src map[string]string) map[string]string {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+	for key, value := range src {
+		dst[key] = value
+	}
+	return dst
+}
+
+// Registry holds the known features in registration order
+type Registry struct {
+	features map[string]*Feature
+	order    []string
+}
+
+// NewRegistry creates an empty feature registry
+func NewRegistry() *Registry {
+	return &Registry{features: make(map[string]*Feature)}
+}
+
+// Register adds a feature. Requirements and conflicts may name features
+// registered later; Resolve reports names that never appear.
+func (r *Registry) Register(feature Feature) error {
+	if feature.Name == "" {
+		return errors.New("feature name is required")
+	}
+	if _, exists := r.features[feature.Name]; exists {
+		return errors.Errorf("feature %s is already registered", feature.Name)
+	}
+	if len(feature.Frameworks) == 0 {
+		feature.Frameworks = []string{registry.Wildcard}
+	}
+	for _, other := range feature.Requires {
+		if utils.Contains(feature.Conflicts, other) {
+			return errors.Errorf("feature %s both requires and conflicts with %s", feature.Name, other)
+		}
+	}
+
+	r.features[feature.Name] = &feature
+	r.order = append(r.order, feature.Name)
+	return nil
+}
+
+// Get returns a registered feature
+func (r *Registry) Get(name string) (*Feature, bool) {
+	feature, exists := r.features[name]
+	return feature, exists
+}
+
+// Available returns the features that apply to a framework, in
+// registration order
+func (r *Registry) Available(framework string) []*Feature {
+	var available []*Feature
+	for _, name := range r.order {
+		if feature := r.features[name]; feature.appliesTo(framework) {
+			available = append(available, feature)
+		}
+	}
+	return available
+}
+
+func (f *Feature) appliesTo(framework string) bool {
+	for _, pattern := range f.Frameworks {
+		if registry.MatchFramework(pattern, framework) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve works out the features of a project. It starts from the
+// defaults, where configured overrides each feature's own default, adds
+// the features asked for with with and their requirements, and removes
+// those in without.
+//
+// Features that were asked for win over defaults: a default that conflicts
+// with one, or requires one that was removed, is dropped. Conflicts and
+// missing requirements among features that were asked for are errors.
+func (r *Registry) Resolve(framework string, with, without []string, configured map[string]bool) (Selection, error) {
+	for _, name := range append(append([]string{}, with...), without...) {
+		if err := r.check(name, framework); err != nil {
+			return Selection{}, err
+		}
+	}
+	for _, name := range with {
+		if utils.Contains(without, name) {
+			return Selection{}, errors.Errorf("feature %s is both requested and removed", name)
+		}
+	}
+
+	// explicit maps every feature the user asked for, directly or through
+	// a requirement, to the feature that asked for it
+	explicit := make(map[string]string)
+	var queue []string
+	for _, name := range with {
+		explicit[name] = name
+		queue = append(queue, name)
+	}
+	for len(queue) > 0 {
+		feature := r.features[queue[0]]
+		queue = queue[1:]
+		for _, required := range feature.Requires {
+			if err := r.check(required, framework); err != nil {
+				return Selection{}, errors.Wrapf(err, "feature %s", feature.Name)
+			}
+			if utils.Contains(without, required) {
+				return Selection{}, errors.Errorf("feature %s requires %s, which is removed with --without", explicit[feature.Name], required)
+			}
+			if _, seen := explicit[required]; !seen {
+				explicit[required] = explicit[feature.Name]
+				queue = append(queue, required)
+			}
+		}
+	}
+
+	selected := make(map[string]bool)
+	for name := range explicit {
+		selected[name] = true
+	}
+	for _, feature := range r.Available(framework) {
+		enabled := feature.Default
+		if value, exists := configured[feature.Name]; exists {
+			enabled = value
+		}
+		if enabled && !utils.Contains(without, feature.Name) {
+			selected[feature.Name] = true
+			queue = append(queue, feature.Name)
+		}
+	}
+	// Defaults bring their requirements along unless those were removed
+	for len(queue) > 0 {
+		for _, required := range r.features[queue[0]].Requires {
+			if required, known := r.features[required]; known && !selected[required.Name] &&
+				required.appliesTo(framework) && !utils.Contains(without, required.Name) {
+				selected[required.Name] = true
+				queue = append(queue, required.Name)
+			}
+		}
+		queue = queue[1:]
+	}
+
+	// Drop defaults that clash with what was asked for, or that need
+	// something that is no longer selected, until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, name := range r.order {
+			if !selected[name] {
+				continue
+			}
+			before := len(selected)
+			if err := r.settle(name, selected, explicit); err != nil {
+				return Selection{}, err
+			}
+			changed = changed || len(selected) != before
+		}
+	}
+
+	selection := Selection{Framework: framework}
+	for _, name := range r.order {
+		if selected[name] {
+			selection.Features = append(selection.Features, name)
+		}
+	}
+	return selection, nil
+}
+
+// settle removes a default feature from selected when a feature it
+// requires is missing, or when it conflicts with an explicit feature or an
+// earlier registered default. Two explicit features in conflict are an
+// error.
+func (r *Registry) settle(name string, selected map[string]bool, explicit map[string]string) error {
+	feature := r.features[name]
+	_, isExplicit := explicit[name]
+
+	for _, required := range feature.Requires {
+		if !selected[required] {
+			if isExplicit {
+				return errors.Errorf("feature %s requires %s, which conflicts with the selection", explicit[name], required)
+			}
+			delete(selected, name)
+			return nil
+		}
+	}
+
+	earlier := true
+	for _, other := range r.order {
+		if other == name {
+			earlier = false
+			continue
+		}
+		if !selected[other] || !r.conflict(name, other) {
+			continue
+		}
+		_, otherExplicit := explicit[other]
+		switch {
+		case isExplicit && otherExplicit:
+			return errors.Errorf("features %s and %s conflict", explicit[other], explicit[name])
+		case isExplicit:
+			delete(selected, other)
+		case otherExplicit || earlier:
+			delete(selected, name)
+			return nil
+		}
+	}
+	return nil
+}
+
+// conflict reports whether either feature declares a conflict with the
+// other
+func (r *Registry) conflict(a, b string) bool {
+	return utils.Contains(r.features[a].Conflicts, b) || utils.Contains(r.features[b].Conflicts, a)
+}
+
+// Validate checks an already resolved list of features, such as one read
+// from a recipe: every feature must apply to the framework, have its
+// requirements in the list and not conflict with another in it
+func (r *Registry) Validate(framework string, names []string) error {
+	for _, name := range names {
+		if err := r.check(name, framework); err != nil {
+			return err
+		}
+		for _, required := range r.features[name].Requires {
+			if !utils.Contains(names, required) {
+				return errors.Errorf("feature %s requires %s", name, required)
+			}
+		}
+		for _, other := range names {
+			if other != name && r.conflict(name, other) {
+				return errors.Errorf("features %s and %s conflict", name, other)
+			}
+		}
+	}
+	return nil
+}
+
+// check reports unknown features and features that don't apply to the
+// framework
+func (r *Registry) check(name, framework string) error {
+	feature, exists := r.features[name]
+	if !exists {
+		return errors.Errorf("unknown feature %s, available: %s", name, strings.Join(r.names(framework), ", "))
+	}
+	if !feature.appliesTo(framework) {
+		return errors.Errorf("feature %s is not available for %s", name, framework)
+	}
+	return nil
+}
+
+func (r *Registry) names(framework string) []string {
+	var names []string
+	for _, feature := range r.Available(framework) {
+		names = append(names, feature.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Add resolves the features of an existing project with added on top.
+// Nothing is defaulted, so the selection holds the existing features, the
+// added ones and their requirements. Existing features that are unknown or
+// don't apply to the framework are ignored. It also returns the features
+// that are new to the project, in registration order.
+func (r *Registry) Add(framework string, existing, added []string) (Selection, []string, error) {
+	var present []string
+	for _, name := range existing {
+		if r.check(name, framework) == nil {
+			present = append(present, name)
+		}
+	}
+	for _, name := range added {
+		if utils.Contains(present, name) {
+			return Selection{}, nil, errors.Errorf("the project already has feature %s", name)
+		}
+	}
+
+	configured := make(map[string]bool)
+	for _, feature := range r.Available(framework) {
+		configured[feature.Name] = false
+	}
+	selection, err := r.Resolve(framework, append(present, added...), nil, configured)
+	if err != nil {
+		return Selection{}, nil, err
+	}
+
+	var newFeatures []string
+	for _, name := range selection.Features {
+		if !utils.Contains(present, name) {
+			newFeatures = append(newFeatures, name)
+		}
+	}
+	return selection, newFeatures, nil
+}
+
+// Contribution returns what the named features add to a selection, in
+// registration order
+func (r *Registry) Contribution(s Selection, names []string) Contribution {
+	var merged Contribution
+	for _, name := range r.order {
+		feature := r.features[name]
+		if !utils.Contains(names, name) || feature.Contribute == nil {
+			continue
+		}
+		merged.Merge(feature.Contribute(s))
+	}
+	return merged
+}
+
+// Contributions merges what every selected feature adds, in registration
+// order
+func (r *Registry) Contributions(s Selection) Contribution {
+	return r.Contribution(s, s.Features)
+}
@@ -0,0 +1,206 @@
+package features
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	for _, feature := range []Feature{
+		{Name: "typescript", Frameworks: []string{"js:*"}, Conflicts: []string{"javascript"}, Default: true},
+		{Name: "javascript", Frameworks: []string{"js:*"}},
+		{Name: "lint", Default: true},
+		{Name: "typed-lint", Frameworks: []string{"js:*"}, Requires: []string{"lint", "typescript"}},
+		{Name: "storybook", Frameworks: []string{"react"}, Requires: []string{"docs"}},
+		{Name: "docs"},
+	} {
+		if err := r.Register(feature); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		framework  string
+		with       []string
+		without    []string
+		configured map[string]bool
+		want       []string
+	}{
+		{name: "defaults", framework: "react", want: []string{"typescript", "lint"}},
+		{name: "only applicable defaults", framework: "django", want: []string{"lint"}},
+		{name: "configured off", framework: "react", configured: map[string]bool{"lint": false}, want: []string{"typescript"}},
+		{name: "configured on", framework: "react", configured: map[string]bool{"docs": true}, want: []string{"typescript", "lint", "docs"}},
+		{name: "without", framework: "vue", without: []string{"lint"}, want: []string{"typescript"}},
+		{name: "explicit beats conflicting default", framework: "vue", with: []string{"javascript"}, want: []string{"javascript", "lint"}},
+		{name: "requirements are added", framework: "react", with: []string{"storybook"}, want: []string{"typescript", "lint", "storybook", "docs"}},
+		{name: "requirement conflicts with explicit feature", framework: "react", with: []string{"javascript", "typed-lint"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := newTestRegistry(t).Resolve(tt.framework, tt.with, tt.without, tt.configured)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("Expected an error, got %v", selection.Features)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if !reflect.DeepEqual(selection.Features, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, selection.Features)
+			}
+		})
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		with    []string
+		without []string
+		message string
+	}{
+		{name: "unknown", with: []string{"graphql"}, message: "unknown feature graphql"},
+		{name: "not available", with: []string{"storybook"}, message: "not available for vue"},
+		{name: "requested and removed", with: []string{"lint"}, without: []string{"lint"}, message: "both requested and removed"},
+		{name: "requirement removed", with: []string{"typed-lint"}, without: []string{"lint"}, message: "requires lint"},
+		{name: "explicit conflict", with: []string{"typescript", "javascript"}, message: "conflict"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestRegistry(t).Resolve("vue", tt.with, tt.without, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got: %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestRegisterRejectsInvalidFeatures(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(Feature{Name: "a", Requires: []string{"b"}, Conflicts: []string{"b"}}); err == nil {
+		t.Error("Expected a feature that requires and conflicts with b to be rejected")
+	}
+	if err := r.Register(Feature{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(Feature{Name: "a"}); err == nil {
+		t.Error("Expected a duplicate feature to be rejected")
+	}
+}
+
+func TestBuiltinContributionsFollowSelection(t *testing.T) {
+	r := Builtin()
+
+	typescript, err := r.Resolve("react", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contribution := r.Contributions(typescript)
+	for _, path := range []string{".eslintrc.cjs", "src/App.test.tsx"} {
+		if _, exists := contribution.Files[path]; !exists {
+			t.Errorf("Expected default React features to add %s", path)
+		}
+	}
+	for _, path := range []string{"tsconfig.json", "tsconfig.node.json", ".prettierrc.json"} {
+		if _, exists := contribution.Patches[path]; !exists {
+			t.Errorf("Expected default React features to patch %s", path)
+		}
+	}
+	if _, exists := contribution.DevDependencies.Get("eslint-config-prettier"); !exists {
+		t.Error("Expected eslint to be combined with prettier")
+	}
+
+	javascript, err := r.Resolve("react", []string{JavaScript}, []string{Prettier, Testing}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contribution = r.Contributions(javascript)
+	if _, exists := contribution.Patches["tsconfig.json"]; exists {
+		t.Error("Expected no tsconfig.json for a JavaScript project")
+	}
+	if _, exists := contribution.DevDependencies.Get("typescript"); exists {
+		t.Error("Expected no typescript dependency for a JavaScript project")
+	}
+	if _, exists := contribution.Scripts.Get("test"); exists {
+		t.Error("Expected no test script without the testing feature")
+	}
+	if lint, _ := contribution.Scripts.Get("lint"); !strings.Contains(fmt.Sprint(lint), "js,jsx") {
+		t.Errorf("Expected JavaScript lint script, got %q", lint)
+	}
+}
+
+func TestBuiltinAdd(t *testing.T) {
+	r := Builtin()
+
+	selection, added, err := r.Add("react", []string{TypeScript, ESLint, "unknown"}, []string{Docker, CI})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(added, []string{Docker, CI}) {
+		t.Errorf("Expected only the added features to be new, got %v", added)
+	}
+	if selection.Has(Prettier) || selection.Has(Git) || !selection.Has(ESLint) {
+		t.Errorf("Expected the existing features without defaults, got %v", selection.Features)
+	}
+	contribution := r.Contribution(selection, added)
+	if _, exists := contribution.Files["Dockerfile"]; !exists {
+		t.Error("Expected docker to add a Dockerfile")
+	}
+	if _, exists := contribution.Patches["tsconfig.json"]; exists {
+		t.Error("Expected existing features not to contribute again")
+	}
+	if ci := contribution.Files[".github/workflows/ci.yml"]; !strings.Contains(ci, "npm run lint") {
+		t.Errorf("Expected CI to lint a project with eslint, got %q", ci)
+	}
+
+	if _, _, err := r.Add("react", []string{TypeScript, Docker}, []string{Docker}); err == nil {
+		t.Error("Expected adding a feature the project has to fail")
+	}
+}
+
+func TestBuiltinServerStacks(t *testing.T) {
+	r := Builtin()
+
+	selection, added, err := r.Add("gin", nil, []string{Docker, CI})
+	if err != nil {
+		t.Fatalf("Expected docker and ci to be available for gin, got: %v", err)
+	}
+	contribution := r.Contribution(selection, added)
+	if dockerfile := contribution.Files["Dockerfile"]; !strings.Contains(dockerfile, "go build") {
+		t.Errorf("Expected a Go Dockerfile, got %q", dockerfile)
+	}
+	if ci := contribution.Files[".github/workflows/ci.yml"]; !strings.Contains(ci, "go test ./...") {
+		t.Errorf("Expected CI to run go test, got %q", ci)
+	}
+	if _, _, err := r.Add("gin", nil, []string{Testing}); err == nil {
+		t.Error("Expected testing not to be available for gin")
+	}
+
+	selection, err = r.Resolve("django", []string{Testing, Docker, CI}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selection.BuildTool, selection.Module = "poetry", "shop"
+	contribution = r.Contributions(selection)
+	if dockerfile := contribution.Files["Dockerfile"]; !strings.Contains(dockerfile, "poetry install") || !strings.Contains(dockerfile, "shop.wsgi:application") {
+		t.Errorf("Expected a Django Dockerfile for poetry, got %q", dockerfile)
+	}
+	if ci := contribution.Files[".github/workflows/ci.yml"]; !strings.Contains(ci, "poetry run python -m pytest") {
+		t.Errorf("Expected CI to run pytest through poetry, got %q", ci)
+	}
+	if _, exists := contribution.Files["tests/test_smoke.py"]; !exists {
+		t.Error("Expected testing to add a pytest smoke test")
+	}
+}
@@ -0,0 +1,312 @@
+package features
+
+import (
+	"strings"
+
+	"github.com/ti-lo/tilokit/internal/core/patch"
+)
+
+// ScriptExt returns the extension for script files in the selection's
+// language, "ts" or "js"
+func (s Selection) ScriptExt() string {
+	if s.Has(TypeScript) {
+		return "ts"
+	}
+	return "js"
+}
+
+func typescriptFiles(s Selection) Contribution {
+	if s.Framework == "vue" {
+		return Contribution{
+			Files: map[string]string{
+				"src/env.d.ts": `/// <reference types="vite/client" />`,
+			},
+			Patches: map[string]patch.Object{
+				"tsconfig.json": {
+					{Key: "extends", Value: "@vue/tsconfig/tsconfig.dom.json"},
+					{Key: "include", Value: []string{"src/env.d.ts", "src/**/*", "src/**/*.vue", "vite.config.*", "vitest.config.*"}},
+					{Key: "compilerOptions", Value: patch.Object{
+						{Key: "baseUrl", Value: "."},
+						{Key: "paths", Value: patch.Object{{Key: "@/*", Value: []string{"./src/*"}}}},
+					}},
+				},
+			},
+			DevDependencies: patch.Object{
+				{Key: "@tsconfig/node18", Value: "^18.2.2"},
+				{Key: "@types/node", Value: "^18.18.5"},
+				{Key: "@vue/tsconfig", Value: "^0.4.0"},
+				{Key: "typescript", Value: "~5.2.0"},
+				{Key: "vue-tsc", Value: "^1.8.19"},
+			},
+			Scripts: patch.Object{
+				{Key: "build", Value: "vue-tsc --noEmit && vite build"},
+				{Key: "type-check", Value: "vue-tsc --noEmit"},
+			},
+		}
+	}
+
+	return Contribution{
+		Patches: map[string]patch.Object{
+			"tsconfig.json": {
+				{Key: "compilerOptions", Value: patch.Object{
+					{Key: "target", Value: "ES2020"},
+					{Key: "useDefineForClassFields", Value: true},
+					{Key: "lib", Value: []string{"ES2020", "DOM", "DOM.Iterable"}},
+					{Key: "module", Value: "ESNext"},
+					{Key: "skipLibCheck", Value: true},
+					// Bundler mode
+					{Key: "moduleResolution", Value: "bundler"},
+					{Key: "allowImportingTsExtensions", Value: true},
+					{Key: "resolveJsonModule", Value: true},
+					{Key: "isolatedModules", Value: true},
+					{Key: "noEmit", Value: true},
+					{Key: "jsx", Value: "react-jsx"},
+					// Linting
+					{Key: "strict", Value: true},
+					{Key: "noUnusedLocals", Value: true},
+					{Key: "noUnusedParameters", Value: true},
+					{Key: "noFallthroughCasesInSwitch", Value: true},
+					// Path mapping
+					{Key: "baseUrl", Value: "."},
+					{Key: "paths", Value: patch.Object{{Key: "@/*", Value: []string{"./src/*"}}}},
+				}},
+				{Key: "include", Value: []string{"src"}},
+				{Key: "references", Value: []patch.Object{{{Key: "path", Value: "./tsconfig.node.json"}}}},
+			},
+			"tsconfig.node.json": {
+				{Key: "compilerOptions", Value: patch.Object{
+					{Key: "composite", Value: true},
+					{Key: "skipLibCheck", Value: true},
+					{Key: "module", Value: "ESNext"},
+					{Key: "moduleResolution", Value: "bundler"},
+					{Key: "allowSyntheticDefaultImports", Value: true},
+				}},
+				{Key: "include", Value: []string{"vite.config.ts"}},
+			},
+		},
+		DevDependencies: patch.Object{
+			{Key: "@types/react", Value: "^18.2.37"},
+			{Key: "@types/react-dom", Value: "^18.2.15"},
+			{Key: "typescript", Value: "^5.2.2"},
+		},
+		Scripts: patch.Object{
+			{Key: "build", Value: "tsc && vite build"},
+		},
+	}
+}
+
+func eslintFiles(s Selection) Contribution {
+	ts := s.Has(TypeScript)
+	prettier := s.Has(Prettier)
+
+	if s.Framework == "vue" {
+		extends := []string{"'plugin:vue/vue3-essential'", "'eslint:recommended'"}
+		devDependencies := patch.Object{
+			{Key: "@rushstack/eslint-patch", Value: "^1.3.3"},
+			{Key: "eslint", Value: "^8.49.0"},
+			{Key: "eslint-plugin-vue", Value: "^9.17.0"},
+		}
+		if ts {
+			extends = append(extends, "'@vue/eslint-config-typescript'")
+			devDependencies = append(devDependencies, patch.Field{Key: "@vue/eslint-config-typescript", Value: "^12.0.0"})
+		}
+		if prettier {
+			extends = append(extends, "'@vue/eslint-config-prettier/skip-formatting'")
+			devDependencies = append(devDependencies, patch.Field{Key: "@vue/eslint-config-prettier", Value: "^8.0.0"})
+		}
+
+		return Contribution{
+			Files: map[string]string{
+				".eslintrc.cjs": `/* eslint-env node */
+require('@rushstack/eslint-patch/modern-module-resolution')
+
+module.exports = {
+  root: true,
+  extends: [
+    ` + strings.Join(extends, ",\n    ") + `
+  ],
+  parserOptions: {
+    ecmaVersion: 'latest'
+  }
+}`,
+			},
+			DevDependencies: devDependencies,
+			Scripts: patch.Object{
+				{Key: "lint", Value: "eslint . --ext .vue,.js,.jsx,.cjs,.mjs,.ts,.tsx,.cts,.mts --fix --ignore-path .gitignore"},
+			},
+		}
+	}
+
+	extends := []string{"'eslint:recommended'"}
+	devDependencies := patch.Object{
+		{Key: "eslint", Value: "^8.53.0"},
+		{Key: "eslint-plugin-react", Value: "^7.33.2"},
+		{Key: "eslint-plugin-react-hooks", Value: "^4.6.0"},
+		{Key: "eslint-plugin-react-refresh", Value: "^0.4.4"},
+	}
+	parser := ""
+	extensions := "js,jsx"
+	if ts {
+		extends = append(extends, "'plugin:@typescript-eslint/recommended'")
+		devDependencies = append(devDependencies, patch.Field{Key: "@typescript-eslint/eslint-plugin", Value: "^6.10.0"})
+		devDependencies = append(devDependencies, patch.Field{Key: "@typescript-eslint/parser", Value: "^6.10.0"})
+		parser = "\n  parser: '@typescript-eslint/parser',"
+		extensions = "ts,tsx"
+	}
+	extends = append(extends, "'plugin:react/recommended'", "'plugin:react/jsx-runtime'", "'plugin:react-hooks/recommended'")
+	if prettier {
+		extends = append(extends, "'prettier'")
+		devDependencies = append(devDependencies, patch.Field{Key: "eslint-config-prettier", Value: "^9.0.0"})
+	}
+
+	return Contribution{
+		Files: map[string]string{
+			".eslintrc.cjs": `module.exports = {
+  root: true,
+  env: { browser: true, es2020: true },
+  extends: [
+    ` + strings.Join(extends, ",\n    ") + `,
+  ],
+  ignorePatterns: ['dist', '.eslintrc.cjs'],` + parser + `
+  parserOptions: { ecmaVersion: 'latest', sourceType: 'module' },
+  settings: { react: { version: '18.2' } },
+  plugins: ['react-refresh'],
+  rules: {
+    'react-refresh/only-export-components': [
+      'warn',
+      { allowConstantExport: true },
+    ],
+  },
+}`,
+		},
+		DevDependencies: devDependencies,
+		Scripts: patch.Object{
+			{Key: "lint", Value: "eslint . --ext " + extensions + " --report-unused-disable-directives --max-warnings 0"},
+		},
+	}
+}
+
+func prettierFiles(s Selection) Contribution {
+	return Contribution{
+		Patches: map[string]patch.Object{
+			".prettierrc.json": {
+				{Key: "semi", Value: false},
+				{Key: "singleQuote", Value: true},
+				{Key: "tabWidth", Value: 2},
+				{Key: "printWidth", Value: 100},
+				{Key: "trailingComma", Value: "es5"},
+			},
+		},
+		DevDependencies: patch.Object{
+			{Key: "prettier", Value: "^3.0.3"},
+		},
+		Scripts: patch.Object{
+			{Key: "format", Value: "prettier --write src/"},
+		},
+	}
+}
+
+func testingFiles(s Selection) Contribution {
+	if stack := stackOf(s.Framework); stack != "" {
+		return stackTestingFiles(s, stack)
+	}
+
+	devDependencies := patch.Object{
+		{Key: "jsdom", Value: "^23.0.1"},
+		{Key: "vitest", Value: "^1.0.0"},
+	}
+	scripts := patch.Object{
+		{Key: "test", Value: "vitest"},
+	}
+
+	if s.Framework == "vue" {
+		devDependencies = append(devDependencies, patch.Field{Key: "@vue/test-utils", Value: "^2.4.3"})
+		return Contribution{
+			Files: map[string]string{
+				"src/components/__tests__/HelloWorld.spec." + s.ScriptExt(): `// @vitest-environment jsdom
+import { describe, expect, it } from 'vitest'
+import { mount } from '@vue/test-utils'
+import HelloWorld from '../HelloWorld.vue'
+
+describe('HelloWorld', () => {
+  it('renders the message', () => {
+    const wrapper = mount(HelloWorld, { props: { msg: 'Hello Vitest' } })
+    expect(wrapper.text()).toContain('Hello Vitest')
+  })
+})
+`,
+			},
+			DevDependencies: devDependencies,
+			Scripts:         scripts,
+		}
+	}
+
+	devDependencies = append(devDependencies, patch.Field{Key: "@testing-library/react", Value: "^14.1.2"})
+	devDependencies = append(devDependencies, patch.Field{Key: "@vitest/ui", Value: "^1.0.0"})
+	scripts = append(scripts, patch.Field{Key: "test:ui", Value: "vitest --ui"})
+	return Contribution{
+		Files: map[string]string{
+			"src/App.test." + s.ScriptExt() + "x": `// @vitest-environment jsdom
+import { render, screen } from '@testing-library/react'
+import { describe, expect, it } from 'vitest'
+import App from './App'
+
+describe('App', () => {
+  it('renders the welcome heading', () => {
+    render(<App />)
+    expect(screen.getByRole('heading', { level: 1 }).textContent).toContain('Welcome')
+  })
+})
+`,
+		},
+		DevDependencies: devDependencies,
+		Scripts:         scripts,
+	}
+}
+
+func dockerFiles(s Selection) Contribution {
+	if stack := stackOf(s.Framework); stack != "" {
+		return stackDockerFiles(s, stack)
+	}
+
+	return Contribution{
+		Files: map[string]string{
+			"Dockerfile": `FROM node:20-alpine AS build
+WORKDIR /app
+COPY package*.json ./
+RUN npm ci
+COPY . .
+RUN npm run build
+
+FROM nginx:1.27-alpine
+COPY --from=build /app/dist /usr/share/nginx/html
+EXPOSE 80
+`,
+			".dockerignore": `node_modules
+dist
+.git
+`,
+		},
+	}
+}
+
+func ciFiles(s Selection) Contribution {
+	if stack := stackOf(s.Framework); stack != "" {
+		return stackCIFiles(s, stack)
+	}
+
+	steps := []string{"npm ci"}
+	if s.Has(ESLint) {
+		steps = append(steps, "npm run lint")
+	}
+	if s.Has(Testing) {
+		steps = append(steps, "npm test -- --run")
+	}
+	steps = append(steps, "npm run build")
+
+	return workflow(`      - uses: actions/setup-node@v4
+        with:
+          node-version: 20
+          cache: npm
+`, steps)
+}
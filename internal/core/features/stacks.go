@@ -0,0 +1,280 @@
+package features
+
+import (
+	"strings"
+
+	"github.com/ti-lo/tilokit/internal/core/registry"
+)
+
+// Stacks of the server frameworks whose projects can get the Docker and CI
+// features, as framework family patterns
+const (
+	goStack     = "go:*"
+	pythonStack = "python:*"
+	rustStack   = "rust:*"
+	phpStack    = "php:*"
+	javaStack   = "java:*"
+	rubyStack   = "ruby:*"
+)
+
+var serverStacks = []string{goStack, pythonStack, rustStack, phpStack, javaStack, rubyStack}
+
+// stackOf returns the server stack of a framework, or "" for JavaScript
+// and other frameworks
+func stackOf(framework string) string {
+	for _, stack := range serverStacks {
+		if registry.MatchFramework(stack, framework) {
+			return stack
+		}
+	}
+	return ""
+}
+
+// pythonRunner returns how commands run in a Python project's environment
+// for its build tool
+func pythonRunner(buildTool string) string {
+	switch buildTool {
+	case "poetry":
+		return "poetry run "
+	case "pipenv":
+		return "pipenv run "
+	}
+	return ""
+}
+
+func stackDockerFiles(s Selection, stack string) Contribution {
+	var dockerfile, ignore string
+	switch stack {
+	case goStack:
+		dockerfile = `FROM golang:1.21-alpine AS build
+WORKDIR /src
+COPY go.mod go.sum* ./
+RUN go mod download
+COPY . .
+RUN CGO_ENABLED=0 go build -o /out/app .
+
+FROM gcr.io/distroless/static-debian12
+COPY --from=build /out/app /app
+EXPOSE 8080
+ENTRYPOINT ["/app"]
+`
+		ignore = ".git\n"
+	case pythonStack:
+		install := "COPY requirements.txt .\nRUN pip install --no-cache-dir -r requirements.txt\n"
+		switch s.BuildTool {
+		case "poetry":
+			install = "COPY pyproject.toml poetry.lock* ./\n" +
+				"RUN pip install --no-cache-dir poetry && poetry config virtualenvs.create false && poetry install --no-root --only main\n"
+		case "pipenv":
+			install = "COPY Pipfile Pipfile.lock* ./\nRUN pip install --no-cache-dir pipenv && pipenv install --system\n"
+		}
+		cmd := `["gunicorn", "--bind", "0.0.0.0:8000", "app:app"]`
+		switch s.Framework {
+		case "django":
+			module := s.Module
+			if module == "" {
+				module = "app"
+			}
+			cmd = `["gunicorn", "--bind", "0.0.0.0:8000", "` + module + `.wsgi:application"]`
+		case "fastapi":
+			cmd = `["uvicorn", "main:app", "--host", "0.0.0.0", "--port", "8000"]`
+		}
+		dockerfile = `FROM python:3.11-slim
+WORKDIR /app
+ENV PYTHONDONTWRITEBYTECODE=1 PYTHONUNBUFFERED=1
+` + install + `COPY . .
+EXPOSE 8000
+CMD ` + cmd + "\n"
+		ignore = "__pycache__\n*.pyc\n.venv\nvenv\n.git\n"
+	case rustStack:
+		dockerfile = `FROM rust:1.75 AS build
+WORKDIR /src
+COPY . .
+RUN cargo install --path . --root /out && mv /out/bin/* /out/app
+
+FROM debian:bookworm-slim
+COPY --from=build /out/app /usr/local/bin/app
+EXPOSE 8080
+CMD ["app"]
+`
+		ignore = "target\n.git\n"
+	case phpStack:
+		dockerfile = `FROM composer:2 AS vendor
+WORKDIR /app
+COPY composer.json composer.lock* ./
+RUN composer install --no-dev --no-scripts --no-interaction --prefer-dist
+
+FROM php:8.2-apache
+WORKDIR /var/www/html
+COPY . .
+COPY --from=vendor /app/vendor ./vendor
+ENV APACHE_DOCUMENT_ROOT=/var/www/html/public
+RUN sed -ri -e 's!/var/www/html!${APACHE_DOCUMENT_ROOT}!g' /etc/apache2/sites-available/*.conf && a2enmod rewrite
+EXPOSE 80
+`
+		ignore = "vendor\nnode_modules\n.env\n.git\n"
+	case javaStack:
+		build, jar := "mvn -B package -DskipTests", "target"
+		if s.BuildTool == "gradle" {
+			build, jar = "./gradlew bootJar", "build/libs"
+			if s.Framework == "quarkus" {
+				build, jar = "./gradlew build -x test", "build"
+			}
+		}
+		// Quarkus packages the app as a directory rather than one jar
+		copyApp := "RUN " + build + " && cp " + jar + "/*.jar /app.jar"
+		runtime := "COPY --from=build /app.jar /app.jar\nEXPOSE 8080\nENTRYPOINT [\"java\", \"-jar\", \"/app.jar\"]\n"
+		if s.Framework == "quarkus" {
+			copyApp = "RUN " + build + " && cp -r " + jar + "/quarkus-app /app"
+			runtime = "COPY --from=build /app /app\nEXPOSE 8080\nENTRYPOINT [\"java\", \"-jar\", \"/app/quarkus-run.jar\"]\n"
+		}
+		image := "maven:3.9-eclipse-temurin-17"
+		if s.BuildTool == "gradle" {
+			image = "eclipse-temurin:17-jdk"
+		}
+		dockerfile = "FROM " + image + ` AS build
+WORKDIR /src
+COPY . .
+` + copyApp + `
+
+FROM eclipse-temurin:17-jre
+` + runtime
+		ignore = "target\nbuild\n.gradle\n.git\n"
+	case rubyStack:
+		port, cmd := "3000", `["bin/rails", "server", "-b", "0.0.0.0"]`
+		if s.Framework == "sinatra" {
+			port, cmd = "4567", `["bundle", "exec", "rackup", "--host", "0.0.0.0", "--port", "4567"]`
+		}
+		dockerfile = `FROM ruby:3.2-slim
+WORKDIR /app
+RUN apt-get update && apt-get install -y build-essential && rm -rf /var/lib/apt/lists/*
+COPY Gemfile Gemfile.lock* ./
+RUN bundle install
+COPY . .
+EXPOSE ` + port + `
+CMD ` + cmd + "\n"
+		ignore = "log\ntmp\n.git\n"
+	}
+
+	return Contribution{
+		Files: map[string]string{
+			"Dockerfile":    dockerfile,
+			".dockerignore": ignore,
+		},
+	}
+}
+
+func stackCIFiles(s Selection, stack string) Contribution {
+	var setup string
+	var steps []string
+	switch stack {
+	case goStack:
+		setup = `      - uses: actions/setup-go@v5
+        with:
+          go-version-file: go.mod
+`
+		steps = []string{"go vet ./...", "go test ./...", "go build ./..."}
+	case pythonStack:
+		setup = `      - uses: actions/setup-python@v5
+        with:
+          python-version: "3.11"
+`
+		run := pythonRunner(s.BuildTool)
+		switch s.BuildTool {
+		case "poetry":
+			steps = []string{"pipx install poetry", "poetry install"}
+		case "pipenv":
+			steps = []string{"pip install pipenv", "pipenv install --dev"}
+		default:
+			steps = []string{"pip install -r requirements.txt"}
+		}
+		if s.Has(Testing) {
+			steps = append(steps, run+"pip install pytest", run+"python -m pytest")
+		}
+	case rustStack:
+		setup = "      - uses: dtolnay/rust-toolchain@stable\n"
+		steps = []string{"cargo build", "cargo test"}
+	case phpStack:
+		setup = `      - uses: shivammathur/setup-php@v2
+        with:
+          php-version: "8.2"
+`
+		steps = []string{"composer install --no-interaction --prefer-dist", "vendor/bin/phpunit"}
+	case javaStack:
+		setup = `      - uses: actions/setup-java@v4
+        with:
+          distribution: temurin
+          java-version: "17"
+`
+		steps = []string{"mvn -B verify"}
+		if s.BuildTool == "gradle" {
+			steps = []string{"./gradlew build"}
+		}
+	case rubyStack:
+		setup = `      - uses: ruby/setup-ruby@v1
+        with:
+          bundler-cache: true
+`
+		steps = []string{"bundle exec rake"}
+		if s.Framework == "rails" {
+			steps = []string{"bin/rails test"}
+		}
+	}
+	return workflow(setup, steps)
+}
+
+func stackTestingFiles(s Selection, stack string) Contribution {
+	switch stack {
+	case pythonStack:
+		return Contribution{
+			Files: map[string]string{
+				"pytest.ini":        "[pytest]\ntestpaths = tests\n",
+				"tests/__init__.py": "",
+				"tests/test_smoke.py": `import importlib
+
+
+def test_framework_is_installed():
+    assert importlib.import_module("` + s.Framework + `")
+`,
+			},
+		}
+	case rustStack:
+		return Contribution{
+			Files: map[string]string{
+				"tests/smoke.rs": `#[test]
+fn package_builds() {
+    assert!(!env!("CARGO_PKG_NAME").is_empty());
+}
+`,
+			},
+		}
+	}
+	return Contribution{}
+}
+
+// workflow returns a GitHub Actions workflow that checks out the project,
+// runs setup and then each step
+func workflow(setup string, steps []string) Contribution {
+	var run strings.Builder
+	for _, step := range steps {
+		run.WriteString("      - run: " + step + "\n")
+	}
+
+	return Contribution{
+		Files: map[string]string{
+			".github/workflows/ci.yml": `name: CI
+
+on:
+  push:
+    branches: [main]
+  pull_request:
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+` + setup + run.String(),
+		},
+	}
+}
@@ -0,0 +1,236 @@
+package patch
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// goModDocument edits go.mod line by line, so comments and blocks it
+// doesn't touch stay as written. Directives such as module and go are keys
+// with their argument as value; require and the other module lists are
+// objects keyed by module path, whose values are the rest of the line, e.g.
+// "v1.9.1". A trailing comment such as // indirect is not part of the value
+// and is kept when the value changes.
+type goModDocument struct {
+	lines   []string
+	newline bool
+}
+
+// goModLists are the directives that list modules
+var goModLists = map[string]bool{"require": true, "exclude": true, "replace": true, "retract": true}
+
+// goModEntry is a directive line, or a line inside a directive's block
+type goModEntry struct {
+	directive string
+	// module is the module path of a list entry
+	module  string
+	value   string
+	comment string
+	line    int
+}
+
+// goModBlock is a directive ( ... ) block; end is the line of the )
+type goModBlock struct {
+	directive  string
+	start, end int
+}
+
+func parseGoMod(content string) (*goModDocument, error) {
+	doc := &goModDocument{newline: true}
+	if strings.TrimSpace(content) == "" {
+		return doc, nil
+	}
+	doc.newline = strings.HasSuffix(content, "\n")
+	doc.lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if _, _, err := doc.scan(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// scan finds the directives and blocks of the document
+func (d *goModDocument) scan() ([]goModEntry, []goModBlock, error) {
+	var entries []goModEntry
+	var blocks []goModBlock
+	block := -1
+	for i, raw := range d.lines {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if block >= 0 {
+			if line == ")" {
+				blocks[block].end = i
+				block = -1
+				continue
+			}
+			entries = append(entries, goModEntry{directive: blocks[block].directive, line: i, value: line})
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "(" {
+			blocks = append(blocks, goModBlock{directive: fields[0], start: i})
+			block = len(blocks) - 1
+			continue
+		}
+		value := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+		entries = append(entries, goModEntry{directive: fields[0], line: i, value: value})
+	}
+	if block >= 0 {
+		return nil, nil, errors.Errorf("line %d: %s block is not closed", blocks[block].start+1, blocks[block].directive)
+	}
+
+	for i, entry := range entries {
+		if index := strings.Index(entry.value, "//"); index >= 0 {
+			entries[i].comment = entry.value[index:]
+			entries[i].value = strings.TrimSpace(entry.value[:index])
+		}
+		if !goModLists[entry.directive] {
+			continue
+		}
+		fields := strings.Fields(entries[i].value)
+		if len(fields) == 0 {
+			return nil, nil, errors.Errorf("line %d: %s needs a module path", entry.line+1, entry.directive)
+		}
+		entries[i].module = fields[0]
+		entries[i].value = strings.TrimSpace(strings.TrimPrefix(entries[i].value, fields[0]))
+	}
+	return entries, blocks, nil
+}
+
+func (d *goModDocument) lookup(keyPath []string) (interface{}, kind) {
+	if len(keyPath) == 0 {
+		return nil, object
+	}
+	if len(keyPath) > 2 {
+		return nil, missing
+	}
+	entries, _, _ := d.scan()
+	modules := make(map[string]interface{})
+	for _, entry := range entries {
+		if entry.directive != keyPath[0] {
+			continue
+		}
+		if !goModLists[entry.directive] {
+			if len(keyPath) == 1 {
+				return entry.value, value
+			}
+			continue
+		}
+		if len(keyPath) == 2 && entry.module == keyPath[1] {
+			return entry.value, value
+		}
+		modules[entry.module] = entry.value
+	}
+	if len(keyPath) == 1 && goModLists[keyPath[0]] {
+		return modules, object
+	}
+	return nil, missing
+}
+
+func (d *goModDocument) set(keyPath []string, v interface{}, sorted bool) error {
+	if len(keyPath) == 0 {
+		return errors.New("can't replace the go.mod document")
+	}
+	directive := keyPath[0]
+	if goModLists[directive] != (len(keyPath) == 2) || len(keyPath) > 2 {
+		return errors.Errorf("go.mod has no key %s", strings.Join(keyPath, "."))
+	}
+	// Blocks are written with their first module
+	if fields, _, ok := objectFields(v); ok && len(fields) == 0 {
+		return nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return errors.Errorf("go.mod values are strings, got %s", display(v))
+	}
+
+	entries, blocks, err := d.scan()
+	if err != nil {
+		return err
+	}
+	if len(keyPath) == 1 {
+		for _, entry := range entries {
+			if entry.directive == directive {
+				d.lines[entry.line] = withComment(directive+" "+s, entry.comment)
+				return nil
+			}
+		}
+		d.insert(len(d.lines), true, directive+" "+s)
+		return nil
+	}
+
+	module := keyPath[1]
+	line := "\t" + strings.TrimSpace(module+" "+s)
+	for _, entry := range entries {
+		if entry.directive == directive && entry.module == module {
+			if strings.HasPrefix(strings.TrimSpace(d.lines[entry.line]), directive+" ") {
+				line = directive + " " + strings.TrimPrefix(line, "\t")
+			}
+			d.lines[entry.line] = withComment(line, entry.comment)
+			return nil
+		}
+	}
+
+	// A new module goes in the first block of its directive, where it
+	// sorts if the block is sorted
+	for _, block := range blocks {
+		if block.directive != directive {
+			continue
+		}
+		index := block.end
+		if sorted {
+			var modules []string
+			var lines []int
+			for _, entry := range entries {
+				if entry.line > block.start && entry.line < block.end {
+					modules = append(modules, entry.module)
+					lines = append(lines, entry.line)
+				}
+			}
+			if sort.StringsAreSorted(modules) {
+				if i := sort.SearchStrings(modules, module); i < len(lines) {
+					index = lines[i]
+				}
+			}
+		}
+		d.insert(index, false, line)
+		return nil
+	}
+	d.insert(len(d.lines), true, directive+" (", line, ")")
+	return nil
+}
+
+// withComment appends the trailing comment of the line it replaces to line
+func withComment(line, comment string) string {
+	if comment == "" {
+		return line
+	}
+	return line + " " + comment
+}
+
+// insert adds lines before index, after a blank line if spaced and the
+// document already has content
+func (d *goModDocument) insert(index int, spaced bool, lines ...string) {
+	if spaced && index > 0 && strings.TrimSpace(d.lines[index-1]) != "" {
+		lines = append([]string{""}, lines...)
+	}
+	updated := append([]string(nil), d.lines[:index]...)
+	updated = append(updated, lines...)
+	d.lines = append(updated, d.lines[index:]...)
+}
+
+func (d *goModDocument) normalize(v interface{}) interface{} {
+	return v
+}
+
+func (d *goModDocument) encode() (string, error) {
+	content := strings.Join(d.lines, "\n")
+	if d.newline && len(d.lines) > 0 {
+		content += "\n"
+	}
+	return content, nil
+}
@@ -0,0 +1,245 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// jsonObject is a JSON object that keeps its key order
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newJSONObject() *jsonObject {
+	return &jsonObject{values: make(map[string]interface{})}
+}
+
+// put sets key, replacing its value in place, or adding it at the end or,
+// if sorted and the keys are sorted, where it sorts
+func (o *jsonObject) put(key string, value interface{}, sorted bool) {
+	if _, exists := o.values[key]; !exists {
+		index := len(o.keys)
+		if sorted && sort.StringsAreSorted(o.keys) {
+			index = sort.SearchStrings(o.keys, key)
+		}
+		o.keys = append(o.keys, "")
+		copy(o.keys[index+1:], o.keys[index:])
+		o.keys[index] = key
+	}
+	o.values[key] = value
+}
+
+// MarshalJSON encodes the object with its keys in order
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	fields := make(Object, len(o.keys))
+	for i, key := range o.keys {
+		fields[i] = Field{Key: key, Value: o.values[key]}
+	}
+	return fields.MarshalJSON()
+}
+
+type jsonDocument struct {
+	root    interface{}
+	indent  string
+	newline bool
+}
+
+func parseJSON(content string) (*jsonDocument, error) {
+	doc := &jsonDocument{root: newJSONObject(), indent: "  ", newline: true}
+	if strings.TrimSpace(content) == "" {
+		return doc, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	root, err := decodeJSON(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+
+	doc.root = root
+	doc.indent = detectIndent(content, doc.indent)
+	doc.newline = strings.HasSuffix(content, "\n")
+	return doc, nil
+}
+
+// decodeJSON decodes the next value, keeping the key order of objects
+func decodeJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		obj := newJSONObject()
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			obj.put(key.(string), value, false)
+		}
+		_, err := decoder.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for decoder.More() {
+			item, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		_, err := decoder.Token()
+		return list, err
+	}
+	return token, nil
+}
+
+func (d *jsonDocument) lookup(keyPath []string) (interface{}, kind) {
+	node := d.root
+	for _, key := range keyPath {
+		obj, ok := node.(*jsonObject)
+		if !ok {
+			return nil, missing
+		}
+		if node, ok = obj.values[key]; !ok {
+			return nil, missing
+		}
+	}
+	if _, ok := node.(*jsonObject); ok {
+		return node, object
+	}
+	return node, value
+}
+
+func (d *jsonDocument) set(keyPath []string, v interface{}, sorted bool) error {
+	if len(keyPath) == 0 {
+		d.root = toJSON(v)
+		return nil
+	}
+	obj, ok := d.root.(*jsonObject)
+	if !ok {
+		return errors.New("the document is not a JSON object")
+	}
+	for _, key := range keyPath[:len(keyPath)-1] {
+		child, ok := obj.values[key].(*jsonObject)
+		if !ok {
+			child = newJSONObject()
+			obj.put(key, child, false)
+		}
+		obj = child
+	}
+	obj.put(keyPath[len(keyPath)-1], toJSON(v), sorted)
+	return nil
+}
+
+func (d *jsonDocument) normalize(v interface{}) interface{} {
+	return v
+}
+
+// toJSON converts a patch value to the document's representation
+func toJSON(v interface{}) interface{} {
+	if fields, sorted, ok := objectFields(v); ok {
+		obj := newJSONObject()
+		for _, field := range fields {
+			obj.put(field.Key, toJSON(field.Value), sorted)
+		}
+		return obj
+	}
+	if items, ok := listItems(v); ok {
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[i] = toJSON(item)
+		}
+		return list
+	}
+	return v
+}
+
+func (d *jsonDocument) encode() (string, error) {
+	var buf bytes.Buffer
+	if err := d.write(&buf, d.root, 0); err != nil {
+		return "", err
+	}
+	if d.newline {
+		buf.WriteByte('\n')
+	}
+	return buf.String(), nil
+}
+
+// write encodes v the way npm and most editors do: one key or item per
+// line, and no HTML escaping
+func (d *jsonDocument) write(buf *bytes.Buffer, v interface{}, depth int) error {
+	indent := strings.Repeat(d.indent, depth+1)
+	switch v := v.(type) {
+	case *jsonObject:
+		if len(v.keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i, key := range v.keys {
+			buf.WriteString(indent)
+			if err := writeJSONScalar(buf, key); err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			if err := d.write(buf, v.values[key], depth+1); err != nil {
+				return err
+			}
+			if i < len(v.keys)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat(d.indent, depth) + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range v {
+			buf.WriteString(indent)
+			if err := d.write(buf, item, depth+1); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat(d.indent, depth) + "]")
+	case json.Number:
+		buf.WriteString(v.String())
+	default:
+		return writeJSONScalar(buf, v)
+	}
+	return nil
+}
+
+func writeJSONScalar(buf *bytes.Buffer, v interface{}) error {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimSuffix(out.Bytes(), []byte("\n")))
+	return nil
+}
@@ -0,0 +1,405 @@
+// Package patch merges changes into structured project files such as
+// package.json, YAML configs, pyproject.toml, pom.xml and go.mod. Documents
+// keep their key order, formatting and comments where the format allows it,
+// so several plugins can contribute to one file without rewriting each
+// other.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Format is the syntax of a structured file
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+	TOML Format = "toml"
+	XML  Format = "xml"
+	// GoMod is a Go module's go.mod
+	GoMod Format = "go.mod"
+)
+
+// FormatOf returns the format of a file from its name
+func FormatOf(filePath string) (Format, error) {
+	name := path.Base(filePath)
+	switch name {
+	case "Pipfile":
+		return TOML, nil
+	case ".prettierrc", ".babelrc":
+		return JSON, nil
+	case "go.mod":
+		return GoMod, nil
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		return JSON, nil
+	case ".yaml", ".yml":
+		return YAML, nil
+	case ".toml":
+		return TOML, nil
+	case ".xml", ".csproj", ".props":
+		return XML, nil
+	}
+	return "", errors.Errorf("%s is not a JSON, YAML, TOML, XML or go.mod file", filePath)
+}
+
+// Policy decides what happens when a patch sets a key that already has a
+// different value
+type Policy int
+
+const (
+	// Error fails with a *ConflictError
+	Error Policy = iota
+	// Keep leaves the existing value
+	Keep
+	// Override replaces the existing value
+	Override
+	// Append adds the patch's list items the existing value doesn't have
+	// yet. A single existing value becomes the first item; values that
+	// aren't lists conflict as with Error.
+	Append
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Keep:
+		return "keep"
+	case Override:
+		return "override"
+	case Append:
+		return "append"
+	}
+	return "error"
+}
+
+// Field is one key of an Object
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Object is a set of keys to merge into a document, in order. Values may be
+// scalars, lists, Objects or maps. New keys from an Object are appended in
+// order; keys from a map have no order of their own and are inserted where
+// they sort when the keys already in the document are sorted, as npm keeps
+// dependencies. A nil map or Object adds nothing, while an empty one
+// creates an empty object. In XML, keys are element names, "@name" is an attribute and a
+// list is a repeated element.
+type Object []Field
+
+// Get returns the value of key
+func (o Object) Get(key string) (interface{}, bool) {
+	for _, field := range o {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// With returns a copy of the object with fields set. Keys the object has
+// keep their place; new ones are appended in order.
+func (o Object) With(fields ...Field) Object {
+	out := append(Object(nil), o...)
+next:
+	for _, field := range fields {
+		for i := range out {
+			if out[i].Key == field.Key {
+				out[i].Value = field.Value
+				continue next
+			}
+		}
+		out = append(out, field)
+	}
+	return out
+}
+
+// MarshalJSON encodes the object with its keys in order
+func (o Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// ConflictError is returned when a patch sets a key to a different value
+// under the Error policy
+type ConflictError struct {
+	Path string
+	// Key is the dotted path of the key, e.g. scripts.build
+	Key      string
+	Existing interface{}
+	Value    interface{}
+	// Owner is the plugin that set the existing value and Plugin the one
+	// patching, when known
+	Owner  string
+	Plugin string
+}
+
+func (e *ConflictError) Error() string {
+	existing := display(e.Existing)
+	if e.Owner != "" {
+		existing += " (set by " + e.Owner + ")"
+	}
+	by := ""
+	if e.Plugin != "" {
+		by = " by " + e.Plugin
+	}
+	return fmt.Sprintf("%s: %s is %s and can't be changed to %s%s", e.Path, e.Key, existing, display(e.Value), by)
+}
+
+// kind is what a document holds at a key path
+type kind int
+
+const (
+	missing kind = iota
+	object
+	value
+)
+
+// document is one format's order-preserving representation of a file
+type document interface {
+	// lookup returns the value at path and whether it is an object that
+	// can be merged key by key
+	lookup(path []string) (interface{}, kind)
+	// set stores value at path, creating parent objects. sorted places a
+	// new key where it sorts if the object's keys are sorted.
+	set(path []string, value interface{}, sorted bool) error
+	// normalize converts a patch value to the types lookup returns
+	normalize(value interface{}) interface{}
+	encode() (string, error)
+}
+
+// Document is a parsed structured file
+type Document struct {
+	path string
+	doc  document
+}
+
+// Parse parses the content of the file at path, in the format its name
+// implies. Empty content is an empty document.
+func Parse(filePath, content string) (*Document, error) {
+	format, err := FormatOf(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc document
+	switch format {
+	case JSON:
+		doc, err = parseJSON(content)
+	case YAML:
+		doc, err = parseYAML(content)
+	case TOML:
+		doc, err = parseTOML(content)
+	case XML:
+		doc, err = parseXML(content)
+	case GoMod:
+		doc, err = parseGoMod(content)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", filePath)
+	}
+	return &Document{path: filePath, doc: doc}, nil
+}
+
+// Get returns the value at a key path
+func (d *Document) Get(key ...string) (interface{}, bool) {
+	value, kind := d.doc.lookup(key)
+	return value, kind != missing
+}
+
+// Merge applies patch, resolving keys that already have a different value
+// with policy. It returns the dotted paths of the keys it set.
+func (d *Document) Merge(patch Object, policy Policy) ([]string, error) {
+	var changed []string
+	if err := d.merge(nil, d.doc.normalize(patch), false, policy, &changed); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// String encodes the document
+func (d *Document) String() (string, error) {
+	return d.doc.encode()
+}
+
+func (d *Document) merge(keyPath []string, patch interface{}, sorted bool, policy Policy, changed *[]string) error {
+	// A nil map or Object has nothing to merge, unlike an empty one
+	if isNilObject(patch) {
+		return nil
+	}
+	existing, kind := d.doc.lookup(keyPath)
+
+	if fields, fieldsSorted, ok := objectFields(patch); ok && kind != value {
+		if kind == missing {
+			if err := d.doc.set(keyPath, Object{}, sorted); err != nil {
+				return err
+			}
+		}
+		for _, field := range fields {
+			if err := d.merge(appendKey(keyPath, field.Key), field.Value, fieldsSorted, policy, changed); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	key := strings.Join(keyPath, ".")
+	switch {
+	case kind == missing:
+	case equal(existing, patch):
+		return nil
+	case policy == Keep:
+		return nil
+	case policy == Override:
+	case policy == Append && isList(patch):
+		patch = appendItems(existing, patch)
+		if equal(existing, patch) {
+			return nil
+		}
+	default:
+		return &ConflictError{Path: d.path, Key: key, Existing: existing, Value: patch}
+	}
+
+	if err := d.doc.set(keyPath, patch, sorted); err != nil {
+		return errors.Wrapf(err, "failed to set %s in %s", key, d.path)
+	}
+	*changed = append(*changed, key)
+	return nil
+}
+
+// appendKey returns keyPath with key added, without sharing its array
+func appendKey(keyPath []string, key string) []string {
+	return append(append([]string(nil), keyPath...), key)
+}
+
+// objectFields returns the fields of an Object or map, and whether they
+// were sorted because the map has no order
+func objectFields(v interface{}) ([]Field, bool, bool) {
+	if o, ok := v.(Object); ok {
+		return o, false, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false, false
+	}
+	fields := make([]Field, 0, rv.Len())
+	for _, key := range rv.MapKeys() {
+		fields = append(fields, Field{Key: key.String(), Value: rv.MapIndex(key).Interface()})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	return fields, true, true
+}
+
+func isNilObject(v interface{}) bool {
+	if o, ok := v.(Object); ok {
+		return o == nil
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Map && rv.IsNil()
+}
+
+// listItems returns the items of a slice
+func listItems(v interface{}) ([]interface{}, bool) {
+	rv := reflect.ValueOf(v)
+	if v == nil || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	if _, ok := v.(Object); ok {
+		return nil, false
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, true
+}
+
+func isList(v interface{}) bool {
+	_, ok := listItems(v)
+	return ok
+}
+
+// appendItems returns existing with the items of patch it doesn't have
+func appendItems(existing, patch interface{}) []interface{} {
+	items, ok := listItems(existing)
+	if !ok {
+		items = []interface{}{existing}
+	}
+	additions, _ := listItems(patch)
+next:
+	for _, addition := range additions {
+		for _, item := range items {
+			if equal(item, addition) {
+				continue next
+			}
+		}
+		items = append(items, addition)
+	}
+	return items
+}
+
+// equal compares values by their JSON meaning, so the key order of objects
+// and the Go types of numbers don't matter
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(canonical(a), canonical(b))
+}
+
+func canonical(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// display formats a value for messages
+func display(v interface{}) string {
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+// detectIndent returns the indentation of the first indented line, or
+// fallback
+func detectIndent(content, fallback string) string {
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,365 @@
+package patch
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func merge(t *testing.T, path, content string, patch Object, policy Policy) (string, []string) {
+	t.Helper()
+	doc, err := Parse(path, content)
+	if err != nil {
+		t.Fatalf("Expected %s to parse, got: %v", path, err)
+	}
+	changed, err := doc.Merge(patch, policy)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	out, err := doc.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out, changed
+}
+
+const packageJSON = `{
+  "name": "web",
+  "private": true,
+  "scripts": {
+    "build": "tsc && vite build",
+    "lint": "eslint ."
+  },
+  "devDependencies": {
+    "typescript": "^5.2.2",
+    "vite": "^5.0.0"
+  }
+}
+`
+
+func TestMergeJSONKeepsOrder(t *testing.T) {
+	out, changed := merge(t, "package.json", packageJSON, Object{
+		{"scripts", map[string]string{"dev": "vite", "build": "vite build", "preview": "vite preview"}},
+		{"devDependencies", map[string]string{"eslint": "^8.53.0", "vitest": "^1.0.0"}},
+		{"type", "module"},
+	}, Keep)
+
+	want := `{
+  "name": "web",
+  "private": true,
+  "scripts": {
+    "build": "tsc && vite build",
+    "dev": "vite",
+    "lint": "eslint .",
+    "preview": "vite preview"
+  },
+  "devDependencies": {
+    "eslint": "^8.53.0",
+    "typescript": "^5.2.2",
+    "vite": "^5.0.0",
+    "vitest": "^1.0.0"
+  },
+  "type": "module"
+}
+`
+	if out != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out)
+	}
+	wantChanged := []string{"scripts.dev", "scripts.preview", "devDependencies.eslint", "devDependencies.vitest", "type"}
+	if !reflect.DeepEqual(changed, wantChanged) {
+		t.Errorf("Expected changed keys %v, got %v", wantChanged, changed)
+	}
+}
+
+func TestObjectWith(t *testing.T) {
+	scripts := Object{{"dev", "vite"}, {"build", "vite build"}}
+	got := scripts.With(Field{"lint", "eslint ."}, Field{"build", "tsc && vite build"})
+
+	want := Object{{"dev", "vite"}, {"build", "tsc && vite build"}, {"lint", "eslint ."}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if build, _ := scripts.Get("build"); build != "vite build" {
+		t.Errorf("Expected With to leave the object alone, got build %v", build)
+	}
+}
+
+func TestMergePolicies(t *testing.T) {
+	patch := Object{
+		{"scripts", Object{{"build", "vite build"}}},
+		{"files", []string{"dist", "src"}},
+	}
+	content := `{"scripts": {"build": "tsc && vite build"}, "files": ["dist"]}`
+
+	doc, err := Parse("package.json", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = doc.Merge(patch, Error)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Key != "scripts.build" || conflict.Existing != "tsc && vite build" {
+		t.Fatalf("Expected a conflict on scripts.build, got: %v", err)
+	}
+
+	tests := []struct {
+		policy Policy
+		build  interface{}
+		files  interface{}
+	}{
+		{Keep, "tsc && vite build", []interface{}{"dist"}},
+		{Override, "vite build", []interface{}{"dist", "src"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			doc, err := Parse("package.json", content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := doc.Merge(patch, tt.policy); err != nil {
+				t.Fatal(err)
+			}
+			build, _ := doc.Get("scripts", "build")
+			files, _ := doc.Get("files")
+			if build != tt.build || !reflect.DeepEqual(files, tt.files) {
+				t.Errorf("Expected build %v and files %v, got %v and %v", tt.build, tt.files, build, files)
+			}
+		})
+	}
+
+	out, changed := merge(t, "package.json", content, Object{{"files", []string{"src", "dist"}}}, Append)
+	if !strings.Contains(out, "\"dist\",\n    \"src\"") || !reflect.DeepEqual(changed, []string{"files"}) {
+		t.Errorf("Expected append to add only the missing item, got %s (%v)", out, changed)
+	}
+	doc, err = Parse("package.json", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.Merge(Object{{"scripts", Object{{"build", "vite build"}}}}, Append); !errors.As(err, &conflict) {
+		t.Errorf("Expected append to conflict on a scalar, got: %v", err)
+	}
+}
+
+func TestMergeNewJSONFile(t *testing.T) {
+	out, _ := merge(t, "package.json", "", Object{
+		{"name", "web"},
+		{"private", true},
+		{"version", "0.0.0"},
+		{"type", "module"},
+		{"scripts", map[string]string{"test": "vitest", "build": "tsc && vite build"}},
+	}, Error)
+	want := "{\n  \"name\": \"web\",\n  \"private\": true,\n  \"version\": \"0.0.0\",\n  \"type\": \"module\",\n" +
+		"  \"scripts\": {\n    \"build\": \"tsc && vite build\",\n    \"test\": \"vitest\"\n  }\n}\n"
+	if out != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out)
+	}
+}
+
+func TestMergeYAMLKeepsComments(t *testing.T) {
+	content := `# CI for the web app
+name: CI
+on:
+  push:
+    branches: [main] # release branch
+jobs:
+  build:
+    runs-on: ubuntu-latest
+`
+	out, _ := merge(t, "ci.yml", content, Object{
+		{"on", Object{{"pull_request", Object{}}, {"push", Object{{"branches", []string{"main"}}}}}},
+		{"jobs", Object{{"build", Object{{"timeout-minutes", 10}}}}},
+	}, Error)
+
+	for _, want := range []string{"# CI for the web app", "# release branch", "  pull_request: {}", "    timeout-minutes: 10"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "name: CI") > strings.Index(out, "on:") {
+		t.Errorf("Expected key order to be kept, got\n%s", out)
+	}
+}
+
+func TestMergeTOML(t *testing.T) {
+	content := `[tool.poetry]
+name = "api" # the package
+
+[tool.poetry.dependencies]
+django = "^4.2"
+python = "^3.11"
+
+[build-system]
+requires = ["poetry-core"]
+`
+	out, changed := merge(t, "pyproject.toml", content, Object{
+		{"tool", Object{
+			{"poetry", Object{
+				{"version", "0.1.0"},
+				{"dependencies", map[string]string{"celery": "^5.3", "redis": "^5.0"}},
+			}},
+			{"black", Object{{"line-length", 100}}},
+		}},
+		{"build-system", Object{{"requires", []string{"poetry-core", "setuptools"}}}},
+	}, Append)
+
+	want := `[tool.poetry]
+name = "api" # the package
+version = "0.1.0"
+
+[tool.poetry.dependencies]
+celery = "^5.3"
+django = "^4.2"
+python = "^3.11"
+redis = "^5.0"
+
+[build-system]
+requires = ["poetry-core", "setuptools"]
+
+[tool.black]
+line-length = 100
+`
+	if out != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out)
+	}
+	if len(changed) != 5 {
+		t.Errorf("Expected 5 changed keys, got %v", changed)
+	}
+}
+
+func TestMergeGoMod(t *testing.T) {
+	content := `module example.com/api
+
+go 1.20
+
+require (
+	github.com/gin-gonic/gin v1.9.1
+	github.com/stretchr/testify v1.8.4 // indirect
+)
+`
+	out, changed := merge(t, "go.mod", content, Object{
+		{"go", "1.21"},
+		{"require", map[string]string{
+			"github.com/gin-contrib/cors": "v1.4.0",
+			"github.com/gin-gonic/gin":    "v1.9.1",
+			"github.com/stretchr/testify": "v1.9.0",
+		}},
+		{"replace", Object{{"example.com/lib", "=> ../lib"}}},
+	}, Override)
+
+	want := `module example.com/api
+
+go 1.21
+
+require (
+	github.com/gin-contrib/cors v1.4.0
+	github.com/gin-gonic/gin v1.9.1
+	github.com/stretchr/testify v1.9.0 // indirect
+)
+
+replace (
+	example.com/lib => ../lib
+)
+`
+	if out != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out)
+	}
+	if !reflect.DeepEqual(changed, []string{"go", "require.github.com/gin-contrib/cors", "require.github.com/stretchr/testify", "replace.example.com/lib"}) {
+		t.Errorf("Expected the changed keys, got %v", changed)
+	}
+
+	doc, err := Parse("go.mod", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.Merge(Object{{"go", "1.21"}}, Error); err == nil {
+		t.Error("Expected a different go version to conflict")
+	}
+	if _, err := doc.Merge(Object{{"require", Object{{"github.com/stretchr/testify", "v1.8.4"}}}}, Error); err != nil {
+		t.Errorf("Expected the // indirect comment not to be part of the version, got %v", err)
+	}
+	if _, err := Parse("go.mod", "module a\n\nrequire (\n"); err == nil {
+		t.Error("Expected an unclosed block to be rejected")
+	}
+}
+
+const pom = `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <!-- Generated by TiLoKit -->
+  <artifactId>api</artifactId>
+  <properties>
+    <java.version>17</java.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>org.springframework.boot</groupId>
+      <artifactId>spring-boot-starter-web</artifactId>
+    </dependency>
+  </dependencies>
+</project>
+`
+
+func TestMergeXML(t *testing.T) {
+	out, _ := merge(t, "pom.xml", pom, Object{{"project", Object{
+		{"properties", Object{{"java.version", 21}}},
+		{"dependencies", Object{{"dependency", []interface{}{
+			Object{{"groupId", "org.springframework.boot"}, {"artifactId", "spring-boot-starter-web"}},
+			Object{{"groupId", "org.postgresql"}, {"artifactId", "postgresql"}, {"scope", "runtime"}},
+		}}}},
+		{"build", Object{{"finalName", "api"}}},
+	}}}, Override)
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <!-- Generated by TiLoKit -->
+  <artifactId>api</artifactId>
+  <properties>
+    <java.version>21</java.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>org.springframework.boot</groupId>
+      <artifactId>spring-boot-starter-web</artifactId>
+    </dependency>
+    <dependency>
+      <groupId>org.postgresql</groupId>
+      <artifactId>postgresql</artifactId>
+      <scope>runtime</scope>
+    </dependency>
+  </dependencies>
+  <build>
+    <finalName>api</finalName>
+  </build>
+</project>
+`
+	if out != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out)
+	}
+
+	doc, err := Parse("pom.xml", pom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = doc.Merge(Object{{"project", Object{{"properties", Object{{"java.version", 21}}}}}}, Error)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Key != "project.properties.java.version" {
+		t.Errorf("Expected a conflict on the Java version, got: %v", err)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for path, want := range map[string]Format{
+		"package.json":   JSON,
+		".github/ci.yml": YAML,
+		"pyproject.toml": TOML,
+		"Pipfile":        TOML,
+		"pom.xml":        XML,
+		"api/go.mod":     GoMod,
+	} {
+		if got, err := FormatOf(path); err != nil || got != want {
+			t.Errorf("Expected %s to be %s, got %s (%v)", path, want, got, err)
+		}
+	}
+	if _, err := FormatOf("Dockerfile"); err == nil {
+		t.Error("Expected a Dockerfile not to be patchable")
+	}
+}
@@ -0,0 +1,372 @@
+package patch
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+)
+
+// tomlDocument edits TOML line by line, so everything it doesn't touch,
+// comments included, stays as written
+type tomlDocument struct {
+	lines   []string
+	newline bool
+}
+
+// tomlTable is a [table] or [[array]] header
+type tomlTable struct {
+	path  []string
+	line  int
+	array bool
+}
+
+// tomlEntry is a key = value pair, which may span several lines
+type tomlEntry struct {
+	// path is the table path followed by the key's parts
+	path []string
+	// table is the number of leading path parts naming the table
+	table      int
+	start, end int
+	array      bool
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func parseTOML(content string) (*tomlDocument, error) {
+	var check map[string]interface{}
+	if err := toml.Unmarshal([]byte(content), &check); err != nil {
+		return nil, err
+	}
+
+	doc := &tomlDocument{newline: true}
+	if content == "" {
+		return doc, nil
+	}
+	doc.newline = strings.HasSuffix(content, "\n")
+	doc.lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	return doc, nil
+}
+
+// scan finds the tables and entries of the document
+func (d *tomlDocument) scan() ([]tomlTable, []tomlEntry) {
+	var tables []tomlTable
+	var entries []tomlEntry
+	var table []string
+	array := false
+
+	for i := 0; i < len(d.lines); {
+		line := strings.TrimSpace(d.lines[i])
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			i++
+			continue
+		case strings.HasPrefix(line, "["):
+			array = strings.HasPrefix(line, "[[")
+			name := line[:strings.LastIndex(line, "]")+1]
+			table = splitTOMLKey(strings.Trim(name, "[] "))
+			tables = append(tables, tomlTable{path: table, line: i, array: array})
+			i++
+			continue
+		}
+
+		eq := indexOutsideQuotes(line, '=')
+		if eq < 0 {
+			i++
+			continue
+		}
+		// A value such as a multi-line array ends on the first line that
+		// completes it
+		end := i + 1
+		for ; end <= len(d.lines); end++ {
+			if _, ok := decodeTOMLValue(d.lines[i:end]); ok {
+				break
+			}
+		}
+		if end > len(d.lines) {
+			end = i + 1
+		}
+		path := append(append([]string(nil), table...), splitTOMLKey(line[:eq])...)
+		entries = append(entries, tomlEntry{path: path, table: len(table), start: i, end: end, array: array})
+		i = end
+	}
+	return tables, entries
+}
+
+// decodeTOMLValue decodes the value of the key = value pair in lines
+func decodeTOMLValue(lines []string) (interface{}, bool) {
+	text := strings.Join(lines, "\n")
+	eq := indexOutsideQuotes(text, '=')
+	var decoded map[string]interface{}
+	if eq < 0 || toml.Unmarshal([]byte("v ="+text[eq+1:]), &decoded) != nil {
+		return nil, false
+	}
+	return decoded["v"], true
+}
+
+func (d *tomlDocument) lookup(keyPath []string) (interface{}, kind) {
+	if len(keyPath) == 0 {
+		return nil, object
+	}
+	tables, entries := d.scan()
+	isObject := false
+	for _, entry := range entries {
+		if entry.array || !hasPrefix(entry.path, keyPath) {
+			continue
+		}
+		if len(entry.path) == len(keyPath) {
+			v, _ := decodeTOMLValue(d.lines[entry.start:entry.end])
+			return v, value
+		}
+		isObject = true
+	}
+	for _, table := range tables {
+		if !table.array && hasPrefix(table.path, keyPath) {
+			isObject = true
+		}
+	}
+	if isObject {
+		return nil, object
+	}
+	return nil, missing
+}
+
+func (d *tomlDocument) set(keyPath []string, v interface{}, sorted bool) error {
+	// Tables are written with their first key
+	if fields, _, ok := objectFields(v); ok && len(fields) == 0 {
+		return nil
+	}
+	if len(keyPath) == 0 {
+		return errors.New("can't replace the TOML document")
+	}
+	encoded, err := encodeTOMLValue(v)
+	if err != nil {
+		return err
+	}
+
+	tables, entries := d.scan()
+	for _, entry := range entries {
+		if !entry.array && equalPath(entry.path, keyPath) {
+			first := d.lines[entry.start]
+			line := first[:indexOutsideQuotes(first, '=')+1] + " " + encoded
+			d.replace(entry.start, entry.end, line)
+			return nil
+		}
+	}
+
+	tablePath, key := keyPath[:len(keyPath)-1], keyPath[len(keyPath)-1]
+	start, end, found := 0, len(d.lines), len(tablePath) == 0
+	for i, table := range tables {
+		if i == 0 && len(tablePath) == 0 {
+			end = table.line
+		}
+		if !table.array && equalPath(table.path, tablePath) {
+			start, end, found = table.line+1, len(d.lines), true
+			if i+1 < len(tables) {
+				end = tables[i+1].line
+			}
+		}
+	}
+	line := formatTOMLKey(key) + " = " + encoded
+
+	if !found {
+		if len(d.lines) > 0 && strings.TrimSpace(d.lines[len(d.lines)-1]) != "" {
+			d.lines = append(d.lines, "")
+		}
+		d.lines = append(d.lines, "["+formatTOMLPath(tablePath)+"]", line)
+		return nil
+	}
+
+	// Add the key after the table's last entry, or where it sorts
+	var keys []string
+	var section []tomlEntry
+	for _, entry := range entries {
+		if entry.start >= start && entry.start < end {
+			section = append(section, entry)
+			keys = append(keys, strings.Join(entry.path[entry.table:], "."))
+		}
+	}
+	at := start
+	if len(section) > 0 {
+		at = section[len(section)-1].end
+		if sorted && sort.StringsAreSorted(keys) {
+			if index := sort.SearchStrings(keys, key); index < len(section) {
+				at = section[index].start
+			}
+		}
+		first := d.lines[section[0].start]
+		line = first[:len(first)-len(strings.TrimLeft(first, " \t"))] + line
+	}
+	if at == 0 && len(tablePath) == 0 && len(tables) > 0 && tables[0].line == 0 {
+		d.replace(0, 0, line, "")
+		return nil
+	}
+	d.replace(at, at, line)
+	return nil
+}
+
+// replace replaces lines[start:end] with lines
+func (d *tomlDocument) replace(start, end int, lines ...string) {
+	updated := append([]string(nil), d.lines[:start]...)
+	updated = append(updated, lines...)
+	d.lines = append(updated, d.lines[end:]...)
+}
+
+func (d *tomlDocument) normalize(v interface{}) interface{} {
+	return v
+}
+
+func (d *tomlDocument) encode() (string, error) {
+	content := strings.Join(d.lines, "\n")
+	if d.newline && len(d.lines) > 0 {
+		content += "\n"
+	}
+	// Catch a patch that defined a key twice
+	var check map[string]interface{}
+	if err := toml.Unmarshal([]byte(content), &check); err != nil {
+		return "", errors.Wrap(err, "the patched TOML is invalid")
+	}
+	return content, nil
+}
+
+// encodeTOMLValue encodes a value for the right-hand side of a key, with
+// objects as inline tables
+func encodeTOMLValue(v interface{}) (string, error) {
+	if fields, _, ok := objectFields(v); ok {
+		parts := make([]string, 0, len(fields))
+		for _, field := range fields {
+			encoded, err := encodeTOMLValue(field.Value)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, formatTOMLKey(field.Key)+" = "+encoded)
+		}
+		if len(parts) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	}
+	if items, ok := listItems(v); ok {
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			encoded, err := encodeTOMLValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, encoded)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	}
+	if s, ok := v.(string); ok {
+		return quoteTOML(s), nil
+	}
+
+	data, err := toml.Marshal(map[string]interface{}{"v": v})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimPrefix(string(data), "v = ")), nil
+}
+
+// quoteTOML writes a basic string, as most TOML files use
+func quoteTOML(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func formatTOMLKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return quoteTOML(key)
+}
+
+func formatTOMLPath(keyPath []string) string {
+	parts := make([]string, len(keyPath))
+	for i, key := range keyPath {
+		parts[i] = formatTOMLKey(key)
+	}
+	return strings.Join(parts, ".")
+}
+
+// splitTOMLKey splits a dotted key into its parts, unquoting them
+func splitTOMLKey(key string) []string {
+	var parts []string
+	for {
+		dot := indexOutsideQuotes(key, '.')
+		part := key
+		if dot >= 0 {
+			part = key[:dot]
+		}
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && (part[0] == '"' || part[0] == '\'') && part[len(part)-1] == part[0] {
+			part = part[1 : len(part)-1]
+		}
+		parts = append(parts, part)
+		if dot < 0 {
+			return parts
+		}
+		key = key[dot+1:]
+	}
+}
+
+// indexOutsideQuotes returns the index of the first c that isn't quoted
+func indexOutsideQuotes(s string, c byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' && quote == '"' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == c:
+			return i
+		}
+	}
+	return -1
+}
+
+func hasPrefix(keyPath, prefix []string) bool {
+	return len(keyPath) >= len(prefix) && equalPath(keyPath[:len(prefix)], prefix)
+}
+
+func equalPath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,461 @@
+package patch
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type xmlKind int
+
+const (
+	xmlElement xmlKind = iota
+	xmlText
+	xmlComment
+	xmlProcInst
+	xmlDirective
+)
+
+// xmlNode is a node of the document, kept as written so unchanged parts
+// round-trip
+type xmlNode struct {
+	kind     xmlKind
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	// text is the content of text, comment and directive nodes, and the
+	// target and instruction of processing instructions
+	text string
+}
+
+// elements returns the child elements named name
+func (n *xmlNode) elements(name string) []*xmlNode {
+	var elements []*xmlNode
+	for _, child := range n.children {
+		if child.kind == xmlElement && child.name == name {
+			elements = append(elements, child)
+		}
+	}
+	return elements
+}
+
+// hasElements reports whether n has child elements
+func (n *xmlNode) hasElements() bool {
+	for _, child := range n.children {
+		if child.kind == xmlElement {
+			return true
+		}
+	}
+	return false
+}
+
+// textContent returns the text of an element without child elements
+func (n *xmlNode) textContent() string {
+	var b strings.Builder
+	for _, child := range n.children {
+		if child.kind == xmlText {
+			b.WriteString(child.text)
+		}
+	}
+	return b.String()
+}
+
+// xmlDocument is an XML file such as pom.xml. Keys are element names below
+// the root element, which is the first key.
+type xmlDocument struct {
+	nodes   []*xmlNode
+	indent  string
+	newline bool
+}
+
+func parseXML(content string) (*xmlDocument, error) {
+	doc := &xmlDocument{indent: "    ", newline: true}
+	if strings.TrimSpace(content) == "" {
+		return doc, nil
+	}
+	doc.newline = strings.HasSuffix(content, "\n")
+
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	var stack []*xmlNode
+	add := func(node *xmlNode) {
+		if len(stack) == 0 {
+			doc.nodes = append(doc.nodes, node)
+			return
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+	}
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{kind: xmlElement, name: xmlName(token.Name), attrs: token.Attr}
+			add(node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1].name != xmlName(token.Name) {
+				return nil, errors.Errorf("unexpected </%s>", xmlName(token.Name))
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			add(&xmlNode{kind: xmlText, text: string(token)})
+		case xml.Comment:
+			add(&xmlNode{kind: xmlComment, text: string(token)})
+		case xml.ProcInst:
+			text := token.Target
+			if len(token.Inst) > 0 {
+				text += " " + string(token.Inst)
+			}
+			add(&xmlNode{kind: xmlProcInst, text: text})
+		case xml.Directive:
+			add(&xmlNode{kind: xmlDirective, text: string(token)})
+		}
+	}
+	if len(stack) > 0 {
+		return nil, errors.Errorf("<%s> is not closed", stack[len(stack)-1].name)
+	}
+
+	if root := doc.root(); root != nil {
+		for _, child := range root.children {
+			if child.kind == xmlText && strings.Contains(child.text, "\n") {
+				if indent := child.text[strings.LastIndex(child.text, "\n")+1:]; indent != "" {
+					doc.indent = indent
+				}
+				break
+			}
+		}
+	}
+	return doc, nil
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+func (d *xmlDocument) root() *xmlNode {
+	for _, node := range d.nodes {
+		if node.kind == xmlElement {
+			return node
+		}
+	}
+	return nil
+}
+
+func (d *xmlDocument) lookup(keyPath []string) (interface{}, kind) {
+	root := d.root()
+	if len(keyPath) == 0 {
+		return nil, object
+	}
+	if root == nil || root.name != keyPath[0] {
+		return nil, missing
+	}
+
+	node := root
+	for i, key := range keyPath[1:] {
+		last := i == len(keyPath)-2
+		if strings.HasPrefix(key, "@") {
+			for _, attr := range node.attrs {
+				if last && xmlName(attr.Name) == key[1:] {
+					return attr.Value, value
+				}
+			}
+			return nil, missing
+		}
+		elements := node.elements(key)
+		switch {
+		case len(elements) == 0:
+			return nil, missing
+		case last && len(elements) > 1:
+			values := make([]interface{}, len(elements))
+			for j, element := range elements {
+				values[j] = xmlValue(element)
+			}
+			return values, value
+		}
+		node = elements[0]
+	}
+
+	switch {
+	case node.hasElements():
+		return xmlValue(node), object
+	case len(node.attrs) == 0 && strings.TrimSpace(node.textContent()) == "":
+		// An empty element is filled in as if it were missing
+		return nil, missing
+	}
+	return xmlValue(node), value
+}
+
+// xmlValue returns the content of an element as text, or as a map for an
+// element with children or attributes. Repeated children become lists.
+func xmlValue(node *xmlNode) interface{} {
+	if !node.hasElements() && len(node.attrs) == 0 {
+		return node.textContent()
+	}
+	values := make(map[string]interface{})
+	for _, attr := range node.attrs {
+		values["@"+xmlName(attr.Name)] = attr.Value
+	}
+	for _, child := range node.children {
+		if child.kind != xmlElement {
+			continue
+		}
+		v := xmlValue(child)
+		switch existing := values[child.name].(type) {
+		case nil:
+			values[child.name] = v
+		case []interface{}:
+			values[child.name] = append(existing, v)
+		default:
+			values[child.name] = []interface{}{existing, v}
+		}
+	}
+	return values
+}
+
+func (d *xmlDocument) set(keyPath []string, v interface{}, sorted bool) error {
+	if len(keyPath) == 0 {
+		return errors.New("can't replace the XML document")
+	}
+	root := d.root()
+	if root == nil {
+		root = &xmlNode{kind: xmlElement, name: keyPath[0]}
+		d.nodes = append(d.nodes,
+			&xmlNode{kind: xmlProcInst, text: `xml version="1.0" encoding="UTF-8"`},
+			&xmlNode{kind: xmlText, text: "\n"},
+			root)
+	}
+	if root.name != keyPath[0] {
+		return errors.Errorf("the root element is <%s>, not <%s>", root.name, keyPath[0])
+	}
+	if len(keyPath) == 1 {
+		d.setContent(root, v, 0)
+		return nil
+	}
+
+	parent, depth := root, 0
+	for _, key := range keyPath[1 : len(keyPath)-1] {
+		elements := parent.elements(key)
+		if len(elements) == 0 {
+			element := &xmlNode{kind: xmlElement, name: key}
+			d.insert(parent, len(parent.children), element, depth)
+			elements = append(elements, element)
+		}
+		parent, depth = elements[0], depth+1
+	}
+
+	key := keyPath[len(keyPath)-1]
+	if strings.HasPrefix(key, "@") {
+		setXMLAttr(parent, key[1:], fmt.Sprint(v))
+		return nil
+	}
+
+	existing := parent.elements(key)
+	items, isList := listItems(v)
+	if !isList {
+		if len(existing) > 0 {
+			d.setContent(existing[0], v, depth+1)
+			for _, extra := range existing[1:] {
+				d.remove(parent, extra)
+			}
+			return nil
+		}
+		items = []interface{}{v}
+	}
+
+	// Keep the elements that are still in the list, in place, and add the
+	// new items after them
+	kept := make([]bool, len(items))
+	for _, element := range existing {
+		found := false
+		for i, item := range items {
+			if !kept[i] && equal(xmlValue(element), item) {
+				kept[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			d.remove(parent, element)
+		}
+	}
+	at := len(parent.children)
+	for i, child := range parent.children {
+		if child.kind == xmlElement && child.name == key {
+			at = i + 1
+		}
+	}
+	for i, item := range items {
+		if kept[i] {
+			continue
+		}
+		element := &xmlNode{kind: xmlElement, name: key}
+		d.setContent(element, item, depth+1)
+		at = d.insert(parent, at, element, depth)
+	}
+	return nil
+}
+
+// insert adds child to parent, which is at depth, before the node at
+// index, indenting it like its siblings. It returns the index after child.
+func (d *xmlDocument) insert(parent *xmlNode, index int, child *xmlNode, depth int) int {
+	indent := "\n" + strings.Repeat(d.indent, depth+1)
+	if !parent.hasElements() {
+		parent.children = []*xmlNode{
+			{kind: xmlText, text: indent},
+			child,
+			{kind: xmlText, text: "\n" + strings.Repeat(d.indent, depth)},
+		}
+		return 2
+	}
+	// Insert after the last element before index, ahead of the whitespace
+	// that closes the parent
+	for index > 0 && parent.children[index-1].kind != xmlElement {
+		index--
+	}
+	nodes := append([]*xmlNode(nil), parent.children[:index]...)
+	nodes = append(nodes, &xmlNode{kind: xmlText, text: indent}, child)
+	parent.children = append(nodes, parent.children[index:]...)
+	return index + 2
+}
+
+// remove removes child from parent with the whitespace before it
+func (d *xmlDocument) remove(parent *xmlNode, child *xmlNode) {
+	for i, node := range parent.children {
+		if node != child {
+			continue
+		}
+		start := i
+		if i > 0 && parent.children[i-1].kind == xmlText && strings.TrimSpace(parent.children[i-1].text) == "" {
+			start--
+		}
+		parent.children = append(parent.children[:start], parent.children[i+1:]...)
+		return
+	}
+}
+
+// setContent replaces the content of element, which is at depth
+func (d *xmlDocument) setContent(element *xmlNode, v interface{}, depth int) {
+	fields, _, ok := objectFields(v)
+	if !ok {
+		element.children = []*xmlNode{{kind: xmlText, text: fmt.Sprint(v)}}
+		return
+	}
+
+	element.children = nil
+	for _, field := range fields {
+		if strings.HasPrefix(field.Key, "@") {
+			setXMLAttr(element, field.Key[1:], fmt.Sprint(field.Value))
+			continue
+		}
+		items, isList := listItems(field.Value)
+		if !isList {
+			items = []interface{}{field.Value}
+		}
+		for _, item := range items {
+			child := &xmlNode{kind: xmlElement, name: field.Key}
+			d.setContent(child, item, depth+1)
+			d.insert(element, len(element.children), child, depth)
+		}
+	}
+}
+
+func setXMLAttr(element *xmlNode, name, v string) {
+	for i, attr := range element.attrs {
+		if xmlName(attr.Name) == name {
+			element.attrs[i].Value = v
+			return
+		}
+	}
+	attrName := xml.Name{Local: name}
+	if prefix, local, ok := strings.Cut(name, ":"); ok {
+		attrName = xml.Name{Space: prefix, Local: local}
+	}
+	element.attrs = append(element.attrs, xml.Attr{Name: attrName, Value: v})
+}
+
+// normalize turns scalars into text, the only kind of value XML has
+func (d *xmlDocument) normalize(v interface{}) interface{} {
+	if isNilObject(v) {
+		return v
+	}
+	if o, ok := v.(Object); ok {
+		fields := make(Object, len(o))
+		for i, field := range o {
+			fields[i] = Field{Key: field.Key, Value: d.normalize(field.Value)}
+		}
+		return fields
+	}
+	if fields, _, ok := objectFields(v); ok {
+		values := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			values[field.Key] = d.normalize(field.Value)
+		}
+		return values
+	}
+	if items, ok := listItems(v); ok {
+		values := make([]interface{}, len(items))
+		for i, item := range items {
+			values[i] = d.normalize(item)
+		}
+		return values
+	}
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func (d *xmlDocument) encode() (string, error) {
+	var b strings.Builder
+	for _, node := range d.nodes {
+		writeXML(&b, node)
+	}
+	content := strings.TrimRight(b.String(), "\n")
+	if d.newline {
+		content += "\n"
+	}
+	return content, nil
+}
+
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;")
+)
+
+func writeXML(b *strings.Builder, node *xmlNode) {
+	switch node.kind {
+	case xmlText:
+		b.WriteString(xmlTextEscaper.Replace(node.text))
+	case xmlComment:
+		b.WriteString("<!--" + node.text + "-->")
+	case xmlProcInst:
+		b.WriteString("<?" + node.text + "?>")
+	case xmlDirective:
+		b.WriteString("<!" + node.text + ">")
+	case xmlElement:
+		b.WriteString("<" + node.name)
+		for _, attr := range node.attrs {
+			b.WriteString(" " + xmlName(attr.Name) + `="` + xmlAttrEscaper.Replace(attr.Value) + `"`)
+		}
+		if len(node.children) == 0 {
+			b.WriteString("/>")
+			return
+		}
+		b.WriteString(">")
+		for _, child := range node.children {
+			writeXML(b, child)
+		}
+		b.WriteString("</" + node.name + ">")
+	}
+}
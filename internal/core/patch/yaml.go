@@ -0,0 +1,169 @@
+package patch
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlDocument keeps the node tree, so comments and key order survive
+type yamlDocument struct {
+	root   *yaml.Node
+	indent int
+}
+
+func parseYAML(content string) (*yamlDocument, error) {
+	doc := &yamlDocument{indent: 2}
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return nil, err
+	}
+	if root.Kind == 0 || len(root.Content) == 0 {
+		root = yaml.Node{Kind: yaml.DocumentNode, HeadComment: root.HeadComment}
+		root.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("the document is not a YAML mapping")
+	}
+
+	doc.root = &root
+	if indent := len(strings.ReplaceAll(detectIndent(content, "  "), "\t", "  ")); indent >= 2 {
+		doc.indent = indent
+	}
+	return doc, nil
+}
+
+// yamlChild returns the value node of key in a mapping
+func yamlChild(mapping *yaml.Node, key string) (*yaml.Node, int) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			node := mapping.Content[i+1]
+			if node.Kind == yaml.AliasNode {
+				node = node.Alias
+			}
+			return node, i + 1
+		}
+	}
+	return nil, -1
+}
+
+func (d *yamlDocument) lookup(keyPath []string) (interface{}, kind) {
+	node := d.root.Content[0]
+	for _, key := range keyPath {
+		if node.Kind != yaml.MappingNode {
+			return nil, missing
+		}
+		if node, _ = yamlChild(node, key); node == nil {
+			return nil, missing
+		}
+	}
+
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return nil, value
+	}
+	if node.Kind == yaml.MappingNode {
+		return v, object
+	}
+	return v, value
+}
+
+func (d *yamlDocument) set(keyPath []string, v interface{}, sorted bool) error {
+	if len(keyPath) == 0 {
+		return errors.New("can't replace the YAML document")
+	}
+	mapping := d.root.Content[0]
+	for _, key := range keyPath[:len(keyPath)-1] {
+		child, _ := yamlChild(mapping, key)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			yamlPut(mapping, key, child, false)
+		}
+		if child.Kind != yaml.MappingNode {
+			return errors.Errorf("%s is not a mapping", key)
+		}
+		mapping = child
+	}
+
+	node, err := toYAML(v)
+	if err != nil {
+		return err
+	}
+	yamlPut(mapping, keyPath[len(keyPath)-1], node, sorted)
+	return nil
+}
+
+// yamlPut sets key in a mapping, keeping the comments of a replaced value
+func yamlPut(mapping *yaml.Node, key string, node *yaml.Node, sorted bool) {
+	if old, index := yamlChild(mapping, key); old != nil {
+		node.LineComment = old.LineComment
+		mapping.Content[index] = node
+		return
+	}
+
+	keys := make([]string, 0, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keys = append(keys, mapping.Content[i].Value)
+	}
+	index := len(keys)
+	if sorted && sort.StringsAreSorted(keys) {
+		index = sort.SearchStrings(keys, key)
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	content := append([]*yaml.Node(nil), mapping.Content[:index*2]...)
+	content = append(content, keyNode, node)
+	mapping.Content = append(content, mapping.Content[index*2:]...)
+}
+
+func (d *yamlDocument) normalize(v interface{}) interface{} {
+	return v
+}
+
+// toYAML converts a patch value to a node, keeping the order of Objects
+func toYAML(v interface{}) (*yaml.Node, error) {
+	if fields, sorted, ok := objectFields(v); ok {
+		mapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, field := range fields {
+			node, err := toYAML(field.Value)
+			if err != nil {
+				return nil, err
+			}
+			yamlPut(mapping, field.Key, node, sorted)
+		}
+		return mapping, nil
+	}
+	if items, ok := listItems(v); ok {
+		sequence := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range items {
+			node, err := toYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			sequence.Content = append(sequence.Content, node)
+		}
+		return sequence, nil
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (d *yamlDocument) encode() (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(d.indent)
+	if err := encoder.Encode(d.root); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
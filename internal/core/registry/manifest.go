@@ -0,0 +1,345 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/pkg/constants"
+)
+
+// APIVersion is the plugin API this build of TiLoKit implements. It only
+// changes when existing plugins would break; new capabilities are added as
+// optional interfaces detected by type assertion instead.
+const APIVersion = 1
+
+// MinAPIVersion is the oldest plugin API still supported
+const MinAPIVersion = 1
+
+// semverPattern matches a semantic version such as 1.4.0 or 2.0.0-rc.1,
+// optionally with a leading "v"
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+
+// OptionType is the kind of value an option or question takes
+type OptionType string
+
+// Supported option types
+const (
+	OptionString      OptionType = "string"
+	OptionBool        OptionType = "bool"
+	OptionInt         OptionType = "int"
+	OptionChoice      OptionType = "choice"
+	OptionMultiChoice OptionType = "multichoice"
+)
+
+// Option is a setting a plugin accepts under plugins.<name> in the config
+type Option struct {
+	Name        string      `json:"name" yaml:"name"`
+	Type        OptionType  `json:"type" yaml:"type"`
+	Description string      `json:"description,omitempty" yaml:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+	// Choices lists the values of a choice or multichoice option
+	Choices []string `json:"choices,omitempty" yaml:"choices,omitempty"`
+}
+
+// Manifest describes a plugin: what it is, the plugin API it was written
+// against, what it supports and the options it accepts
+type Manifest struct {
+	Name        string
+	Version     string
+	Description string
+	APIVersion  int
+	Frameworks  []string
+	BuildTools  []string
+	Options     []Option
+	// Capabilities lists the optional interfaces the plugin implements,
+	// such as "features" or "validator". ManifestOf fills it in.
+	Capabilities []string
+}
+
+// ManifestProvider is implemented by plugins that declare a manifest.
+// Plugins that don't are taken to target API version 1 and to accept no
+// options.
+type ManifestProvider interface {
+	Plugin
+	Manifest() Manifest
+}
+
+// Validator is implemented by plugins that check a configuration before
+// anything is generated. An error stops the run before any hook.
+type Validator interface {
+	Plugin
+	Validate(config *tilocontext.ProjectConfig) error
+}
+
+// Question is asked by a Prompter before generation. Its answer is stored
+// in the template variables under Name.
+type Question struct {
+	Name     string
+	Type     OptionType
+	Message  string
+	Help     string
+	Default  interface{}
+	Choices  []string
+	Required bool
+}
+
+// Prompter is implemented by plugins that ask the user questions of their
+// own. Questions whose variables are already set, e.g. with --set, are not
+// asked.
+type Prompter interface {
+	Plugin
+	Questions(config *tilocontext.ProjectConfig) []Question
+}
+
+// Augmenter is implemented by feature plugins that add their features to
+// an existing project differently from how they generate them. When
+// features are added, Augment runs instead of Generate.
+type Augmenter interface {
+	FeaturePlugin
+	Augment(ctx *tilocontext.ExecutionContext) error
+}
+
+// IncompatibleError is returned by Register for a plugin this version of
+// TiLoKit can't run
+type IncompatibleError struct {
+	Plugin string
+	Reason string
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("plugin %s is incompatible: %s", e.Plugin, e.Reason)
+}
+
+// ManifestOf returns the manifest of a plugin, built from its methods when
+// it doesn't declare one
+func ManifestOf(plugin Plugin) Manifest {
+	var m Manifest
+	if provider, ok := plugin.(ManifestProvider); ok {
+		m = provider.Manifest()
+	} else {
+		m.APIVersion = 1
+	}
+
+	if m.Name == "" {
+		m.Name = plugin.Name()
+	}
+	if m.Version == "" {
+		m.Version = plugin.Version()
+	}
+	if m.Description == "" {
+		m.Description = plugin.Description()
+	}
+	if m.Frameworks == nil {
+		m.Frameworks = plugin.SupportedFrameworks()
+	}
+	if m.BuildTools == nil {
+		m.BuildTools = plugin.SupportedBuildTools()
+	}
+	m.Capabilities = capabilities(plugin)
+	return m
+}
+
+// capabilities names the optional interfaces a plugin implements
+func capabilities(plugin Plugin) []string {
+	var names []string
+	if _, ok := plugin.(FeaturePlugin); ok {
+		names = append(names, "features")
+	}
+	if _, ok := plugin.(Augmenter); ok {
+		names = append(names, "augmenter")
+	}
+	if _, ok := plugin.(TemplateFuncProvider); ok {
+		names = append(names, "template-funcs")
+	}
+	if _, ok := plugin.(Dependent); ok {
+		names = append(names, "dependencies")
+	}
+	if _, ok := plugin.(Validator); ok {
+		names = append(names, "validator")
+	}
+	if _, ok := plugin.(Prompter); ok {
+		names = append(names, "prompter")
+	}
+	if tool, ok := plugin.(ToolPlugin); ok && tool.IsTool() {
+		names = append(names, "tool")
+	}
+	return names
+}
+
+// CheckPlugin reports whether this version of TiLoKit can run a plugin,
+// returning an *IncompatibleError that says what to change when it can't
+func CheckPlugin(plugin Plugin) error {
+	m := ManifestOf(plugin)
+	incompatible := func(format string, args ...interface{}) error {
+		return &IncompatibleError{Plugin: plugin.Name(), Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if plugin.Name() == "" {
+		return &IncompatibleError{Plugin: fmt.Sprintf("%T", plugin), Reason: "it has no name"}
+	}
+	if m.Name != plugin.Name() {
+		return incompatible("its manifest is named %s; Manifest().Name must match Name()", m.Name)
+	}
+	if !semverPattern.MatchString(m.Version) {
+		return incompatible("version %q is not a semantic version such as 1.2.3", m.Version)
+	}
+
+	supported := fmt.Sprintf("v%d", APIVersion)
+	if MinAPIVersion < APIVersion {
+		supported = fmt.Sprintf("v%d to v%d", MinAPIVersion, APIVersion)
+	}
+	switch {
+	case m.APIVersion <= 0:
+		return incompatible("its manifest declares no API version; set APIVersion to %d", APIVersion)
+	case m.APIVersion > APIVersion:
+		return incompatible("it needs plugin API v%d but TiLoKit %s supports %s; upgrade TiLoKit or use an older release of the plugin",
+			m.APIVersion, constants.Version, supported)
+	case m.APIVersion < MinAPIVersion:
+		return incompatible("it targets plugin API v%d, which TiLoKit %s no longer supports (it supports %s); upgrade the plugin",
+			m.APIVersion, constants.Version, supported)
+	}
+
+	if len(m.Frameworks) == 0 && len(m.BuildTools) == 0 {
+		return incompatible("it supports no frameworks or build tools")
+	}
+
+	seen := make(map[string]bool)
+	for _, option := range m.Options {
+		if err := option.check(); err != nil {
+			return incompatible("%v", err)
+		}
+		if seen[option.Name] {
+			return incompatible("option %s is declared twice", option.Name)
+		}
+		seen[option.Name] = true
+	}
+	return nil
+}
+
+// check validates the option definition itself
+func (o Option) check() error {
+	if o.Name == "" {
+		return fmt.Errorf("an option has no name")
+	}
+	switch o.Type {
+	case OptionString, OptionBool, OptionInt:
+	case OptionChoice, OptionMultiChoice:
+		if len(o.Choices) == 0 {
+			return fmt.Errorf("%s option %s lists no choices", o.Type, o.Name)
+		}
+	default:
+		return fmt.Errorf("option %s has unknown type %q; use string, bool, int, choice or multichoice", o.Name, o.Type)
+	}
+	if o.Default != nil {
+		if err := o.Check(o.Default); err != nil {
+			return fmt.Errorf("default of option %s: %v", o.Name, err)
+		}
+	}
+	return nil
+}
+
+// Check reports whether value, as decoded from YAML or JSON, suits the
+// option
+func (o Option) Check(value interface{}) error {
+	switch o.Type {
+	case OptionString:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected a string, got %v", value)
+		}
+	case OptionBool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected true or false, got %v", value)
+		}
+	case OptionInt:
+		switch n := value.(type) {
+		case int, int64:
+		case float64:
+			// JSON numbers
+			if n != float64(int64(n)) {
+				return fmt.Errorf("expected a whole number, got %v", value)
+			}
+		default:
+			return fmt.Errorf("expected a whole number, got %v", value)
+		}
+	case OptionChoice:
+		s, ok := value.(string)
+		if !ok || !contains(o.Choices, s) {
+			return fmt.Errorf("expected one of %s, got %v", strings.Join(o.Choices, ", "), value)
+		}
+	case OptionMultiChoice:
+		values, ok := value.([]interface{})
+		if !ok {
+			if list, isList := value.([]string); isList {
+				for _, s := range list {
+					values = append(values, s)
+				}
+				ok = true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("expected a list, got %v", value)
+		}
+		for _, v := range values {
+			if s, isString := v.(string); !isString || !contains(o.Choices, s) {
+				return fmt.Errorf("expected values from %s, got %v", strings.Join(o.Choices, ", "), v)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckOptions reports the first value in values the plugin doesn't
+// accept, in name order
+func (m Manifest) CheckOptions(values map[string]interface{}) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		option, found := m.option(name)
+		if !found {
+			accepted := make([]string, len(m.Options))
+			for i, o := range m.Options {
+				accepted[i] = o.Name
+			}
+			if len(accepted) == 0 {
+				return fmt.Errorf("plugin %s has no option %s; it accepts no options", m.Name, name)
+			}
+			return fmt.Errorf("plugin %s has no option %s; it accepts %s", m.Name, name, strings.Join(accepted, ", "))
+		}
+		if err := option.Check(values[name]); err != nil {
+			return fmt.Errorf("option %s of plugin %s: %v", name, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// OptionValues returns values with the defaults of the options it leaves
+// out
+func (m Manifest) OptionValues(values map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(values))
+	for _, option := range m.Options {
+		if option.Default != nil {
+			merged[option.Name] = option.Default
+		}
+	}
+	for name, value := range values {
+		merged[name] = value
+	}
+	return merged
+}
+
+func (m Manifest) option(name string) (Option, bool) {
+	for _, option := range m.Options {
+		if option.Name == name {
+			return option, true
+		}
+	}
+	return Option{}, false
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// manifestStub declares a manifest of its own
+type manifestStub struct {
+	stubPlugin
+	manifest Manifest
+}
+
+func (p *manifestStub) Manifest() Manifest { return p.manifest }
+
+func TestRegisterRejectsIncompatiblePlugins(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest Manifest
+		want     string
+	}{
+		{"no api version", Manifest{}, "set APIVersion to 1"},
+		{"newer api", Manifest{APIVersion: APIVersion + 1}, "upgrade TiLoKit"},
+		{"other name", Manifest{Name: "other", APIVersion: 1}, "Manifest().Name must match Name()"},
+		{"bad version", Manifest{Version: "1.0", APIVersion: 1}, `version "1.0" is not a semantic version`},
+		{"nothing supported", Manifest{APIVersion: 1, Frameworks: []string{}, BuildTools: []string{}}, "supports no frameworks or build tools"},
+		{"unknown option type", Manifest{APIVersion: 1, Options: []Option{{Name: "port", Type: "number"}}}, `unknown type "number"`},
+		{"duplicate option", Manifest{APIVersion: 1, Options: []Option{{Name: "port", Type: OptionInt}, {Name: "port", Type: OptionInt}}}, "declared twice"},
+		{"bad default", Manifest{APIVersion: 1, Options: []Option{{Name: "port", Type: OptionInt, Default: "80"}}}, "default of option port"},
+	}
+
+	for _, tt := range tests {
+		err := New().Register(&manifestStub{stubPlugin: stubPlugin{name: "acme"}, manifest: tt.manifest})
+		var incompatible *IncompatibleError
+		if !errors.As(err, &incompatible) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an incompatibility mentioning %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	compatible := &manifestStub{stubPlugin: stubPlugin{name: "acme"}, manifest: Manifest{
+		APIVersion: APIVersion,
+		Version:    "2.1.0-beta.1",
+		Options:    []Option{{Name: "tier", Type: OptionChoice, Choices: []string{"free", "pro"}, Default: "free"}},
+	}}
+	if err := New().Register(compatible); err != nil {
+		t.Errorf("Expected a compatible plugin to register, got %v", err)
+	}
+}
+
+func TestManifestOfPluginsWithoutManifest(t *testing.T) {
+	m := ManifestOf(&toolStub{stubPlugin{name: "git"}})
+
+	if m.Name != "git" || m.Version != "1.0.0" || m.APIVersion != 1 {
+		t.Errorf("Expected the manifest to come from the plugin's methods, got %+v", m)
+	}
+	if strings.Join(m.Capabilities, ",") != "dependencies,tool" {
+		t.Errorf("Expected capabilities detected by type assertion, got %v", m.Capabilities)
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	m := Manifest{Name: "acme", Options: []Option{
+		{Name: "port", Type: OptionInt, Default: 8080},
+		{Name: "regions", Type: OptionMultiChoice, Choices: []string{"eu", "us"}},
+	}}
+
+	if err := m.CheckOptions(map[string]interface{}{"port": 80, "regions": []interface{}{"eu"}}); err != nil {
+		t.Errorf("Expected valid options, got %v", err)
+	}
+	if err := m.CheckOptions(map[string]interface{}{"prot": 80}); err == nil || !strings.Contains(err.Error(), "it accepts port, regions") {
+		t.Errorf("Expected an unknown option to list the accepted ones, got %v", err)
+	}
+	if err := m.CheckOptions(map[string]interface{}{"regions": []interface{}{"ap"}}); err == nil {
+		t.Error("Expected a value outside the choices to be rejected")
+	}
+
+	values := m.OptionValues(map[string]interface{}{"regions": []interface{}{"us"}})
+	if values["port"] != 8080 || len(values) != 2 {
+		t.Errorf("Expected defaults for options left out, got %v", values)
+	}
+}
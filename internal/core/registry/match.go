@@ -0,0 +1,161 @@
+package registry
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Wildcard matches every framework or build tool
+const Wildcard = "*"
+
+// ToolPlugin is implemented by cross-cutting plugins, such as git
+// integration, that attach to every run their patterns match unless the
+// user excludes them. Tool plugins alone never satisfy a selection.
+type ToolPlugin interface {
+	Plugin
+	IsTool() bool
+}
+
+// frameworkFamilies groups frameworks so plugins can target a whole
+// ecosystem with patterns like "js:*" or "python:fla*".
+var frameworkFamilies = map[string][]string{
+	"js":      {"react", "vue", "svelte", "angular", "next", "nuxt", "gatsby", "vanilla"},
+	"node":    {"express", "nestjs", "fastify"},
+	"python":  {"django", "flask", "fastapi"},
+	"php":     {"laravel", "symfony"},
+	"java":    {"spring-boot", "quarkus"},
+	"go":      {"gin", "echo", "fiber"},
+	"rust":    {"actix", "rocket", "axum"},
+	"csharp":  {"aspnetcore", "blazor"},
+	"ruby":    {"rails", "sinatra"},
+	"mobile":  {"react-native", "flutter", "ionic"},
+	"desktop": {"electron", "tauri", "wails"},
+}
+
+// buildToolFamilies groups build tools the same way as frameworkFamilies
+var buildToolFamilies = map[string][]string{
+	"js":     {"vite", "webpack", "rollup", "parcel", "angular-cli", "next", "nuxt", "gatsby"},
+	"node":   {"npm", "yarn", "pnpm"},
+	"python": {"pip", "poetry", "pipenv"},
+	"php":    {"composer"},
+	"java":   {"maven", "gradle"},
+	"go":     {"go-modules"},
+	"rust":   {"cargo"},
+	"csharp": {"dotnet"},
+	"ruby":   {"bundler", "gem"},
+	"mobile": {"metro", "expo", "flutter-cli"},
+}
+
+// Decision records whether a plugin was selected for a run and why
+type Decision struct {
+	Plugin   Plugin
+	Selected bool
+	Tool     bool
+	Reason   string
+}
+
+// MatchPattern reports whether value matches a plugin pattern. Patterns are
+// "*", shell globs such as "spring-*", or family patterns such as "js:*".
+func MatchPattern(pattern, value string, families map[string][]string) bool {
+	if pattern == Wildcard {
+		return true
+	}
+
+	if family, glob, ok := strings.Cut(pattern, ":"); ok {
+		members, known := families[family]
+		if !known || !contains(members, value) {
+			return false
+		}
+		pattern = glob
+	}
+
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
+
+// MatchFramework reports whether a framework matches a plugin pattern,
+// using the framework families
+func MatchFramework(pattern, framework string) bool {
+	return MatchPattern(pattern, framework, frameworkFamilies)
+}
+
+// IsPattern reports whether a supported framework or build tool entry is a
+// pattern rather than a literal name.
+func IsPattern(entry string) bool {
+	return strings.ContainsAny(entry, "*?[:")
+}
+
+// Explain evaluates every registered plugin against the given framework and
+// build tool and reports why each one was or wasn't selected.
+func (r *PluginRegistry) Explain(framework, buildTool string, exclude []string) []Decision {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	decisions := make([]Decision, 0, len(r.plugins))
+	for _, name := range r.sortedNames() {
+		decisions = append(decisions, r.decide(r.plugins[name], framework, buildTool, exclude))
+	}
+	return decisions
+}
+
+func (r *PluginRegistry) decide(plugin Plugin, framework, buildTool string, exclude []string) Decision {
+	decision := Decision{Plugin: plugin, Tool: isTool(plugin)}
+
+	if contains(exclude, plugin.Name()) {
+		decision.Reason = "excluded by user"
+		return decision
+	}
+
+	var reasons []string
+	if framework != "" {
+		pattern, ok := firstMatch(plugin.SupportedFrameworks(), framework, frameworkFamilies)
+		if !ok {
+			decision.Reason = fmt.Sprintf("framework %q not supported (supports: %s)",
+				framework, strings.Join(plugin.SupportedFrameworks(), ", "))
+			return decision
+		}
+		reasons = append(reasons, fmt.Sprintf("framework %q matched %q", framework, pattern))
+	}
+
+	if buildTool != "" {
+		pattern, ok := firstMatch(plugin.SupportedBuildTools(), buildTool, buildToolFamilies)
+		if !ok {
+			decision.Reason = fmt.Sprintf("build tool %q not supported (supports: %s)",
+				buildTool, strings.Join(plugin.SupportedBuildTools(), ", "))
+			return decision
+		}
+		reasons = append(reasons, fmt.Sprintf("build tool %q matched %q", buildTool, pattern))
+	}
+
+	if decision.Tool {
+		reasons = append(reasons, "tool plugin attached to every run")
+	}
+
+	decision.Selected = true
+	decision.Reason = strings.Join(reasons, "; ")
+	return decision
+}
+
+func firstMatch(patterns []string, value string, families map[string][]string) (string, bool) {
+	for _, pattern := range patterns {
+		if MatchPattern(pattern, value, families) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func isTool(plugin Plugin) bool {
+	tool, ok := plugin.(ToolPlugin)
+	return ok && tool.IsTool()
+}
+
+func contains(list []string, item string) bool {
+	for _, entry := range list {
+		if entry == item {
+			return true
+		}
+	}
+	return false
+}
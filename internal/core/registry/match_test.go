@@ -0,0 +1,68 @@
+package registry
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"*", "react", true},
+		{"react", "react", true},
+		{"react", "vue", false},
+		{"spring-*", "spring-boot", true},
+		{"js:*", "vue", true},
+		{"js:*", "django", false},
+		{"python:fla*", "flask", true},
+		{"python:fla*", "fastapi", false},
+		{"unknown:*", "react", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPattern(tt.pattern, tt.value, frameworkFamilies); got != tt.want {
+			t.Errorf("MatchPattern(%q, %q) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPluginsAttachesToolPlugins(t *testing.T) {
+	r := New()
+	for _, plugin := range []Plugin{
+		&stubPlugin{name: "react"},
+		&toolStub{stubPlugin{name: "git"}},
+	} {
+		if err := r.Register(plugin); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plugins, err := r.LoadPlugins("stub", "stub")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(plugins) != 2 {
+		t.Fatalf("Expected framework and tool plugin, got %v", pluginNames(plugins))
+	}
+
+	plugins, err = r.LoadPlugins("stub", "stub", "git")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(plugins) != 1 || plugins[0].Name() != "react" {
+		t.Fatalf("Expected git to be excluded, got %v", pluginNames(plugins))
+	}
+
+	if _, err := r.LoadPlugins("other", "other"); err == nil {
+		t.Fatal("Expected error when only tool plugins match, got nil")
+	}
+}
+
+// toolStub is a cross-cutting stub plugin matching every framework
+type toolStub struct {
+	stubPlugin
+}
+
+func (p *toolStub) SupportedFrameworks() []string { return []string{Wildcard} }
+func (p *toolStub) SupportedBuildTools() []string { return []string{Wildcard} }
+func (p *toolStub) IsTool() bool                  { return true }
@@ -2,6 +2,7 @@ package registry
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -20,6 +21,22 @@ type Plugin interface {
 	PostGenerate(ctx *tilocontext.ExecutionContext) error
 }
 
+// TemplateFuncProvider is implemented by plugins that add functions to
+// every template rendered during a run. Later plugins in schedule order
+// override functions of the same name.
+type TemplateFuncProvider interface {
+	Plugin
+	TemplateFuncs() map[string]interface{}
+}
+
+// FeaturePlugin is implemented by plugins that can add features to a
+// project that already exists. When features are added, only the plugins
+// providing one of them run, with the execution context in augment mode.
+type FeaturePlugin interface {
+	Plugin
+	Features() []string
+}
+
 // PluginRegistry manages plugin registration and loading
 type PluginRegistry struct {
 	plugins map[string]Plugin
@@ -33,8 +50,13 @@ func New() *PluginRegistry {
 	}
 }
 
-// Register registers a plugin with the registry
+// Register registers a plugin with the registry. A plugin this version of
+// TiLoKit can't run is rejected with an *IncompatibleError.
 func (r *PluginRegistry) Register(plugin Plugin) error {
+	if err := CheckPlugin(plugin); err != nil {
+		return err
+	}
+
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -60,34 +82,61 @@ func (r *PluginRegistry) GetPlugin(name string) (Plugin, error) {
 	return plugin, nil
 }
 
-// LoadPlugins loads plugins based on framework and build tool
-func (r *PluginRegistry) LoadPlugins(framework, buildTool string) ([]Plugin, error) {
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
-
+// LoadPlugins loads plugins based on framework and build tool. Plugins named
+// in exclude are skipped, which is how users opt out of tool plugins.
+func (r *PluginRegistry) LoadPlugins(framework, buildTool string, exclude ...string) ([]Plugin, error) {
 	var selectedPlugins []Plugin
+	foundNonTool := false
 
-	for _, plugin := range r.plugins {
-		// Check if plugin supports the framework
-		if framework != "" && !r.supportsFramework(plugin, framework) {
+	for _, decision := range r.Explain(framework, buildTool, exclude) {
+		if !decision.Selected {
 			continue
 		}
-
-		// Check if plugin supports the build tool
-		if buildTool != "" && !r.supportsBuildTool(plugin, buildTool) {
-			continue
+		if !decision.Tool {
+			foundNonTool = true
 		}
-
-		selectedPlugins = append(selectedPlugins, plugin)
+		selectedPlugins = append(selectedPlugins, decision.Plugin)
 	}
 
-	if len(selectedPlugins) == 0 {
+	if !foundNonTool {
 		return nil, errors.Errorf("no plugins found for framework: %s, build tool: %s", framework, buildTool)
 	}
 
 	return selectedPlugins, nil
 }
 
+// LoadFeaturePlugins loads the plugins that add features to a project of
+// the given framework and build tool. Every feature must be provided by at
+// least one plugin.
+func (r *PluginRegistry) LoadFeaturePlugins(framework, buildTool string, features []string, exclude ...string) ([]Plugin, error) {
+	var selectedPlugins []Plugin
+	provided := make(map[string]bool)
+
+	for _, decision := range r.Explain(framework, buildTool, exclude) {
+		provider, ok := decision.Plugin.(FeaturePlugin)
+		if !decision.Selected || !ok {
+			continue
+		}
+		selected := false
+		for _, feature := range provider.Features() {
+			if contains(features, feature) {
+				provided[feature] = true
+				selected = true
+			}
+		}
+		if selected {
+			selectedPlugins = append(selectedPlugins, decision.Plugin)
+		}
+	}
+
+	for _, feature := range features {
+		if !provided[feature] {
+			return nil, errors.Errorf("no plugin can add feature %s to a %s project", feature, framework)
+		}
+	}
+	return selectedPlugins, nil
+}
+
 // ListPlugins returns all registered plugins
 func (r *PluginRegistry) ListPlugins() map[string]Plugin {
 	r.mutex.RLock()
@@ -109,6 +158,9 @@ func (r *PluginRegistry) GetSupportedFrameworks() []string {
 	frameworkSet := make(map[string]bool)
 	for _, plugin := range r.plugins {
 		for _, framework := range plugin.SupportedFrameworks() {
+			if IsPattern(framework) {
+				continue
+			}
 			frameworkSet[framework] = true
 		}
 	}
@@ -117,6 +169,7 @@ func (r *PluginRegistry) GetSupportedFrameworks() []string {
 	for framework := range frameworkSet {
 		frameworks = append(frameworks, framework)
 	}
+	sort.Strings(frameworks)
 
 	return frameworks
 }
@@ -129,6 +182,9 @@ func (r *PluginRegistry) GetSupportedBuildTools() []string {
 	buildToolSet := make(map[string]bool)
 	for _, plugin := range r.plugins {
 		for _, buildTool := range plugin.SupportedBuildTools() {
+			if IsPattern(buildTool) {
+				continue
+			}
 			buildToolSet[buildTool] = true
 		}
 	}
@@ -137,24 +193,18 @@ func (r *PluginRegistry) GetSupportedBuildTools() []string {
 	for buildTool := range buildToolSet {
 		buildTools = append(buildTools, buildTool)
 	}
+	sort.Strings(buildTools)
 
 	return buildTools
 }
 
-func (r *PluginRegistry) supportsFramework(plugin Plugin, framework string) bool {
-	for _, supported := range plugin.SupportedFrameworks() {
-		if supported == framework {
-			return true
-		}
-	}
-	return false
-}
-
-func (r *PluginRegistry) supportsBuildTool(plugin Plugin, buildTool string) bool {
-	for _, supported := range plugin.SupportedBuildTools() {
-		if supported == buildTool {
-			return true
-		}
+// sortedNames returns registered plugin names in a stable order; callers
+// must hold the registry lock.
+func (r *PluginRegistry) sortedNames() []string {
+	names := make([]string, 0, len(r.plugins))
+	for name := range r.plugins {
+		names = append(names, name)
 	}
-	return false
+	sort.Strings(names)
+	return names
 }
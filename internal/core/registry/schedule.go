@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RunAfterAll can be listed in Dependencies.After to order a plugin after
+// every selected plugin that does not itself use RunAfterAll.
+const RunAfterAll = "*"
+
+// Dependencies describes how a plugin relates to other plugins in a run.
+// Entries in Requires and After may name either a plugin or a capability
+// listed in another plugin's Provides.
+type Dependencies struct {
+	// Requires must be satisfied by a selected plugin, which runs first
+	Requires []string
+	// After only orders the plugin when a matching plugin is selected
+	After []string
+	// Provides lists capabilities this plugin contributes, e.g. "package.json"
+	Provides []string
+}
+
+// Dependent is implemented by plugins that declare ordering constraints.
+// Plugins that don't implement it are scheduled by name only.
+type Dependent interface {
+	Dependencies() Dependencies
+}
+
+// CycleError reports a dependency cycle between plugins
+type CycleError struct {
+	Cycle []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("plugin dependency cycle detected: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// Schedule orders plugins so that every plugin runs after the plugins it
+// requires or runs after. Ties are broken by plugin name, which makes the
+// order identical from run to run.
+func Schedule(plugins []Plugin) ([]Plugin, error) {
+	byName := make(map[string]Plugin, len(plugins))
+	names := make([]string, 0, len(plugins))
+	providers := make(map[string][]string)
+
+	for _, plugin := range plugins {
+		name := plugin.Name()
+		byName[name] = plugin
+		names = append(names, name)
+		providers[name] = append(providers[name], name)
+		for _, capability := range dependenciesOf(plugin).Provides {
+			providers[capability] = append(providers[capability], name)
+		}
+	}
+	sort.Strings(names)
+
+	edges := make(map[string]map[string]bool, len(names))
+	indegree := make(map[string]int, len(names))
+	addEdge := func(from, to string) {
+		if from == to || edges[from][to] {
+			return
+		}
+		if edges[from] == nil {
+			edges[from] = make(map[string]bool)
+		}
+		edges[from][to] = true
+		indegree[to]++
+	}
+
+	runsLast := make(map[string]bool)
+	for _, name := range names {
+		deps := dependenciesOf(byName[name])
+		for _, required := range deps.Requires {
+			found := providers[required]
+			if len(found) == 0 {
+				return nil, errors.Errorf("plugin %s requires %q, which no selected plugin provides", name, required)
+			}
+			for _, provider := range found {
+				addEdge(provider, name)
+			}
+		}
+		for _, after := range deps.After {
+			if after == RunAfterAll {
+				runsLast[name] = true
+				continue
+			}
+			for _, provider := range providers[after] {
+				addEdge(provider, name)
+			}
+		}
+	}
+
+	for name := range runsLast {
+		for _, other := range names {
+			if !runsLast[other] {
+				addEdge(other, name)
+			}
+		}
+	}
+
+	// Kahn's algorithm, always picking the alphabetically first ready plugin
+	var ready []string
+	for _, name := range names {
+		if indegree[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	ordered := make([]Plugin, 0, len(names))
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, byName[name])
+
+		for _, next := range sortedKeys(edges[name]) {
+			indegree[next]--
+			if indegree[next] == 0 {
+				ready = insertSorted(ready, next)
+			}
+		}
+	}
+
+	if len(ordered) != len(names) {
+		return nil, &CycleError{Cycle: findCycle(names, edges, indegree)}
+	}
+
+	return ordered, nil
+}
+
+func dependenciesOf(plugin Plugin) Dependencies {
+	if dependent, ok := plugin.(Dependent); ok {
+		return dependent.Dependencies()
+	}
+	return Dependencies{}
+}
+
+// findCycle walks the plugins left unscheduled by Schedule and returns the
+// first cycle found, closed by repeating its starting plugin.
+func findCycle(names []string, edges map[string]map[string]bool, indegree map[string]int) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	var cycle []string
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, next := range sortedKeys(edges[name]) {
+			if indegree[next] == 0 {
+				continue
+			}
+			switch state[next] {
+			case visiting:
+				for i, entry := range stack {
+					if entry == next {
+						cycle = append(append([]string{}, stack[i:]...), next)
+						return true
+					}
+				}
+			case unvisited:
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		return false
+	}
+
+	for _, name := range names {
+		if indegree[name] > 0 && state[name] == unvisited && visit(name) {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func insertSorted(list []string, item string) []string {
+	i := sort.SearchStrings(list, item)
+	list = append(list, "")
+	copy(list[i+1:], list[i:])
+	list[i] = item
+	return list
+}
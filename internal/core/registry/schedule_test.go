@@ -0,0 +1,100 @@
+package registry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+)
+
+func TestScheduleOrdersByDependencies(t *testing.T) {
+	plugins := []Plugin{
+		&stubPlugin{name: "git", deps: Dependencies{After: []string{RunAfterAll}}},
+		&stubPlugin{name: "builder", deps: Dependencies{After: []string{"package.json"}}},
+		&stubPlugin{name: "zz-framework", deps: Dependencies{Provides: []string{"package.json"}}},
+		&stubPlugin{name: "linter", deps: Dependencies{Requires: []string{"builder"}}},
+	}
+
+	ordered, err := Schedule(plugins)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := []string{"zz-framework", "builder", "linter", "git"}
+	if got := pluginNames(ordered); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected order %v, got %v", want, got)
+	}
+}
+
+func TestScheduleIsStable(t *testing.T) {
+	first := []Plugin{&stubPlugin{name: "c"}, &stubPlugin{name: "a"}, &stubPlugin{name: "b"}}
+	second := []Plugin{&stubPlugin{name: "b"}, &stubPlugin{name: "c"}, &stubPlugin{name: "a"}}
+
+	a, err := Schedule(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Schedule(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(pluginNames(a), pluginNames(b)) {
+		t.Fatalf("Expected identical orders, got %v and %v", pluginNames(a), pluginNames(b))
+	}
+}
+
+func TestScheduleReportsCycle(t *testing.T) {
+	plugins := []Plugin{
+		&stubPlugin{name: "a", deps: Dependencies{After: []string{"c"}}},
+		&stubPlugin{name: "b", deps: Dependencies{After: []string{"a"}}},
+		&stubPlugin{name: "c", deps: Dependencies{Requires: []string{"b"}}},
+		&stubPlugin{name: "d", deps: Dependencies{After: []string{"c"}}},
+	}
+
+	_, err := Schedule(plugins)
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("Expected CycleError, got: %v", err)
+	}
+
+	want := []string{"a", "b", "c", "a"}
+	if !reflect.DeepEqual(cycleErr.Cycle, want) {
+		t.Fatalf("Expected cycle %v, got %v", want, cycleErr.Cycle)
+	}
+}
+
+func TestScheduleMissingRequirement(t *testing.T) {
+	plugins := []Plugin{
+		&stubPlugin{name: "builder", deps: Dependencies{Requires: []string{"package.json"}}},
+	}
+
+	if _, err := Schedule(plugins); err == nil {
+		t.Fatal("Expected error for unsatisfied requirement, got nil")
+	}
+}
+
+func pluginNames(plugins []Plugin) []string {
+	names := make([]string, 0, len(plugins))
+	for _, plugin := range plugins {
+		names = append(names, plugin.Name())
+	}
+	return names
+}
+
+// stubPlugin for testing
+type stubPlugin struct {
+	name string
+	deps Dependencies
+}
+
+func (p *stubPlugin) Name() string                                         { return p.name }
+func (p *stubPlugin) Version() string                                      { return "1.0.0" }
+func (p *stubPlugin) Description() string                                  { return "Stub plugin for testing" }
+func (p *stubPlugin) SupportedFrameworks() []string                        { return []string{"stub"} }
+func (p *stubPlugin) SupportedBuildTools() []string                        { return []string{"stub"} }
+func (p *stubPlugin) Dependencies() Dependencies                           { return p.deps }
+func (p *stubPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error  { return nil }
+func (p *stubPlugin) Generate(ctx *tilocontext.ExecutionContext) error     { return nil }
+func (p *stubPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error { return nil }
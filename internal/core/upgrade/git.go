@@ -0,0 +1,266 @@
+package upgrade
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/pkg/errors"
+
+	"github.com/ti-lo/tilokit/internal/core/engine"
+)
+
+// GeneratedRef points at a commit holding exactly what TiLoKit generated at
+// the last upgrade. It is the merge base of the next upgrade and is never
+// checked out.
+const GeneratedRef = plumbing.ReferenceName("refs/tilokit/generated")
+
+// repository is the git repository a project lives in. The project may be
+// a subdirectory, e.g. in a monorepo.
+type repository struct {
+	repo *git.Repository
+	// prefix is the project's path inside the repository, "" at its root
+	prefix string
+}
+
+// openRepository opens the repository containing dir. It returns nil when
+// dir is not in a git repository.
+func openRepository(dir string) (*repository, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open git repository")
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open git worktree")
+	}
+	root, err := filepath.EvalSymlinks(worktree.Filesystem.Root())
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return nil, err
+	}
+	prefix, err := filepath.Rel(root, abs)
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "." {
+		prefix = ""
+	}
+	return &repository{repo: repo, prefix: filepath.ToSlash(prefix)}, nil
+}
+
+// repoPath returns the repository path of a project path
+func (r *repository) repoPath(projectPath string) string {
+	return path.Join(r.prefix, projectPath)
+}
+
+// changes lists the project's uncommitted changes, including untracked files
+func (r *repository) changes() ([]string, error) {
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open git worktree")
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read git status")
+	}
+
+	var changed []string
+	for file, fileStatus := range status {
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
+			continue
+		}
+		if r.prefix == "" || strings.HasPrefix(file, r.prefix+"/") {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// baseFiles returns the files of the last generation that still match the
+// hashes in lock. They come from GeneratedRef or, for a project that was
+// never upgraded, from the last commit that changed the lockfile. Files
+// without a trustworthy base are left out.
+func (r *repository) baseFiles(lock *engine.Lock) (map[string]string, error) {
+	tree, err := r.baseTree()
+	if err != nil || tree == nil {
+		return map[string]string{}, err
+	}
+
+	files := make(map[string]string)
+	for _, locked := range lock.Files {
+		file, err := tree.File(locked.Path)
+		if err != nil {
+			continue
+		}
+		content, err := file.Contents()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s from git", locked.Path)
+		}
+		if engine.HashContent(content) == locked.SHA256 {
+			files[locked.Path] = content
+		}
+	}
+	return files, nil
+}
+
+// baseTree returns the project tree of the last generation, or nil if the
+// history doesn't have it
+func (r *repository) baseTree() (*object.Tree, error) {
+	if ref, err := r.repo.Reference(GeneratedRef, true); err == nil {
+		commit, err := r.repo.CommitObject(ref.Hash())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", GeneratedRef)
+		}
+		return commit.Tree()
+	}
+
+	head, err := r.repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read HEAD")
+	}
+
+	lockPath := r.repoPath(engine.LockPath)
+	commits, err := r.repo.Log(&git.LogOptions{From: head.Hash(), FileName: &lockPath})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read git history")
+	}
+	defer commits.Close()
+	commit, err := commits.Next()
+	if err != nil {
+		// The lockfile was never committed
+		return nil, nil
+	}
+
+	tree, err := commit.Tree()
+	if err != nil || r.prefix == "" {
+		return tree, err
+	}
+	return tree.Tree(r.prefix)
+}
+
+// recordGenerated commits files, keyed by project path, to GeneratedRef
+func (r *repository) recordGenerated(files map[string]string, message string) error {
+	root := newTreeNode()
+	for filePath, content := range files {
+		root.add(strings.Split(filePath, "/"), content)
+	}
+	treeHash, err := root.store(r.repo.Storer)
+	if err != nil {
+		return errors.Wrap(err, "failed to store generated files")
+	}
+
+	signature := object.Signature{Name: "TiLoKit", Email: "tilokit@example.com", When: time.Now()}
+	commit := &object.Commit{
+		Author:    signature,
+		Committer: signature,
+		Message:   message,
+		TreeHash:  treeHash,
+	}
+	if ref, err := r.repo.Reference(GeneratedRef, true); err == nil {
+		commit.ParentHashes = []plumbing.Hash{ref.Hash()}
+	}
+
+	obj := r.repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return errors.Wrap(err, "failed to encode generated commit")
+	}
+	commitHash, err := r.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return errors.Wrap(err, "failed to store generated commit")
+	}
+	return r.repo.Storer.SetReference(plumbing.NewHashReference(GeneratedRef, commitHash))
+}
+
+// treeNode is a directory of files being written as a git tree
+type treeNode struct {
+	files map[string]string
+	dirs  map[string]*treeNode
+}
+
+func newTreeNode() *treeNode {
+	return &treeNode{files: map[string]string{}, dirs: map[string]*treeNode{}}
+}
+
+func (n *treeNode) add(parts []string, content string) {
+	if len(parts) == 1 {
+		n.files[parts[0]] = content
+		return
+	}
+	dir, exists := n.dirs[parts[0]]
+	if !exists {
+		dir = newTreeNode()
+		n.dirs[parts[0]] = dir
+	}
+	dir.add(parts[1:], content)
+}
+
+// store writes the blobs and trees below n and returns the hash of n's tree
+func (n *treeNode) store(s storer.EncodedObjectStorer) (plumbing.Hash, error) {
+	var entries []object.TreeEntry
+	for name, content := range n.files {
+		blob := s.NewEncodedObject()
+		blob.SetType(plumbing.BlobObject)
+		writer, err := blob.Writer()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if err := writer.Close(); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		hash, err := s.SetEncodedObject(blob)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: hash})
+	}
+	for name, dir := range n.dirs {
+		hash, err := dir.store(s)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// Git orders tree entries by name, comparing directories as if their
+	// names ended in a slash
+	sortKey := func(entry object.TreeEntry) string {
+		if entry.Mode == filemode.Dir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return sortKey(entries[i]) < sortKey(entries[j])
+	})
+
+	tree := s.NewEncodedObject()
+	if err := (&object.Tree{Entries: entries}).Encode(tree); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return s.SetEncodedObject(tree)
+}
@@ -0,0 +1,102 @@
+package upgrade
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// Conflict markers written around the two sides of a conflicting change
+const (
+	markerOurs   = "<<<<<<<"
+	markerSep    = "======="
+	markerTheirs = ">>>>>>>"
+)
+
+// Merge3 applies the changes between base and theirs to ours, line by line.
+// Where both sides changed the same lines differently, both versions are
+// written between conflict markers labelled oursLabel and theirsLabel, and
+// conflicted is true.
+func Merge3(base, ours, theirs, oursLabel, theirsLabel string) (merged string, conflicted bool) {
+	o, a, b := utils.SplitLines(base), utils.SplitLines(ours), utils.SplitLines(theirs)
+	matchA, matchB := matchLines(o, a), matchLines(o, b)
+
+	var out strings.Builder
+	po, pa, pb := 0, 0, 0
+	for po < len(o) || pa < len(a) || pb < len(b) {
+		// Copy lines that are unchanged on both sides
+		stable := 0
+		for po+stable < len(o) && matchA[po+stable] == pa+stable && matchB[po+stable] == pb+stable {
+			stable++
+		}
+		if stable > 0 {
+			writeLines(&out, o[po:po+stable])
+			po, pa, pb = po+stable, pa+stable, pb+stable
+			continue
+		}
+
+		// The changed region ends at the next base line both sides kept
+		eo, ea, eb := len(o), len(a), len(b)
+		for j := po; j < len(o); j++ {
+			if matchA[j] >= 0 && matchB[j] >= 0 {
+				eo, ea, eb = j, matchA[j], matchB[j]
+				break
+			}
+		}
+
+		baseChunk, oursChunk, theirsChunk := o[po:eo], a[pa:ea], b[pb:eb]
+		switch {
+		case slices.Equal(oursChunk, baseChunk):
+			writeLines(&out, theirsChunk)
+		case slices.Equal(theirsChunk, baseChunk), slices.Equal(oursChunk, theirsChunk):
+			writeLines(&out, oursChunk)
+		default:
+			conflicted = true
+			out.WriteString(markerOurs + " " + oursLabel + "\n")
+			writeSide(&out, oursChunk)
+			out.WriteString(markerSep + "\n")
+			writeSide(&out, theirsChunk)
+			out.WriteString(markerTheirs + " " + theirsLabel + "\n")
+		}
+		po, pa, pb = eo, ea, eb
+	}
+
+	return out.String(), conflicted
+}
+
+// matchLines maps each line of base to the line of other it is kept as, or
+// -1 if other dropped it
+func matchLines(base, other []string) []int {
+	match := make([]int, len(base))
+	i, j := 0, 0
+	for _, line := range utils.DiffLines(base, other) {
+		switch line.Op {
+		case utils.DiffEqual:
+			match[i] = j
+			i++
+			j++
+		case utils.DiffDelete:
+			match[i] = -1
+			i++
+		case utils.DiffInsert:
+			j++
+		}
+	}
+	return match
+}
+
+func writeLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+	}
+}
+
+// writeSide writes one side of a conflict, terminating its last line so
+// the following marker starts on a line of its own
+func writeSide(out *strings.Builder, lines []string) {
+	writeLines(out, lines)
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		out.WriteString("\n")
+	}
+}
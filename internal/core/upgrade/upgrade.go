@@ -0,0 +1,283 @@
+// Package upgrade brings generated projects up to date with the templates
+// of the running TiLoKit by three-way merging the template changes into the
+// project.
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ti-lo/tilokit/internal/core/engine"
+	"github.com/ti-lo/tilokit/internal/utils"
+	"github.com/ti-lo/tilokit/pkg/constants"
+)
+
+// Status is what an upgrade did to a file
+type Status string
+
+const (
+	// StatusClean is a file only the template changed; the new version was
+	// written as is
+	StatusClean Status = "clean"
+	// StatusMerged is a file both the template and the project changed in
+	// different places
+	StatusMerged Status = "merged"
+	// StatusConflicted is a file both sides changed in the same place. It
+	// holds conflict markers, or was left alone when one side deleted it.
+	StatusConflicted Status = "conflicted"
+)
+
+// FileResult is the outcome for one file
+type FileResult struct {
+	Path   string
+	Status Status
+	// Note explains additions, removals and conflicts without markers
+	Note string
+}
+
+// Result summarizes an upgrade
+type Result struct {
+	Dir         string
+	FromVersion string
+	ToVersion   string
+	// Files lists the files the upgrade touched or couldn't, sorted by path
+	Files []FileResult
+}
+
+// Count returns the number of files with status
+func (r *Result) Count(status Status) int {
+	count := 0
+	for _, file := range r.Files {
+		if file.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Options controls an upgrade
+type Options struct {
+	// Force upgrades a project with uncommitted changes, or one that is not
+	// in a git repository
+	Force bool
+	// Templates returns the tree of a template at a revision recorded in
+	// the lockfile, and false when it doesn't have it. With it, the last
+	// generation can be rendered again when git doesn't hold it.
+	Templates func(name, revision string) (fs.FS, bool)
+}
+
+// Run upgrades the project in dir. The inputs recorded in its lockfile are
+// rendered again with eng, which must have the plugins registered, and the
+// difference to the last generation is merged into the working tree. The
+// last generation is rendered again from the lockfile's config and
+// template revisions; git history, when it has it, saves doing so. Files
+// neither yields get template changes only if they weren't edited.
+func Run(ctx context.Context, eng *engine.Engine, dir string, opts Options) (*Result, error) {
+	lock, err := engine.ReadLock(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s was not generated by TiLoKit or predates lockfiles", dir)
+	}
+
+	repo, err := openRepository(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWorktree(repo, dir, opts.Force); err != nil {
+		return nil, err
+	}
+
+	base := map[string]string{}
+	if repo != nil {
+		if base, err = repo.baseFiles(lock); err != nil {
+			return nil, err
+		}
+	}
+	if len(base) < len(lock.Files) {
+		if err := regenerateBase(ctx, eng, lock, opts.Templates, base); err != nil {
+			return nil, err
+		}
+	}
+
+	config := lock.Config
+	generated, err := eng.Render(ctx, &config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render the project with the current templates")
+	}
+
+	result := &Result{Dir: dir, FromVersion: lock.TiLoKitVersion, ToVersion: constants.Version}
+	for _, path := range upgradePaths(lock, generated) {
+		file, err := upgradeFile(dir, path, lock, base, generated)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			result.Files = append(result.Files, *file)
+		}
+	}
+
+	// Leave an up-to-date project untouched
+	if len(result.Files) == 0 {
+		return result, nil
+	}
+	if err := utils.WriteFile(projectFile(dir, engine.LockPath), generated[engine.LockPath]); err != nil {
+		return nil, errors.Wrapf(err, "failed to write %s", engine.LockPath)
+	}
+	if repo != nil {
+		message := fmt.Sprintf("TiLoKit %s generated files", constants.Version)
+		if err := repo.recordGenerated(generated, message); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// regenerateBase renders the last generation again with the template
+// revisions the lockfile recorded, adding the files that match their
+// recorded hashes to base. Templates that can't be found at their revision
+// render as they are now, so only the files they didn't change count.
+func regenerateBase(ctx context.Context, eng *engine.Engine, lock *engine.Lock, source func(name, revision string) (fs.FS, bool), base map[string]string) error {
+	templates := make(map[string]fs.FS)
+	if source != nil {
+		for _, locked := range lock.Templates {
+			if tree, ok := source(locked.Name, locked.Revision); ok {
+				templates[locked.Name] = tree
+			}
+		}
+	}
+
+	config := lock.Config
+	previous, err := eng.RenderTemplates(ctx, &config, templates)
+	if err != nil {
+		return errors.Wrap(err, "failed to render the last generation again")
+	}
+	for _, locked := range lock.Files {
+		if _, ok := base[locked.Path]; ok {
+			continue
+		}
+		if content, ok := previous[locked.Path]; ok && engine.HashContent(content) == locked.SHA256 {
+			base[locked.Path] = content
+		}
+	}
+	return nil
+}
+
+// checkWorktree refuses to upgrade over changes that can't be recovered
+func checkWorktree(repo *repository, dir string, force bool) error {
+	if force {
+		return nil
+	}
+	if repo == nil {
+		return errors.Errorf("%s is not in a git repository, so local changes can't be recovered; commit it first or use --force", dir)
+	}
+	changed, err := repo.changes()
+	if err != nil {
+		return err
+	}
+	if len(changed) > 0 {
+		return errors.Errorf("%s has uncommitted changes (%s); commit or stash them first, or use --force",
+			dir, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// upgradePaths returns every path the last or the new generation wrote,
+// except the lockfile
+func upgradePaths(lock *engine.Lock, generated map[string]string) []string {
+	seen := make(map[string]bool)
+	for _, file := range lock.Files {
+		seen[file.Path] = true
+	}
+	for path := range generated {
+		seen[path] = true
+	}
+	delete(seen, engine.LockPath)
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// upgradeFile merges the template change to one file into the project. It
+// returns nil when there is nothing to do.
+func upgradeFile(dir, path string, lock *engine.Lock, base, generated map[string]string) (*FileResult, error) {
+	fullPath := projectFile(dir, path)
+	ours, err := os.ReadFile(fullPath) // #nosec G304 - path comes from the lockfile or the templates
+	hasOurs := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+
+	baseContent, hasBase := base[path]
+	locked, wasGenerated := lock.File(path)
+	// Without history, a file that still matches its recorded hash is its
+	// own base
+	if !hasBase && hasOurs && wasGenerated && engine.HashContent(string(ours)) == locked.SHA256 {
+		baseContent, hasBase = string(ours), true
+	}
+	theirs, hasTheirs := generated[path]
+
+	switch {
+	case hasTheirs && wasGenerated && engine.HashContent(theirs) == locked.SHA256:
+		// The template didn't change
+		return nil, nil
+
+	case !hasTheirs:
+		if !hasBase || !hasOurs {
+			return nil, nil
+		}
+		if string(ours) != baseContent {
+			return &FileResult{Path: path, Status: StatusConflicted, Note: "removed from the template but changed locally, kept"}, nil
+		}
+		if err := os.Remove(fullPath); err != nil {
+			return nil, errors.Wrapf(err, "failed to remove %s", path)
+		}
+		return &FileResult{Path: path, Status: StatusClean, Note: "removed"}, nil
+
+	case !hasOurs:
+		if wasGenerated {
+			return &FileResult{Path: path, Status: StatusConflicted, Note: "deleted locally but changed in the template, not restored"}, nil
+		}
+		if err := utils.WriteFile(fullPath, theirs); err != nil {
+			return nil, errors.Wrapf(err, "failed to write %s", path)
+		}
+		return &FileResult{Path: path, Status: StatusClean, Note: "added"}, nil
+
+	case string(ours) == theirs:
+		// Already up to date
+		return nil, nil
+
+	case hasBase && string(ours) == baseContent:
+		if err := utils.WriteFile(fullPath, theirs); err != nil {
+			return nil, errors.Wrapf(err, "failed to write %s", path)
+		}
+		return &FileResult{Path: path, Status: StatusClean}, nil
+	}
+
+	merged, conflicted := Merge3(baseContent, string(ours), theirs, "local", "tilokit "+constants.Version)
+	if err := utils.WriteFile(fullPath, merged); err != nil {
+		return nil, errors.Wrapf(err, "failed to write %s", path)
+	}
+	file := &FileResult{Path: path, Status: StatusMerged}
+	if conflicted {
+		file.Status = StatusConflicted
+		if !hasBase {
+			file.Note = "no previous version to merge from"
+		}
+	}
+	return file, nil
+}
+
+// projectFile resolves a lockfile path inside the project
+func projectFile(dir, path string) string {
+	return filepath.Join(dir, filepath.FromSlash(path))
+}
@@ -0,0 +1,299 @@
+package upgrade
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/engine"
+)
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	tests := []struct {
+		name       string
+		ours       string
+		theirs     string
+		want       string
+		conflicted bool
+	}{
+		{name: "only theirs", ours: base, theirs: "a\nB\nc\nd\ne\n", want: "a\nB\nc\nd\ne\n"},
+		{name: "only ours", ours: "a\nb\nc\nd\nE\n", theirs: base, want: "a\nb\nc\nd\nE\n"},
+		{name: "separate changes", ours: "A\nb\nc\nd\ne\n", theirs: "a\nb\nc\nd\ne\nf\n", want: "A\nb\nc\nd\ne\nf\n"},
+		{name: "same change", ours: "a\nb\nC\nd\ne\n", theirs: "a\nb\nC\nd\ne\n", want: "a\nb\nC\nd\ne\n"},
+		{
+			name:       "conflict",
+			ours:       "a\nb\nours\nd\ne\n",
+			theirs:     "a\nb\ntheirs\nd\ne\n",
+			want:       "a\nb\n<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> new\nd\ne\n",
+			conflicted: true,
+		},
+		{
+			name:       "unterminated conflict",
+			ours:       "a\nb\nc\nd\nours",
+			theirs:     "a\nb\nc\nd\ntheirs",
+			want:       "a\nb\nc\nd\n<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> new\n",
+			conflicted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicted := Merge3(base, tt.ours, tt.theirs, "local", "new")
+			if merged != tt.want || conflicted != tt.conflicted {
+				t.Errorf("Expected %q (conflicted %v), got %q (conflicted %v)", tt.want, tt.conflicted, merged, conflicted)
+			}
+		})
+	}
+}
+
+// templatePlugin writes a fixed set of files, standing in for a template
+// at one version
+type templatePlugin struct {
+	files map[string]string
+}
+
+func (p *templatePlugin) Name() string                                         { return "template" }
+func (p *templatePlugin) Version() string                                      { return "1.0.0" }
+func (p *templatePlugin) Description() string                                  { return "Template plugin for testing" }
+func (p *templatePlugin) SupportedFrameworks() []string                        { return []string{"mock"} }
+func (p *templatePlugin) SupportedBuildTools() []string                        { return []string{"mock"} }
+func (p *templatePlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error  { return nil }
+func (p *templatePlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error { return nil }
+
+func (p *templatePlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	for path, content := range p.files {
+		if err := ctx.WriteFile(path, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newEngine(t *testing.T, files map[string]string) *engine.Engine {
+	t.Helper()
+	eng := engine.New()
+	if err := eng.RegisterPlugin(&templatePlugin{files: files}); err != nil {
+		t.Fatal(err)
+	}
+	return eng
+}
+
+func commitAll(t *testing.T, repo *git.Repository, message string) {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("."); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}
+	if _, err := worktree.Commit(message, &git.CommitOptions{Author: signature, All: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeProjectFile(t *testing.T, dir, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readProjectFile(t *testing.T, dir, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRunMergesTemplateChanges(t *testing.T) {
+	outputDir := t.TempDir()
+	dir := filepath.Join(outputDir, "app")
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: outputDir}
+
+	v1 := map[string]string{
+		"README.md":   "# app\n",
+		"main.go":     "package main\n\nfunc main() {\n}\n",
+		"config.yaml": "port: 8080\n",
+		"old.txt":     "obsolete\n",
+	}
+	if err := newEngine(t, v1).Execute(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitAll(t, repo, "Initial commit")
+
+	// Local edits to two generated files
+	writeProjectFile(t, dir, "main.go", "// Package main runs the app\npackage main\n\nfunc main() {\n}\n")
+	writeProjectFile(t, dir, "config.yaml", "port: 9090\n")
+
+	if _, err := Run(context.Background(), newEngine(t, v1), dir, Options{}); err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Fatalf("Expected a dirty worktree to be refused, got: %v", err)
+	}
+	commitAll(t, repo, "Customize")
+
+	v2 := map[string]string{
+		"README.md":   "# app\n\nGenerated by TiLoKit.\n",
+		"main.go":     "package main\n\nfunc main() {\n\trun()\n}\n",
+		"config.yaml": "port: 3000\n",
+		"new.txt":     "new\n",
+	}
+	result, err := Run(context.Background(), newEngine(t, v2), dir, Options{})
+	if err != nil {
+		t.Fatalf("Expected upgrade to succeed, got: %v", err)
+	}
+
+	statuses := make(map[string]Status)
+	for _, file := range result.Files {
+		statuses[file.Path] = file.Status
+	}
+	want := map[string]Status{
+		"README.md":   StatusClean,
+		"main.go":     StatusMerged,
+		"config.yaml": StatusConflicted,
+		"new.txt":     StatusClean,
+		"old.txt":     StatusClean,
+	}
+	for path, status := range want {
+		if statuses[path] != status {
+			t.Errorf("Expected %s to be %s, got %q", path, status, statuses[path])
+		}
+	}
+
+	if got := readProjectFile(t, dir, "main.go"); got != "// Package main runs the app\npackage main\n\nfunc main() {\n\trun()\n}\n" {
+		t.Errorf("Expected both changes in main.go, got %q", got)
+	}
+	if got := readProjectFile(t, dir, "config.yaml"); !strings.Contains(got, "<<<<<<< local\nport: 9090\n=======\nport: 3000\n") {
+		t.Errorf("Expected conflict markers in config.yaml, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected old.txt to be removed, got: %v", err)
+	}
+
+	lock, err := engine.ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lock.File("new.txt"); !ok {
+		t.Error("Expected the lockfile to describe the new generation")
+	}
+	if _, err := repo.Reference(GeneratedRef, true); err != nil {
+		t.Errorf("Expected %s to record the new generation, got: %v", GeneratedRef, err)
+	}
+}
+
+func TestRunWithoutRepositoryNeedsForce(t *testing.T) {
+	outputDir := t.TempDir()
+	dir := filepath.Join(outputDir, "app")
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: outputDir}
+	if err := newEngine(t, map[string]string{"a.txt": "one\n", "b.txt": "one\n"}).Execute(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	writeProjectFile(t, dir, "b.txt", "mine\n")
+
+	v2 := newEngine(t, map[string]string{"a.txt": "two\n", "b.txt": "two\n"})
+	if _, err := Run(context.Background(), v2, dir, Options{}); err == nil || !strings.Contains(err.Error(), "not in a git repository") {
+		t.Fatalf("Expected a project outside git to need --force, got: %v", err)
+	}
+
+	result, err := Run(context.Background(), v2, dir, Options{Force: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count(StatusClean) != 1 || result.Count(StatusConflicted) != 1 {
+		t.Errorf("Expected the untouched file to update and the edited one to conflict, got %+v", result.Files)
+	}
+	if got := readProjectFile(t, dir, "a.txt"); got != "two\n" {
+		t.Errorf("Expected a.txt to be updated, got %q", got)
+	}
+}
+
+// treePlugin writes the files of a template tree at a revision, or of the
+// tree the run pins for "demo"
+type treePlugin struct {
+	templatePlugin
+	revision string
+	tree     fstest.MapFS
+}
+
+func (p *treePlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	var tree fs.FS = p.tree
+	revision := p.revision
+	if pinned, ok := ctx.Templates["demo"]; ok {
+		tree = pinned
+		content, err := fs.ReadFile(pinned, "REVISION")
+		if err != nil {
+			return err
+		}
+		revision = string(content)
+	}
+	ctx.RecordTemplate("demo", revision)
+
+	return fs.WalkDir(tree, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path == "REVISION" {
+			return err
+		}
+		content, err := fs.ReadFile(tree, path)
+		if err != nil {
+			return err
+		}
+		return ctx.WriteFile(path, string(content))
+	})
+}
+
+func newTreeEngine(t *testing.T, revision string, files map[string]string) (*engine.Engine, fstest.MapFS) {
+	t.Helper()
+	tree := fstest.MapFS{"REVISION": {Data: []byte(revision)}}
+	for path, content := range files {
+		tree[path] = &fstest.MapFile{Data: []byte(content)}
+	}
+	eng := engine.New()
+	if err := eng.RegisterPlugin(&treePlugin{revision: revision, tree: tree}); err != nil {
+		t.Fatal(err)
+	}
+	return eng, tree
+}
+
+func TestRunRegeneratesBaseWithoutHistory(t *testing.T) {
+	outputDir := t.TempDir()
+	dir := filepath.Join(outputDir, "app")
+	config := &tilocontext.ProjectConfig{ProjectName: "app", Framework: "mock", BuildTool: "mock", OutputDir: outputDir}
+
+	v1, v1Tree := newTreeEngine(t, "r1", map[string]string{"main.go": "package main\n\nfunc main() {\n}\n"})
+	if err := v1.Execute(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	writeProjectFile(t, dir, "main.go", "// Package main runs the app\npackage main\n\nfunc main() {\n}\n")
+
+	v2, _ := newTreeEngine(t, "r2", map[string]string{"main.go": "package main\n\nfunc main() {\n\trun()\n}\n"})
+	templates := func(name, revision string) (fs.FS, bool) {
+		return v1Tree, name == "demo" && revision == "r1"
+	}
+	result, err := Run(context.Background(), v2, dir, Options{Force: true, Templates: templates})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Files) != 1 || result.Files[0].Status != StatusMerged {
+		t.Errorf("Expected main.go to merge against the regenerated base, got %+v", result.Files)
+	}
+	if got := readProjectFile(t, dir, "main.go"); got != "// Package main runs the app\npackage main\n\nfunc main() {\n\trun()\n}\n" {
+		t.Errorf("Expected both changes in main.go, got %q", got)
+	}
+}
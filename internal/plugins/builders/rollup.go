@@ -3,6 +3,7 @@ package builders
 
 import (
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 // RollupPlugin implements Rollup build tool support
@@ -20,7 +21,7 @@ func (p *RollupPlugin) Name() string {
 
 // Version returns the version of the Rollup plugin.
 func (p *RollupPlugin) Version() string {
-	return "1.0.0"
+	return "0.1.0"
 }
 
 // Description returns the description of the Rollup plugin.
@@ -38,6 +39,18 @@ func (p *RollupPlugin) SupportedBuildTools() []string {
 	return []string{"rollup"}
 }
 
+// Manifest declares the plugin API Rollup targets and that it accepts no options.
+func (p *RollupPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *RollupPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Rollup pre-generation logic
 	return nil
@@ -1,12 +1,13 @@
 package builders
 
 import (
-	"encoding/json"
-	"os"
-	"path/filepath"
+	"fmt"
 
 	"github.com/pkg/errors"
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/core/patch"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 	"github.com/ti-lo/tilokit/internal/utils"
 )
 
@@ -43,6 +44,30 @@ func (p *VitePlugin) SupportedBuildTools() []string {
 	return []string{"vite"}
 }
 
+// Manifest declares the plugin API Vite targets and the options it accepts.
+func (p *VitePlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+		Options: []registry.Option{
+			{Name: "port", Type: registry.OptionInt, Default: 3000,
+				Description: "Port of the dev server"},
+			{Name: "open", Type: registry.OptionBool, Default: true,
+				Description: "Open the browser when the dev server starts"},
+		},
+	}
+}
+
+// Dependencies orders Vite after the plugin that writes package.json,
+// since Vite adds its scripts to that file.
+func (p *VitePlugin) Dependencies() registry.Dependencies {
+	return registry.Dependencies{After: []string{"package.json"}}
+}
+
 // PreGenerate validates Vite compatibility before project generation.
 func (p *VitePlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// Validate Vite compatibility
@@ -62,8 +87,12 @@ func (p *VitePlugin) Generate(ctx *tilocontext.ExecutionContext) error {
 		return errors.Wrap(err, "failed to generate Vite config")
 	}
 
-	configPath := filepath.Join(ctx.ProjectPath, "vite.config.js")
-	if err := utils.WriteFile(configPath, viteConfig); err != nil {
+	// TypeScript projects type-check the config with tsconfig.node.json
+	configFile := "vite.config.js"
+	if utils.Contains(ctx.Config.Features, features.TypeScript) {
+		configFile = "vite.config.ts"
+	}
+	if err := ctx.WriteFile(configFile, viteConfig); err != nil {
 		return errors.Wrap(err, "failed to write Vite config")
 	}
 
@@ -86,6 +115,11 @@ func (p *VitePlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
 
 func (p *VitePlugin) generateViteConfig(ctx *tilocontext.ExecutionContext) (string, error) {
 	framework := ctx.Config.Framework
+	server := fmt.Sprintf(`  server: {
+    port: %d,
+    open: %t,
+    host: true
+  },`, ctx.IntOption("port"), ctx.BoolOption("open"))
 
 	var config string
 	switch framework {
@@ -96,11 +130,7 @@ import react from '@vitejs/plugin-react'
 // https://vitejs.dev/config/
 export default defineConfig({
   plugins: [react()],
-  server: {
-    port: 3000,
-    open: true,
-    host: true
-  },
+` + server + `
   build: {
     outDir: 'dist',
     sourcemap: true,
@@ -125,11 +155,7 @@ import vue from '@vitejs/plugin-vue'
 // https://vitejs.dev/config/
 export default defineConfig({
   plugins: [vue()],
-  server: {
-    port: 3000,
-    open: true,
-    host: true
-  },
+` + server + `
   build: {
     outDir: 'dist',
     sourcemap: true,
@@ -154,11 +180,7 @@ import { svelte } from '@sveltejs/vite-plugin-svelte'
 // https://vitejs.dev/config/
 export default defineConfig({
   plugins: [svelte()],
-  server: {
-    port: 3000,
-    open: true,
-    host: true
-  },
+` + server + `
   build: {
     outDir: 'dist',
     sourcemap: true
@@ -174,11 +196,7 @@ export default defineConfig({
 
 // https://vitejs.dev/config/
 export default defineConfig({
-  server: {
-    port: 3000,
-    open: true,
-    host: true
-  },
+` + server + `
   build: {
     outDir: 'dist',
     sourcemap: true
@@ -195,41 +213,13 @@ export default defineConfig({
 }
 
 func (p *VitePlugin) updatePackageJsonScripts(ctx *tilocontext.ExecutionContext) error {
-	packageJsonPath := filepath.Join(ctx.ProjectPath, "package.json")
-
-	// Read existing package.json if it exists
-	var packageJson map[string]interface{}
-	if utils.FileExists(packageJsonPath) {
-		dataStr, err := utils.ReadFile(packageJsonPath)
-		if err != nil {
-			return err
-		}
-		if err := json.Unmarshal([]byte(dataStr), &packageJson); err != nil {
-			return err
-		}
-	} else {
-		packageJson = make(map[string]interface{})
-	}
-
-	// Ensure scripts section exists
-	if packageJson["scripts"] == nil {
-		packageJson["scripts"] = make(map[string]interface{})
-	}
-
-	scripts := packageJson["scripts"].(map[string]interface{})
-
-	// Add Vite scripts
-	scripts["dev"] = "vite"
-	scripts["build"] = "vite build"
-	scripts["preview"] = "vite preview"
-	scripts["lint"] = "eslint . --ext js,jsx,ts,tsx,vue,svelte --report-unused-disable-directives --max-warnings 0"
-
-	// Write updated package.json
-	data, err := json.MarshalIndent(packageJson, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	// Use more restrictive permissions (0600 instead of 0644)
-	return os.WriteFile(packageJsonPath, data, 0600)
+	// Add Vite scripts, keeping those the framework already set, such as a
+	// build that type-checks first. Linting is added by the eslint feature.
+	return ctx.PatchFile("package.json", patch.Object{
+		{Key: "scripts", Value: patch.Object{
+			{Key: "dev", Value: "vite"},
+			{Key: "build", Value: "vite build"},
+			{Key: "preview", Value: "vite preview"},
+		}},
+	}, patch.Keep)
 }
@@ -2,7 +2,7 @@ package builders
 
 import (
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 // WebpackPlugin implements Webpack build tool support
@@ -20,7 +20,7 @@ func (p *WebpackPlugin) Name() string {
 
 // Version returns the version of the Webpack plugin.
 func (p *WebpackPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 // Description returns the description of the Webpack plugin.
@@ -38,6 +38,18 @@ func (p *WebpackPlugin) SupportedBuildTools() []string {
 	return []string{"webpack"}
 }
 
+// Manifest declares the plugin API Webpack targets and that it accepts no options.
+func (p *WebpackPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *WebpackPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Webpack pre-generation logic
 	return nil
@@ -0,0 +1,98 @@
+package external
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ti-lo/tilokit/internal/core/registry"
+)
+
+// Command is how an external plugin is started
+type Command struct {
+	Path string
+	Args []string
+}
+
+// DefaultDir returns the directory plugins are discovered in,
+// ~/.tilokit/plugins
+func DefaultDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".tilokit", "plugins")
+}
+
+// FindCommands returns the executables and WebAssembly modules in dir in
+// name order, skipping hidden files. A missing directory holds no plugins.
+func FindCommands(dir string) ([]Command, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read plugin directory")
+	}
+
+	var commands []Command
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		// Follow symlinks, e.g. to a plugin installed elsewhere
+		info, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil || !info.Mode().IsRegular() || !isExecutable(entry.Name(), info.Mode()) {
+			continue
+		}
+		commands = append(commands, Command{Path: filepath.Join(dir, entry.Name())})
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Path < commands[j].Path
+	})
+	return commands, nil
+}
+
+func isExecutable(name string, mode os.FileMode) bool {
+	if IsWASM(name) {
+		return true
+	}
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".exe", ".bat", ".cmd":
+			return true
+		}
+		return false
+	}
+	return mode&0111 != 0
+}
+
+// StartAll starts every command. One that fails, or that this version of
+// TiLoKit can't run, doesn't stop the others; it is left out and its error
+// returned along with the started plugins.
+func StartAll(commands []Command, opts Options) ([]*Plugin, []error) {
+	var plugins []*Plugin
+	var errs []error
+	names := make(map[string]string)
+
+	for _, command := range commands {
+		plugin, err := Start(command.Path, command.Args, opts)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := registry.CheckPlugin(plugin); err != nil {
+			plugin.Stop()
+			errs = append(errs, err)
+			continue
+		}
+		if other, exists := names[plugin.Name()]; exists {
+			plugin.Stop()
+			errs = append(errs, errors.Errorf("plugin %s from %s is already provided by %s", plugin.Name(), command.Path, other))
+			continue
+		}
+		names[plugin.Name()] = command.Path
+		plugins = append(plugins, plugin)
+	}
+	return plugins, errs
+}
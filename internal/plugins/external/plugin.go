@@ -0,0 +1,383 @@
+// Package external runs plugins as child processes, or as sandboxed
+// WebAssembly modules, that speak JSON-RPC 2.0 over stdio, so teams can
+// write plugins in any language. The protocol is described in
+// docs/PLUGINS.md.
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/pkg/constants"
+)
+
+const (
+	// DefaultHandshakeTimeout bounds starting a plugin and its handshake
+	DefaultHandshakeTimeout = 10 * time.Second
+	// DefaultCallTimeout bounds each hook of a plugin
+	DefaultCallTimeout = time.Minute
+)
+
+// Options configures how external plugins are run
+type Options struct {
+	// HandshakeTimeout is DefaultHandshakeTimeout if zero
+	HandshakeTimeout time.Duration
+	// CallTimeout is DefaultCallTimeout if zero
+	CallTimeout time.Duration
+	// Grants returns the capabilities granted to the named plugin, such as
+	// GrantCommands. Plugins get none when nil.
+	Grants func(name string) []string
+	// PluginOptions returns the config options of the named plugin, which
+	// are sent with each hook
+	PluginOptions func(name string) map[string]interface{}
+	// CacheDir keeps WebAssembly plugins compiled between runs; they are
+	// compiled every time when empty
+	CacheDir string
+}
+
+// Plugin is a plugin running in a child process, or in a WebAssembly
+// sandbox when its command is a .wasm module. Its hooks run one at a time.
+// A plugin that crashes, hangs past its timeout or breaks the protocol is
+// stopped, fails the hook and is started again for the next one.
+type Plugin struct {
+	command string
+	args    []string
+	opts    Options
+	info    Info
+	grants  []string
+	options map[string]interface{}
+	module  *wasmModule
+
+	mutex   sync.Mutex
+	proc    *process
+	stopped bool
+}
+
+// Start starts a plugin and performs the handshake. The process keeps
+// running until Stop.
+func Start(command string, args []string, opts Options) (*Plugin, error) {
+	if opts.HandshakeTimeout <= 0 {
+		opts.HandshakeTimeout = DefaultHandshakeTimeout
+	}
+	if opts.CallTimeout <= 0 {
+		opts.CallTimeout = DefaultCallTimeout
+	}
+
+	p := &Plugin{command: command, args: args, opts: opts}
+	if IsWASM(command) {
+		module, err := compileWASM(command, opts.CacheDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load plugin %s", command)
+		}
+		p.module = module
+	}
+
+	proc, info, err := p.start()
+	if err != nil {
+		if p.module != nil {
+			p.module.close()
+		}
+		return nil, err
+	}
+	p.proc = proc
+	p.info = *info
+	if opts.Grants != nil {
+		p.grants = opts.Grants(info.Name)
+	}
+	var options map[string]interface{}
+	if opts.PluginOptions != nil {
+		options = opts.PluginOptions(info.Name)
+	}
+	p.options = p.Manifest().OptionValues(options)
+	return p, nil
+}
+
+func (p *Plugin) start() (*process, *Info, error) {
+	var proc *process
+	var err error
+	if p.module != nil {
+		proc, err = p.module.start(filepath.Base(p.command), p.args)
+	} else {
+		proc, err = startProcess(p.command, p.args)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to start plugin %s", p.command)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.HandshakeTimeout)
+	defer cancel()
+
+	var info Info
+	params := HandshakeParams{ProtocolVersion: ProtocolVersion, HostVersion: constants.Version}
+	err = proc.call(ctx, MethodHandshake, params, &info, nil)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = errors.Errorf("no answer within %s", p.opts.HandshakeTimeout)
+	}
+	if err == nil {
+		err = checkInfo(&info)
+	}
+	if err != nil {
+		proc.kill()
+		<-proc.exited
+		return nil, nil, errors.Wrapf(err, "handshake with plugin %s failed", p.command)
+	}
+	return proc, &info, nil
+}
+
+func checkInfo(info *Info) error {
+	if info.ProtocolVersion != ProtocolVersion {
+		return errors.Errorf("plugin speaks protocol version %d, TiLoKit %s speaks version %d",
+			info.ProtocolVersion, constants.Version, ProtocolVersion)
+	}
+	if info.Name == "" {
+		return errors.New("plugin has no name")
+	}
+	if len(info.Frameworks) == 0 && len(info.BuildTools) == 0 {
+		return errors.New("plugin supports no frameworks or build tools")
+	}
+	return nil
+}
+
+// Stop asks the plugin to exit and kills it if it doesn't in time. Its
+// hooks fail afterwards.
+func (p *Plugin) Stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.proc != nil {
+		p.proc.stop()
+		p.proc = nil
+	}
+	if p.module != nil && !p.stopped {
+		p.module.close()
+	}
+	p.stopped = true
+}
+
+// Command returns the plugin's executable or WebAssembly module
+func (p *Plugin) Command() string {
+	return p.command
+}
+
+// Grants returns the capabilities granted to the plugin
+func (p *Plugin) Grants() []string {
+	return p.grants
+}
+
+func (p *Plugin) granted(grant string) bool {
+	for _, g := range p.grants {
+		if g == grant {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Plugin) Name() string                  { return p.info.Name }
+func (p *Plugin) Version() string               { return p.info.Version }
+func (p *Plugin) Description() string           { return p.info.Description }
+func (p *Plugin) SupportedFrameworks() []string { return p.info.Frameworks }
+func (p *Plugin) SupportedBuildTools() []string { return p.info.BuildTools }
+
+// Manifest describes the plugin as its handshake did. Plugins that don't
+// say which API they target are taken to target version 1.
+func (p *Plugin) Manifest() registry.Manifest {
+	apiVersion := p.info.APIVersion
+	if apiVersion == 0 {
+		apiVersion = 1
+	}
+	return registry.Manifest{
+		Name:        p.info.Name,
+		Version:     p.info.Version,
+		Description: p.info.Description,
+		APIVersion:  apiVersion,
+		Frameworks:  p.info.Frameworks,
+		BuildTools:  p.info.BuildTools,
+		Options:     p.info.Options,
+	}
+}
+
+func (p *Plugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
+	return p.hook(ctx, MethodPreGenerate)
+}
+
+func (p *Plugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	return p.hook(ctx, MethodGenerate)
+}
+
+func (p *Plugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
+	return p.hook(ctx, MethodPostGenerate)
+}
+
+// hook calls a hook of the plugin, serving its file writes through ctx
+func (p *Plugin) hook(ctx *tilocontext.ExecutionContext, method string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.stopped {
+		return errors.Errorf("plugin %s was stopped", p.info.Name)
+	}
+	if p.proc == nil || p.proc.hasExited() {
+		proc, info, err := p.start()
+		if err != nil {
+			return err
+		}
+		if info.Name != p.info.Name {
+			proc.stop()
+			return errors.Errorf("plugin %s restarted as %s", p.info.Name, info.Name)
+		}
+		p.proc = proc
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, p.opts.CallTimeout)
+	defer cancel()
+
+	params := HookParams{
+		Config:        ctx.Config,
+		Variables:     ctx.Variables,
+		Virtual:       ctx.Virtual,
+		Augment:       ctx.Augment,
+		AddedFeatures: ctx.AddedFeatures,
+		Options:       p.options,
+	}
+	err := p.proc.call(callCtx, method, params, nil, (&host{ctx: ctx, plugin: p}).handle)
+
+	// The plugin reported a failure and is still usable
+	var remote *rpcError
+	if err == nil || errors.As(err, &remote) {
+		return err
+	}
+
+	// Otherwise it crashed, hung or broke the protocol
+	p.proc.kill()
+	p.proc = nil
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errors.Errorf("plugin did not finish %s within %s", method, p.opts.CallTimeout)
+	}
+	return err
+}
+
+// host serves the calls a plugin makes while one of its hooks runs
+type host struct {
+	ctx    *tilocontext.ExecutionContext
+	plugin *Plugin
+}
+
+func (h *host) handle(method string, raw json.RawMessage) (interface{}, error) {
+	switch method {
+	case MethodWriteFile:
+		var params writeFileParams
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		file, err := projectPath(params.Path)
+		if err != nil {
+			return nil, err
+		}
+		return nil, h.ctx.WriteFile(file, params.Content)
+
+	case MethodReadFile, MethodFileExists, MethodMakeExecutable:
+		var params pathParams
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		file, err := projectPath(params.Path)
+		if err != nil {
+			return nil, err
+		}
+		switch method {
+		case MethodReadFile:
+			content, err := h.ctx.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]string{"content": content}, nil
+		case MethodFileExists:
+			return map[string]bool{"exists": h.ctx.FileExists(file)}, nil
+		default:
+			return nil, h.ctx.MakeExecutable(file)
+		}
+
+	case MethodSetVariable:
+		var params setVariableParams
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		if params.Key == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "variable key is required"}
+		}
+		h.ctx.SetVariable(params.Key, params.Value)
+		return nil, nil
+
+	case MethodSkipStep:
+		var params skipStepParams
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		h.ctx.SkipStep(params.Step, params.Reason)
+		return nil, nil
+
+	case MethodRunCommand:
+		var params runCommandParams
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		return h.runCommand(params)
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "unknown method " + method}
+}
+
+// runCommand runs a command in the project directory for a plugin granted
+// GrantCommands. A command that fails is reported through its exit code;
+// one that can't be started is an error.
+func (h *host) runCommand(params runCommandParams) (interface{}, error) {
+	if !h.plugin.granted(GrantCommands) {
+		return nil, &rpcError{Code: codeNotGranted, Message: fmt.Sprintf(
+			"plugin %s may not run commands; add %q to plugins.%s.grants in the config to allow it",
+			h.plugin.Name(), GrantCommands, h.plugin.Name())}
+	}
+	if params.Command == "" {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "command is required"}
+	}
+
+	line := strings.Join(append([]string{params.Command}, params.Args...), " ")
+	if h.ctx.Virtual {
+		h.ctx.SkipStep(line, "output is not written to disk")
+		return runCommandResult{Skipped: true}, nil
+	}
+
+	cmd := exec.CommandContext(h.ctx, params.Command, params.Args...)
+	cmd.Dir = h.ctx.ProjectPath
+	output, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return runCommandResult{ExitCode: exit.ExitCode(), Output: string(output)}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run %s", line)
+	}
+	return runCommandResult{Output: string(output)}, nil
+}
+
+// projectPath converts a path sent by a plugin to one relative to the
+// project root, refusing anything that would leave it
+func projectPath(p string) (string, error) {
+	clean := path.Clean(filepath.ToSlash(p))
+	if p == "" || path.IsAbs(clean) || filepath.IsAbs(p) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", &rpcError{Code: codeInvalidParams, Message: "path " + p + " is outside the project"}
+	}
+	return filepath.FromSlash(clean), nil
+}
@@ -0,0 +1,280 @@
+package external
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// testPluginEnv makes the test binary act as a plugin. Its value selects
+// how the plugin behaves in generate.
+const testPluginEnv = "TILOKIT_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(testPluginEnv); mode != "" {
+		runTestPlugin(mode)
+		os.Exit(0)
+	}
+	code := m.Run()
+	if wasmBuildDir != "" {
+		os.RemoveAll(wasmBuildDir)
+	}
+	os.Exit(code)
+}
+
+// runTestPlugin speaks the plugin side of the protocol
+func runTestPlugin(mode string) {
+	in := bufio.NewScanner(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+	nextID := 0
+
+	send := func(msg *message) {
+		msg.JSONRPC = "2.0"
+		_ = out.Encode(msg)
+	}
+	reply := func(id *json.RawMessage, result interface{}, err *rpcError) {
+		raw, _ := json.Marshal(result)
+		send(&message{ID: id, Result: raw, Error: err})
+	}
+	call := func(method string, params interface{}) *message {
+		nextID++
+		id := json.RawMessage(fmt.Sprintf(`"plugin-%d"`, nextID))
+		raw, _ := json.Marshal(params)
+		send(&message{ID: &id, Method: method, Params: raw})
+		in.Scan()
+		answer := &message{}
+		_ = json.Unmarshal(in.Bytes(), answer)
+		return answer
+	}
+
+	for in.Scan() {
+		msg := &message{}
+		if err := json.Unmarshal(in.Bytes(), msg); err != nil {
+			os.Exit(1)
+		}
+
+		switch msg.Method {
+		case MethodHandshake:
+			info := Info{
+				ProtocolVersion: ProtocolVersion,
+				Name:            "test-plugin",
+				Version:         "1.0.0",
+				Frameworks:      []string{"test"},
+				Options:         []registry.Option{{Name: "greeting", Type: registry.OptionString, Default: "hello"}},
+			}
+			switch mode {
+			case "old":
+				info.ProtocolVersion = 0
+			case "future":
+				info.APIVersion = registry.APIVersion + 1
+			}
+			reply(msg.ID, info, nil)
+		case MethodShutdown:
+			return
+		case MethodGenerate:
+			switch mode {
+			case "crash":
+				fmt.Fprintln(os.Stderr, "panic: something broke")
+				os.Exit(2)
+			case "hang":
+				time.Sleep(time.Hour)
+			case "noise":
+				fmt.Println("generating...")
+			case "escape":
+				answer := call(MethodWriteFile, writeFileParams{Path: "../escaped", Content: "x"})
+				reply(msg.ID, nil, answer.Error)
+			default:
+				var params HookParams
+				_ = json.Unmarshal(msg.Params, &params)
+				answer := call(MethodWriteFile, writeFileParams{Path: "README.md", Content: "# " + params.Config.ProjectName + "\n"})
+				if answer.Error == nil {
+					answer = call(MethodSetVariable, setVariableParams{Key: "greeting", Value: params.Options["greeting"]})
+				}
+				reply(msg.ID, nil, answer.Error)
+			}
+		default:
+			reply(msg.ID, nil, nil)
+		}
+	}
+}
+
+func startTestPlugin(t *testing.T, mode string, opts Options) *Plugin {
+	t.Helper()
+	t.Setenv(testPluginEnv, mode)
+	plugin, err := Start(os.Args[0], nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(plugin.Stop)
+	return plugin
+}
+
+func newTestContext() *tilocontext.ExecutionContext {
+	ctx := tilocontext.NewExecutionContext(&tilocontext.ProjectConfig{
+		ProjectName: "demo",
+		Framework:   "test",
+		OutputDir:   "/out",
+	})
+	ctx.FS = utils.NewMemoryFS()
+	return ctx
+}
+
+func TestHandshakeProvidesMetadata(t *testing.T) {
+	plugin := startTestPlugin(t, "ok", Options{})
+
+	if plugin.Name() != "test-plugin" || plugin.Version() != "1.0.0" {
+		t.Errorf("Unexpected plugin %s %s", plugin.Name(), plugin.Version())
+	}
+	if frameworks := plugin.SupportedFrameworks(); len(frameworks) != 1 || frameworks[0] != "test" {
+		t.Errorf("Unexpected frameworks %v", frameworks)
+	}
+}
+
+func TestHandshakeRejectsOtherProtocolVersions(t *testing.T) {
+	t.Setenv(testPluginEnv, "old")
+	_, err := Start(os.Args[0], nil, Options{})
+	if err == nil || !strings.Contains(err.Error(), "protocol version 0") {
+		t.Errorf("Expected a protocol version error, got %v", err)
+	}
+}
+
+func TestManifestComesFromTheHandshake(t *testing.T) {
+	plugin := startTestPlugin(t, "ok", Options{})
+
+	m := registry.ManifestOf(plugin)
+	if m.APIVersion != 1 || len(m.Options) != 1 || m.Options[0].Name != "greeting" {
+		t.Errorf("Unexpected manifest %+v", m)
+	}
+
+	t.Setenv(testPluginEnv, "future")
+	plugins, errs := StartAll([]Command{{Path: os.Args[0]}}, Options{})
+	if len(plugins) != 0 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "upgrade TiLoKit") {
+		t.Errorf("Expected a plugin for a newer API to be left out, got %v, %v", plugins, errs)
+	}
+}
+
+func TestHooksReceiveConfiguredOptions(t *testing.T) {
+	plugin := startTestPlugin(t, "ok", Options{
+		PluginOptions: func(name string) map[string]interface{} {
+			return map[string]interface{}{"greeting": "hi"}
+		},
+	})
+	ctx := newTestContext()
+
+	if err := plugin.Generate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if greeting, _ := ctx.GetVariable("greeting"); greeting != "hi" {
+		t.Errorf("Expected the configured option, got %v", greeting)
+	}
+}
+
+func TestGenerateWritesThroughTheHost(t *testing.T) {
+	plugin := startTestPlugin(t, "ok", Options{})
+	ctx := newTestContext()
+	ctx.SetCurrentPlugin(plugin.Name())
+
+	if err := plugin.Generate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := utils.ReadFileFS(ctx.FS, filepath.Join("/out", "demo", "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "# demo\n" {
+		t.Errorf("Unexpected README %q", content)
+	}
+	if files := ctx.WrittenFiles(); len(files) != 1 || files[0].Plugin != "test-plugin" {
+		t.Errorf("Expected the README attributed to the plugin, got %v", files)
+	}
+	if greeting, _ := ctx.GetVariable("greeting"); greeting != "hello" {
+		t.Errorf("Expected the plugin to set a variable, got %v", greeting)
+	}
+}
+
+func TestWritesOutsideTheProjectAreRefused(t *testing.T) {
+	plugin := startTestPlugin(t, "escape", Options{})
+	ctx := newTestContext()
+
+	err := plugin.Generate(ctx)
+	if err == nil || !strings.Contains(err.Error(), "outside the project") {
+		t.Errorf("Expected the write to be refused, got %v", err)
+	}
+	if utils.FileExistsFS(ctx.FS, filepath.Join("/out", "escaped")) {
+		t.Error("Expected nothing written outside the project")
+	}
+}
+
+func TestCrashedPluginIsRestarted(t *testing.T) {
+	plugin := startTestPlugin(t, "crash", Options{})
+
+	err := plugin.Generate(newTestContext())
+	if err == nil || !strings.Contains(err.Error(), "something broke") {
+		t.Fatalf("Expected the crash with the plugin's stderr, got %v", err)
+	}
+
+	t.Setenv(testPluginEnv, "ok")
+	if err := plugin.Generate(newTestContext()); err != nil {
+		t.Errorf("Expected the restarted plugin to work, got %v", err)
+	}
+}
+
+func TestHungPluginTimesOut(t *testing.T) {
+	plugin := startTestPlugin(t, "hang", Options{CallTimeout: 200 * time.Millisecond})
+
+	start := time.Now()
+	err := plugin.Generate(newTestContext())
+	if err == nil || !strings.Contains(err.Error(), "did not finish generate") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Timeout took %s", elapsed)
+	}
+}
+
+func TestProtocolViolationStopsPlugin(t *testing.T) {
+	plugin := startTestPlugin(t, "noise", Options{})
+
+	err := plugin.Generate(newTestContext())
+	if err == nil || !strings.Contains(err.Error(), "other than a JSON-RPC message") {
+		t.Errorf("Expected a protocol error, got %v", err)
+	}
+}
+
+func TestFindCommands(t *testing.T) {
+	dir := t.TempDir()
+	for name, mode := range map[string]os.FileMode{
+		"tilokit-acme": 0755,
+		"README.md":    0644,
+		".hidden":      0755,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	commands, err := FindCommands(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 1 || commands[0].Path != filepath.Join(dir, "tilokit-acme") {
+		t.Errorf("Expected only the executable, got %v", commands)
+	}
+
+	if commands, err := FindCommands(filepath.Join(dir, "missing")); err != nil || len(commands) != 0 {
+		t.Errorf("Expected no plugins in a missing directory, got %v, %v", commands, err)
+	}
+}
@@ -0,0 +1,306 @@
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// stopTimeout is how long a plugin may take to exit once asked to
+	stopTimeout = 2 * time.Second
+	// stderrTail is how much of a plugin's stderr is kept for error messages
+	stderrTail = 2 << 10
+)
+
+// handler serves a call made by the plugin
+type handler func(method string, params json.RawMessage) (interface{}, error)
+
+// process is a running plugin, native or WebAssembly, and its end of the
+// stdio connection
+type process struct {
+	stdin  *os.File
+	stderr *tailBuffer
+	nextID int64
+
+	sendMutex sync.Mutex
+	messages  chan *message
+	// done is closed once no more messages will arrive, with readErr
+	// saying why
+	done    chan struct{}
+	readErr error
+	// exited is closed once the process has exited, with waitErr set
+	exited  chan struct{}
+	waitErr error
+	// stopped is closed when the process is killed
+	stopped   chan struct{}
+	killOnce  sync.Once
+	terminate func()
+}
+
+// newProcess returns a process talking over stdin and stdout; the caller
+// starts it and sets terminate
+func newProcess(stdin *os.File) *process {
+	return &process{
+		stdin:    stdin,
+		stderr:   &tailBuffer{},
+		messages: make(chan *message),
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+func startProcess(command string, args []string) (*process, error) {
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		stdinR.Close()
+		stdinW.Close()
+		return nil, err
+	}
+
+	cmd := exec.Command(command, args...)
+	cmd.Env = append(os.Environ(), "TILOKIT_PLUGIN_PROTOCOL="+strconv.Itoa(ProtocolVersion))
+	cmd.Stdin = stdinR
+	cmd.Stdout = stdoutW
+	proc := newProcess(stdinW)
+	cmd.Stderr = proc.stderr
+	// Don't wait for stderr to close if the plugin left children behind
+	cmd.WaitDelay = stopTimeout
+
+	err = cmd.Start()
+	stdinR.Close()
+	stdoutW.Close()
+	if err != nil {
+		stdinW.Close()
+		stdoutR.Close()
+		return nil, err
+	}
+
+	proc.terminate = func() { _ = cmd.Process.Kill() }
+	go proc.read(stdoutR)
+	go func() {
+		proc.waitErr = cmd.Wait()
+		stdinW.Close()
+		close(proc.exited)
+	}()
+	return proc, nil
+}
+
+// read passes the plugin's messages to call until stdout is closed. A line
+// that isn't a JSON-RPC message breaks the protocol and stops the plugin.
+func (p *process) read(stdout io.ReadCloser) {
+	defer close(p.done)
+	defer stdout.Close()
+
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			msg := &message{}
+			if jsonErr := json.Unmarshal(line, msg); jsonErr != nil || msg.JSONRPC != "2.0" {
+				p.readErr = errors.Errorf("plugin wrote something other than a JSON-RPC message to stdout: %.80q", line)
+				p.kill()
+				return
+			}
+			select {
+			case p.messages <- msg:
+			case <-p.stopped:
+				return
+			}
+		}
+		if err == io.EOF {
+			p.readErr = errors.New("plugin exited")
+			return
+		}
+		if err != nil {
+			p.readErr = errors.Wrap(err, "failed to read from plugin")
+			return
+		}
+	}
+}
+
+func (p *process) send(msg *message) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	p.sendMutex.Lock()
+	defer p.sendMutex.Unlock()
+	_, err = p.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// call sends a request and serves the plugin's own calls with handle until
+// the answer arrives. The plugin is killed when ctx ends first.
+func (p *process) call(ctx context.Context, method string, params, result interface{}, handle handler) error {
+	stop := context.AfterFunc(ctx, p.kill)
+	defer stop()
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode %s params", method)
+	}
+	p.nextID++
+	id := json.RawMessage(strconv.FormatInt(p.nextID, 10))
+	if err := p.send(&message{ID: &id, Method: method, Params: raw}); err != nil {
+		return p.failure(ctx, errors.New("plugin stopped reading requests"))
+	}
+
+	for {
+		select {
+		case msg := <-p.messages:
+			if msg.Method != "" {
+				if err := p.serve(msg, handle); err != nil {
+					return p.failure(ctx, err)
+				}
+				continue
+			}
+			// Answers to calls that timed out before are dropped
+			if msg.ID == nil || string(*msg.ID) != string(id) {
+				continue
+			}
+			if msg.Error != nil {
+				return msg.Error
+			}
+			if result != nil {
+				if err := json.Unmarshal(msg.Result, result); err != nil {
+					return p.failure(ctx, errors.Wrapf(err, "invalid %s result", method))
+				}
+			}
+			return nil
+		case <-p.done:
+			return p.failure(ctx, p.readErr)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// serve answers a call from the plugin. Notifications get no answer.
+func (p *process) serve(msg *message, handle handler) error {
+	var result interface{}
+	var err error
+	if handle == nil {
+		err = &rpcError{Code: codeMethodNotFound, Message: "the host accepts calls only while a hook runs"}
+	} else {
+		result, err = handle(msg.Method, msg.Params)
+	}
+	if msg.ID == nil {
+		return nil
+	}
+
+	reply := &message{ID: msg.ID}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: codeHostError, Message: err.Error()}
+		}
+		reply.Error = rpcErr
+	} else if reply.Result, err = json.Marshal(result); err != nil {
+		return err
+	}
+	return p.send(reply)
+}
+
+// failure describes why the connection to the plugin broke, with its exit
+// status and the end of its stderr
+func (p *process) failure(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	select {
+	case <-p.exited:
+	case <-time.After(stopTimeout):
+		p.kill()
+		<-p.exited
+	}
+
+	msg := err.Error()
+	if p.waitErr != nil {
+		msg = fmt.Sprintf("%s (%v)", msg, p.waitErr)
+	}
+	if tail := p.stderr.String(); tail != "" {
+		msg += ": " + tail
+	}
+	return errors.New(msg)
+}
+
+func (p *process) hasExited() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop asks the plugin to shut down and closes its stdin, then kills it if
+// it hasn't exited in time
+func (p *process) stop() {
+	_ = p.send(&message{Method: MethodShutdown})
+	p.stdin.Close()
+
+	select {
+	case <-p.exited:
+	case <-time.After(stopTimeout):
+		p.kill()
+		<-p.exited
+	}
+}
+
+func (p *process) kill() {
+	p.killOnce.Do(func() {
+		close(p.stopped)
+		p.terminate()
+	})
+}
+
+// tailBuffer keeps the last stderrTail bytes written to it
+type tailBuffer struct {
+	mutex sync.Mutex
+	data  []byte
+}
+
+func (b *tailBuffer) Write(data []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.data = append(b.data, data...)
+	if len(b.data) > stderrTail {
+		b.data = b.data[len(b.data)-stderrTail:]
+	}
+	return len(data), nil
+}
+
+// String returns the line that most likely explains a crash: the last
+// panic or fatal error, as Go prints a stack trace after it, or else the
+// last line written
+func (b *tailBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	lines := strings.Split(strings.TrimSpace(string(b.data)), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], "panic: ") || strings.HasPrefix(lines[i], "fatal error: ") {
+			return strings.TrimSpace(lines[i])
+		}
+	}
+	return strings.TrimSpace(lines[len(lines)-1])
+}
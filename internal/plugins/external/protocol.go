@@ -0,0 +1,137 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+)
+
+// ProtocolVersion is the version of the stdio protocol spoken with plugins.
+// It changes only when existing plugins would break.
+const ProtocolVersion = 1
+
+// Methods the host calls on a plugin
+const (
+	MethodHandshake    = "handshake"
+	MethodPreGenerate  = "pre_generate"
+	MethodGenerate     = "generate"
+	MethodPostGenerate = "post_generate"
+	MethodShutdown     = "shutdown"
+)
+
+// Methods a plugin calls on the host while one of its hooks runs
+const (
+	MethodWriteFile      = "write_file"
+	MethodReadFile       = "read_file"
+	MethodFileExists     = "file_exists"
+	MethodMakeExecutable = "make_executable"
+	MethodSetVariable    = "set_variable"
+	MethodSkipStep       = "skip_step"
+	// MethodRunCommand needs GrantCommands
+	MethodRunCommand = "run_command"
+)
+
+// GrantCommands lets a plugin run commands in the project directory
+const GrantCommands = "commands"
+
+// JSON-RPC error codes
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeHostError      = -32000
+	codeNotGranted     = -32001
+)
+
+// message is a JSON-RPC 2.0 request, notification or response. Messages
+// are sent one per line.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// HandshakeParams is sent by the host when a plugin starts
+type HandshakeParams struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	HostVersion     string `json:"host_version"`
+}
+
+// Info is a plugin's answer to the handshake
+type Info struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Name            string   `json:"name"`
+	Version         string   `json:"version"`
+	Description     string   `json:"description"`
+	Frameworks      []string `json:"frameworks"`
+	BuildTools      []string `json:"build_tools"`
+	// APIVersion is the plugin API the plugin targets; 1 when omitted
+	APIVersion int `json:"api_version,omitempty"`
+	// Options lists the settings the plugin accepts in its config entry
+	Options []registry.Option `json:"options,omitempty"`
+}
+
+// HookParams is sent with each hook call. Plugins never touch the project
+// directory themselves; they ask the host to write files.
+type HookParams struct {
+	Config        *tilocontext.ProjectConfig `json:"config"`
+	Variables     map[string]interface{}     `json:"variables"`
+	Virtual       bool                       `json:"virtual"`
+	Augment       bool                       `json:"augment"`
+	AddedFeatures []string                   `json:"added_features,omitempty"`
+	// Options holds the plugin's config options, with the defaults it
+	// declared for those left out
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// Host call parameters. Paths are relative to the project root and use
+// forward slashes.
+type (
+	pathParams struct {
+		Path string `json:"path"`
+	}
+	writeFileParams struct {
+		Path    string `json:"path"`
+		Content string `json:"content"`
+	}
+	setVariableParams struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"`
+	}
+	skipStepParams struct {
+		Step   string `json:"step"`
+		Reason string `json:"reason"`
+	}
+	runCommandParams struct {
+		Command string   `json:"command"`
+		Args    []string `json:"args"`
+	}
+	runCommandResult struct {
+		Skipped  bool   `json:"skipped"`
+		ExitCode int    `json:"exit_code"`
+		Output   string `json:"output"`
+	}
+)
+
+func decodeParams(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		return &rpcError{Code: codeInvalidParams, Message: "missing params"}
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Command wasmplugin is a plugin for the WebAssembly sandbox tests, built
+// with GOOS=wasip1 GOARCH=wasm. Its first argument selects what generate
+// does.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+var (
+	in     = bufio.NewScanner(os.Stdin)
+	nextID = 0
+)
+
+func send(msg *message) {
+	msg.JSONRPC = "2.0"
+	data, _ := json.Marshal(msg)
+	os.Stdout.Write(append(data, '\n'))
+}
+
+func reply(id *json.RawMessage, result interface{}) {
+	raw, _ := json.Marshal(result)
+	send(&message{ID: id, Result: raw})
+}
+
+// call calls the host and returns its error message, if any
+func call(method string, params interface{}) string {
+	nextID++
+	id := json.RawMessage(fmt.Sprint(nextID))
+	raw, _ := json.Marshal(params)
+	send(&message{ID: &id, Method: method, Params: raw})
+	in.Scan()
+	var answer message
+	json.Unmarshal(in.Bytes(), &answer)
+	if answer.Error != nil {
+		return answer.Error.Message
+	}
+	return string(answer.Result)
+}
+
+func main() {
+	mode := "ok"
+	if len(os.Args) > 1 {
+		mode = os.Args[1]
+	}
+
+	for in.Scan() {
+		var msg message
+		json.Unmarshal(in.Bytes(), &msg)
+		switch msg.Method {
+		case "handshake":
+			reply(msg.ID, map[string]interface{}{
+				"protocol_version": 1,
+				"name":             "wasm-plugin",
+				"version":          "1.0.0",
+				"frameworks":       []string{"wasm"},
+				"build_tools":      []string{"*"},
+			})
+		case "shutdown":
+			return
+		case "generate":
+			var report string
+			switch mode {
+			case "crash":
+				panic("something broke")
+			case "probe":
+				// Nothing outside the sandbox is reachable directly
+				_, readErr := os.ReadDir("/")
+				writeErr := os.WriteFile("/tmp/escaped", []byte("x"), 0644)
+				_, execErr := exec.LookPath("sh")
+				report = fmt.Sprintf("read: %v\nwrite: %v\nexec: %v\n", readErr != nil, writeErr != nil, execErr != nil)
+			case "run":
+				report = call("run_command", map[string]interface{}{"command": "echo", "args": []string{"hi"}}) + "\n"
+			default:
+				report = "hello from wasm\n"
+			}
+			call("write_file", map[string]string{"path": "REPORT.txt", "content": report})
+			reply(msg.ID, nil)
+		default:
+			reply(msg.ID, nil)
+		}
+	}
+}
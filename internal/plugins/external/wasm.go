@@ -0,0 +1,123 @@
+package external
+
+import (
+	"context"
+	crand "crypto/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// wasmMemoryLimitPages caps the memory of a WebAssembly plugin at 256 MiB
+const wasmMemoryLimitPages = 4096
+
+// IsWASM reports whether path is a WebAssembly plugin rather than a native
+// executable
+func IsWASM(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".wasm")
+}
+
+// wasmModule is a compiled WebAssembly plugin. Each start instantiates it
+// afresh, with stdin and stdout as the plugin's only way out: it sees no
+// files, host environment variables or network and reaches the project
+// through host calls.
+type wasmModule struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// compileWASM compiles a module, reusing the machine code cached in
+// cacheDir by earlier runs if it isn't empty
+func compileWASM(path, cacheDir string) (*wasmModule, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	config := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(wasmMemoryLimitPages)
+	if cacheDir != "" {
+		cache, err := wazero.NewCompilationCacheWithDir(cacheDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open compilation cache")
+		}
+		config = config.WithCompilationCache(cache)
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, errors.Wrap(err, "invalid WebAssembly module")
+	}
+	return &wasmModule{runtime: runtime, compiled: compiled}, nil
+}
+
+// start runs the module's _start function in the background
+func (m *wasmModule) start(name string, args []string) (*process, error) {
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		stdinR.Close()
+		stdinW.Close()
+		return nil, err
+	}
+
+	proc := newProcess(stdinW)
+	config := wazero.NewModuleConfig().
+		// Anonymous, so a restarted plugin doesn't clash with the old one
+		WithName("").
+		WithArgs(append([]string{name}, args...)...).
+		WithEnv("TILOKIT_PLUGIN_PROTOCOL", strconv.Itoa(ProtocolVersion)).
+		WithStdin(stdinR).
+		WithStdout(stdoutW).
+		WithStderr(proc.stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(crand.Reader)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	proc.terminate = func() {
+		cancel()
+		// Unblock a plugin waiting for input
+		stdinR.Close()
+	}
+
+	go proc.read(stdoutR)
+	go func() {
+		defer cancel()
+		module, err := m.runtime.InstantiateModule(ctx, m.compiled, config)
+		if module != nil {
+			module.Close(context.Background())
+		}
+		var exit *sys.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() == 0 {
+			err = nil
+		}
+		proc.waitErr = err
+		stdinR.Close()
+		stdoutW.Close()
+		stdinW.Close()
+		close(proc.exited)
+	}()
+	return proc, nil
+}
+
+func (m *wasmModule) close() {
+	m.runtime.Close(context.Background())
+}
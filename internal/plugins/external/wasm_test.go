@@ -0,0 +1,132 @@
+package external
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+var (
+	wasmPluginOnce sync.Once
+	// wasmBuildDir holds the build and its compilation cache; TestMain
+	// removes it
+	wasmBuildDir   string
+	wasmPluginPath string
+	wasmCacheDir   string
+	wasmPluginErr  error
+)
+
+// buildWASMPlugin compiles testdata/wasmplugin once per test run, into
+// wasmBuildDir
+func buildWASMPlugin(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building a WebAssembly plugin is slow")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+
+	wasmPluginOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "tilokit-wasm-")
+		if err != nil {
+			wasmPluginErr = err
+			return
+		}
+		wasmBuildDir = dir
+		wasmPluginPath = filepath.Join(dir, "plugin.wasm")
+		wasmCacheDir = filepath.Join(dir, "cache")
+		cmd := exec.Command(goBin, "build", "-o", wasmPluginPath, "./testdata/wasmplugin")
+		cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Logf("%s", output)
+			wasmPluginErr = err
+		}
+	})
+	if wasmPluginErr != nil {
+		t.Fatal(wasmPluginErr)
+	}
+	return wasmPluginPath
+}
+
+func startWASMPlugin(t *testing.T, mode string, opts Options) *Plugin {
+	t.Helper()
+	path := buildWASMPlugin(t)
+	opts.CacheDir = wasmCacheDir
+	plugin, err := Start(path, []string{mode}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(plugin.Stop)
+	return plugin
+}
+
+func readReport(t *testing.T, plugin *Plugin) string {
+	t.Helper()
+	ctx := newTestContext()
+	if err := plugin.Generate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	report, err := utils.ReadFileFS(ctx.FS, filepath.Join("/out", "demo", "REPORT.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestWASMPluginWritesThroughTheHost(t *testing.T) {
+	plugin := startWASMPlugin(t, "ok", Options{})
+
+	if plugin.Name() != "wasm-plugin" {
+		t.Errorf("Unexpected plugin %s", plugin.Name())
+	}
+	if report := readReport(t, plugin); report != "hello from wasm\n" {
+		t.Errorf("Unexpected report %q", report)
+	}
+}
+
+func TestWASMPluginIsSandboxed(t *testing.T) {
+	plugin := startWASMPlugin(t, "probe", Options{})
+
+	report := readReport(t, plugin)
+	if report != "read: true\nwrite: true\nexec: true\n" {
+		t.Errorf("Expected the filesystem and commands to be out of reach, got %q", report)
+	}
+}
+
+func TestWASMPluginRunsCommandsOnlyWhenGranted(t *testing.T) {
+	plugin := startWASMPlugin(t, "run", Options{})
+	if report := readReport(t, plugin); !strings.Contains(report, `may not run commands; add "commands" to plugins.wasm-plugin.grants`) {
+		t.Errorf("Expected the command to be refused, got %q", report)
+	}
+
+	granted := startWASMPlugin(t, "run", Options{Grants: func(name string) []string {
+		return []string{GrantCommands}
+	}})
+	ctx := newTestContext()
+	ctx.Virtual = true
+	if err := granted.Generate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if skipped := ctx.SkippedSteps(); len(skipped) != 1 || skipped[0].Step != "echo hi" {
+		t.Errorf("Expected the command to be skipped in a virtual run, got %v", skipped)
+	}
+}
+
+func TestCrashedWASMPluginIsRestarted(t *testing.T) {
+	plugin := startWASMPlugin(t, "crash", Options{})
+
+	err := plugin.Generate(newTestContext())
+	if err == nil || !strings.Contains(err.Error(), "something broke") {
+		t.Fatalf("Expected the crash with the plugin's stderr, got %v", err)
+	}
+	if err := plugin.PostGenerate(newTestContext()); err != nil {
+		t.Errorf("Expected the restarted plugin to work, got %v", err)
+	}
+}
@@ -2,7 +2,7 @@ package frameworks
 
 import (
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 // CSharpASPNetCorePlugin implements ASP.NET Core framework support
@@ -17,7 +17,7 @@ func (p *CSharpASPNetCorePlugin) Name() string {
 }
 
 func (p *CSharpASPNetCorePlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *CSharpASPNetCorePlugin) Description() string {
@@ -32,6 +32,18 @@ func (p *CSharpASPNetCorePlugin) SupportedBuildTools() []string {
 	return []string{"dotnet"}
 }
 
+// Manifest declares the plugin API ASP.NET Core targets and that it accepts no options
+func (p *CSharpASPNetCorePlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *CSharpASPNetCorePlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement ASP.NET Core pre-generation logic
 	return nil
@@ -67,7 +79,7 @@ func (p *CSharpBlazorPlugin) Name() string {
 }
 
 func (p *CSharpBlazorPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *CSharpBlazorPlugin) Description() string {
@@ -82,6 +94,18 @@ func (p *CSharpBlazorPlugin) SupportedBuildTools() []string {
 	return []string{"dotnet"}
 }
 
+// Manifest declares the plugin API Blazor targets and that it accepts no options
+func (p *CSharpBlazorPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *CSharpBlazorPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Blazor pre-generation logic
 	return nil
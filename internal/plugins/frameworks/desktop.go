@@ -2,7 +2,7 @@ package frameworks
 
 import (
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 // ElectronPlugin implements Electron framework support
@@ -17,7 +17,7 @@ func (p *ElectronPlugin) Name() string {
 }
 
 func (p *ElectronPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *ElectronPlugin) Description() string {
@@ -32,6 +32,18 @@ func (p *ElectronPlugin) SupportedBuildTools() []string {
 	return []string{"electron-builder", "electron-forge"}
 }
 
+// Manifest declares the plugin API Electron targets and that it accepts no options
+func (p *ElectronPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *ElectronPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Electron pre-generation logic
 	return nil
@@ -66,7 +78,7 @@ func (p *TauriPlugin) Name() string {
 }
 
 func (p *TauriPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *TauriPlugin) Description() string {
@@ -81,6 +93,18 @@ func (p *TauriPlugin) SupportedBuildTools() []string {
 	return []string{"tauri-cli", "cargo"}
 }
 
+// Manifest declares the plugin API Tauri targets and that it accepts no options
+func (p *TauriPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *TauriPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Tauri pre-generation logic
 	return nil
@@ -108,7 +132,7 @@ func (p *WailsPlugin) Name() string {
 }
 
 func (p *WailsPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *WailsPlugin) Description() string {
@@ -123,6 +147,18 @@ func (p *WailsPlugin) SupportedBuildTools() []string {
 	return []string{"wails-cli", "go-modules"}
 }
 
+// Manifest declares the plugin API Wails targets and that it accepts no options
+func (p *WailsPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *WailsPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Wails pre-generation logic
 	return nil
@@ -1,8 +1,11 @@
 package frameworks
 
 import (
+	"github.com/pkg/errors"
+
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/internal/plugins/templates"
 )
 
 // GoGinPlugin implements Gin framework support
@@ -17,7 +20,7 @@ func (p *GoGinPlugin) Name() string {
 }
 
 func (p *GoGinPlugin) Version() string {
-	return constants.VERSION
+	return "0.2.0"
 }
 
 func (p *GoGinPlugin) Description() string {
@@ -32,21 +35,54 @@ func (p *GoGinPlugin) SupportedBuildTools() []string {
 	return []string{"go-modules"}
 }
 
+// Manifest declares the plugin API Gin targets and the options it accepts
+func (p *GoGinPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+		Options: []registry.Option{
+			{Name: "go_version", Type: registry.OptionString, Default: "1.21",
+				Description: "Go version in go.mod"},
+		},
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *GoGinPlugin) Features() []string {
+	return goFeatures
+}
+
 func (p *GoGinPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
+	optionVariable(ctx, "go_version", "go_version")
+
 	// TODO: Implement Gin pre-generation logic
 	return nil
 }
 
 func (p *GoGinPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
-	// TODO: Implement Gin project generation
-	// - Create Go module structure
-	// - Generate main.go with Gin setup
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
+	// Render the shipped Gin template
+	if err := templates.NewTemplateEngine().CopyShippedTemplate("go/gin", ".", ctx); err != nil {
+		return errors.Wrap(err, "failed to render go/gin template")
+	}
+
+	// TODO: Remaining Gin project generation
 	// - Create handlers, middleware
 	// - Set up routing
 	// - Configure database (GORM)
 	// - Generate Docker configuration
 	// - Set up testing
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *GoGinPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -66,7 +102,7 @@ func (p *GoEchoPlugin) Name() string {
 }
 
 func (p *GoEchoPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *GoEchoPlugin) Description() string {
@@ -81,14 +117,38 @@ func (p *GoEchoPlugin) SupportedBuildTools() []string {
 	return []string{"go-modules"}
 }
 
+// Manifest declares the plugin API Echo targets and that it accepts no options
+func (p *GoEchoPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *GoEchoPlugin) Features() []string {
+	return goFeatures
+}
+
 func (p *GoEchoPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Echo pre-generation logic
 	return nil
 }
 
 func (p *GoEchoPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Echo project generation
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *GoEchoPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -108,7 +168,7 @@ func (p *GoFiberPlugin) Name() string {
 }
 
 func (p *GoFiberPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *GoFiberPlugin) Description() string {
@@ -123,14 +183,38 @@ func (p *GoFiberPlugin) SupportedBuildTools() []string {
 	return []string{"go-modules"}
 }
 
+// Manifest declares the plugin API Fiber targets and that it accepts no options
+func (p *GoFiberPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *GoFiberPlugin) Features() []string {
+	return goFeatures
+}
+
 func (p *GoFiberPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Fiber pre-generation logic
 	return nil
 }
 
 func (p *GoFiberPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Fiber project generation
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *GoFiberPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
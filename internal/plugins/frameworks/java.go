@@ -2,7 +2,7 @@ package frameworks
 
 import (
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 // JavaSpringBootPlugin implements Spring Boot framework support
@@ -17,7 +17,7 @@ func (p *JavaSpringBootPlugin) Name() string {
 }
 
 func (p *JavaSpringBootPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *JavaSpringBootPlugin) Description() string {
@@ -32,12 +32,34 @@ func (p *JavaSpringBootPlugin) SupportedBuildTools() []string {
 	return []string{"maven", "gradle"}
 }
 
+// Manifest declares the plugin API Spring Boot targets and that it accepts no options
+func (p *JavaSpringBootPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *JavaSpringBootPlugin) Features() []string {
+	return stackFeatures
+}
+
 func (p *JavaSpringBootPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Spring Boot pre-generation logic
 	return nil
 }
 
 func (p *JavaSpringBootPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Spring Boot project generation
 	// - Create Maven/Gradle project structure
 	// - Generate pom.xml or build.gradle
@@ -47,7 +69,9 @@ func (p *JavaSpringBootPlugin) Generate(ctx *tilocontext.ExecutionContext) error
 	// - Set up security configuration
 	// - Generate Docker configuration
 	// - Set up testing (JUnit, Mockito)
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *JavaSpringBootPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -67,7 +91,7 @@ func (p *JavaQuarkusPlugin) Name() string {
 }
 
 func (p *JavaQuarkusPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *JavaQuarkusPlugin) Description() string {
@@ -82,14 +106,38 @@ func (p *JavaQuarkusPlugin) SupportedBuildTools() []string {
 	return []string{"maven", "gradle"}
 }
 
+// Manifest declares the plugin API Quarkus targets and that it accepts no options
+func (p *JavaQuarkusPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *JavaQuarkusPlugin) Features() []string {
+	return stackFeatures
+}
+
 func (p *JavaQuarkusPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Quarkus pre-generation logic
 	return nil
 }
 
 func (p *JavaQuarkusPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Quarkus project generation
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *JavaQuarkusPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
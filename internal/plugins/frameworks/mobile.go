@@ -2,7 +2,7 @@ package frameworks
 
 import (
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 // ReactNativePlugin implements React Native framework support
@@ -17,7 +17,7 @@ func (p *ReactNativePlugin) Name() string {
 }
 
 func (p *ReactNativePlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *ReactNativePlugin) Description() string {
@@ -32,6 +32,18 @@ func (p *ReactNativePlugin) SupportedBuildTools() []string {
 	return []string{"metro", "expo"}
 }
 
+// Manifest declares the plugin API React Native targets and that it accepts no options
+func (p *ReactNativePlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *ReactNativePlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement React Native pre-generation logic
 	return nil
@@ -66,7 +78,7 @@ func (p *FlutterPlugin) Name() string {
 }
 
 func (p *FlutterPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *FlutterPlugin) Description() string {
@@ -81,6 +93,18 @@ func (p *FlutterPlugin) SupportedBuildTools() []string {
 	return []string{"flutter-cli", "dart"}
 }
 
+// Manifest declares the plugin API Flutter targets and that it accepts no options
+func (p *FlutterPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *FlutterPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Flutter pre-generation logic
 	return nil
@@ -108,7 +132,7 @@ func (p *IonicPlugin) Name() string {
 }
 
 func (p *IonicPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *IonicPlugin) Description() string {
@@ -123,6 +147,18 @@ func (p *IonicPlugin) SupportedBuildTools() []string {
 	return []string{"ionic-cli", "capacitor", "cordova"}
 }
 
+// Manifest declares the plugin API Ionic targets and that it accepts no options
+func (p *IonicPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *IonicPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Ionic pre-generation logic
 	return nil
@@ -2,7 +2,7 @@ package frameworks
 
 import (
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 // NodeExpressPlugin implements Express.js framework support
@@ -17,7 +17,7 @@ func (p *NodeExpressPlugin) Name() string {
 }
 
 func (p *NodeExpressPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *NodeExpressPlugin) Description() string {
@@ -32,6 +32,18 @@ func (p *NodeExpressPlugin) SupportedBuildTools() []string {
 	return []string{"npm", "yarn", "pnpm"}
 }
 
+// Manifest declares the plugin API Express targets and that it accepts no options
+func (p *NodeExpressPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *NodeExpressPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Express pre-generation logic
 	return nil
@@ -67,7 +79,7 @@ func (p *NodeNestJSPlugin) Name() string {
 }
 
 func (p *NodeNestJSPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *NodeNestJSPlugin) Description() string {
@@ -82,6 +94,18 @@ func (p *NodeNestJSPlugin) SupportedBuildTools() []string {
 	return []string{"npm", "yarn", "pnpm"}
 }
 
+// Manifest declares the plugin API NestJS targets and that it accepts no options
+func (p *NodeNestJSPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *NodeNestJSPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement NestJS pre-generation logic
 	return nil
@@ -109,7 +133,7 @@ func (p *NodeFastifyPlugin) Name() string {
 }
 
 func (p *NodeFastifyPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *NodeFastifyPlugin) Description() string {
@@ -124,6 +148,18 @@ func (p *NodeFastifyPlugin) SupportedBuildTools() []string {
 	return []string{"npm", "yarn", "pnpm"}
 }
 
+// Manifest declares the plugin API Fastify targets and that it accepts no options
+func (p *NodeFastifyPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
 func (p *NodeFastifyPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Fastify pre-generation logic
 	return nil
@@ -0,0 +1,131 @@
+package frameworks
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/core/patch"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// packageJSON is the package.json written by the JavaScript framework
+// plugins. Scripts and dependencies are written in order, the framework's
+// first and then those of the features.
+type packageJSON struct {
+	Name            string
+	Private         bool
+	Version         string
+	Type            string
+	Scripts         patch.Object
+	Dependencies    patch.Object
+	DevDependencies patch.Object
+}
+
+// selectedFeatures returns the features resolved for the run
+func selectedFeatures(ctx *tilocontext.ExecutionContext) features.Selection {
+	module, _ := ctx.Variables["module_name"].(string)
+	return features.Selection{
+		Framework: ctx.Config.Framework,
+		Features:  ctx.Config.Features,
+		BuildTool: ctx.Config.BuildTool,
+		Module:    module,
+	}
+}
+
+// writeFeatureProject adds what the selected features contribute to pkg,
+// then writes package.json and the features' files
+func writeFeatureProject(ctx *tilocontext.ExecutionContext, pkg packageJSON) error {
+	contribution := features.Builtin().Contributions(selectedFeatures(ctx))
+
+	// The framework owns these keys, so they replace what an earlier
+	// generation left in a staged copy of the project
+	fields := patch.Object{
+		{Key: "name", Value: pkg.Name},
+		{Key: "private", Value: pkg.Private},
+		{Key: "version", Value: pkg.Version},
+		{Key: "type", Value: pkg.Type},
+		{Key: "scripts", Value: pkg.Scripts.With(contribution.Scripts...)},
+		{Key: "dependencies", Value: pkg.Dependencies.With(contribution.Dependencies...)},
+	}
+	if devDependencies := pkg.DevDependencies.With(contribution.DevDependencies...); len(devDependencies) > 0 {
+		fields = append(fields, patch.Field{Key: "devDependencies", Value: devDependencies})
+	}
+	if err := ctx.PatchFile("package.json", fields, patch.Override); err != nil {
+		return errors.Wrap(err, "failed to write package.json")
+	}
+	if err := patchFeatureFiles(ctx, contribution, patch.Override); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(contribution.Files))
+	for path := range contribution.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := ctx.WriteFile(path, contribution.Files[path]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// npmFeatures are the features the JavaScript framework plugins can add to
+// an existing project
+var npmFeatures = []string{features.ESLint, features.Prettier, features.Testing, features.Docker, features.CI}
+
+// augmentFeatureProject adds what the features being added contribute to an
+// existing project. package.json and the other structured files gain the
+// keys they don't have yet; other files that already exist are left alone.
+func augmentFeatureProject(ctx *tilocontext.ExecutionContext) error {
+	contribution := features.Builtin().Contribution(selectedFeatures(ctx), ctx.AddedFeatures)
+
+	if ctx.FileExists("package.json") {
+		err := ctx.PatchFile("package.json", patch.Object{
+			{Key: "scripts", Value: contribution.Scripts},
+			{Key: "dependencies", Value: contribution.Dependencies},
+			{Key: "devDependencies", Value: contribution.DevDependencies},
+		}, patch.Keep)
+		if err != nil {
+			return errors.Wrap(err, "failed to merge package.json")
+		}
+	}
+	if err := patchFeatureFiles(ctx, contribution, patch.Keep); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(contribution.Files))
+	for path := range contribution.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if ctx.FileExists(path) {
+			utils.Warning("Keeping existing %s", path)
+			continue
+		}
+		if err := ctx.WriteFile(path, contribution.Files[path]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchFeatureFiles merges the structured files of a contribution into the
+// project, resolving keys the files already set with policy
+func patchFeatureFiles(ctx *tilocontext.ExecutionContext, contribution features.Contribution, policy patch.Policy) error {
+	paths := make([]string, 0, len(contribution.Patches))
+	for path := range contribution.Patches {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := ctx.PatchFile(path, contribution.Patches[path], policy); err != nil {
+			return errors.Wrapf(err, "failed to write %s", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package frameworks
+
+import (
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+)
+
+// optionVariable sets a template variable to an option of the running
+// plugin, unless the user already set the variable, e.g. with --set
+func optionVariable(ctx *tilocontext.ExecutionContext, option, variable string) {
+	if _, set := ctx.GetVariable(variable); set {
+		return
+	}
+	if value, ok := ctx.Option(option); ok {
+		ctx.SetVariable(variable, value)
+	}
+}
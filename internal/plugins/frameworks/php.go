@@ -2,7 +2,7 @@ package frameworks
 
 import (
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 // PHPLaravelPlugin implements Laravel framework support
@@ -17,7 +17,7 @@ func (p *PHPLaravelPlugin) Name() string {
 }
 
 func (p *PHPLaravelPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *PHPLaravelPlugin) Description() string {
@@ -32,12 +32,34 @@ func (p *PHPLaravelPlugin) SupportedBuildTools() []string {
 	return []string{"composer", "artisan"}
 }
 
+// Manifest declares the plugin API Laravel targets and that it accepts no options
+func (p *PHPLaravelPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *PHPLaravelPlugin) Features() []string {
+	return stackFeatures
+}
+
 func (p *PHPLaravelPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Laravel pre-generation logic
 	return nil
 }
 
 func (p *PHPLaravelPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Laravel project generation
 	// - Create Laravel project structure
 	// - Generate .env configuration
@@ -47,7 +69,9 @@ func (p *PHPLaravelPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
 	// - Set up authentication
 	// - Generate Docker configuration
 	// - Set up testing (PHPUnit)
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *PHPLaravelPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -67,7 +91,7 @@ func (p *PHPSymfonyPlugin) Name() string {
 }
 
 func (p *PHPSymfonyPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *PHPSymfonyPlugin) Description() string {
@@ -82,14 +106,38 @@ func (p *PHPSymfonyPlugin) SupportedBuildTools() []string {
 	return []string{"composer", "symfony-cli"}
 }
 
+// Manifest declares the plugin API Symfony targets and that it accepts no options
+func (p *PHPSymfonyPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *PHPSymfonyPlugin) Features() []string {
+	return stackFeatures
+}
+
 func (p *PHPSymfonyPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Symfony pre-generation logic
 	return nil
 }
 
 func (p *PHPSymfonyPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Symfony project generation
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *PHPSymfonyPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -1,8 +1,11 @@
 package frameworks
 
 import (
+	"github.com/pkg/errors"
+
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/internal/plugins/templates"
 )
 
 // PythonDjangoPlugin implements Django framework support
@@ -17,7 +20,7 @@ func (p *PythonDjangoPlugin) Name() string {
 }
 
 func (p *PythonDjangoPlugin) Version() string {
-	return constants.VERSION
+	return "0.2.0"
 }
 
 func (p *PythonDjangoPlugin) Description() string {
@@ -32,20 +35,53 @@ func (p *PythonDjangoPlugin) SupportedBuildTools() []string {
 	return []string{"pip", "poetry", "pipenv"}
 }
 
+// Manifest declares the plugin API Django targets and the options it accepts
+func (p *PythonDjangoPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+		Options: []registry.Option{
+			{Name: "python_version", Type: registry.OptionString, Default: "3.11",
+				Description: "Python version of the Docker base image"},
+		},
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *PythonDjangoPlugin) Features() []string {
+	return testedStackFeatures
+}
+
 func (p *PythonDjangoPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
+	optionVariable(ctx, "python_version", "python_version")
+
 	// TODO: Implement Django pre-generation logic
 	return nil
 }
 
 func (p *PythonDjangoPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
-	// TODO: Implement Django project generation
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
+	// Render the shipped Django template
+	if err := templates.NewTemplateEngine().CopyShippedTemplate("python/django", ".", ctx); err != nil {
+		return errors.Wrap(err, "failed to render python/django template")
+	}
+
+	// TODO: Remaining Django project generation
 	// - Create Django project structure
 	// - Generate settings.py with best practices
 	// - Set up virtual environment
-	// - Create requirements.txt
-	// - Generate Docker configuration
 	// - Set up testing framework
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *PythonDjangoPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -65,7 +101,7 @@ func (p *PythonFlaskPlugin) Name() string {
 }
 
 func (p *PythonFlaskPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *PythonFlaskPlugin) Description() string {
@@ -80,14 +116,38 @@ func (p *PythonFlaskPlugin) SupportedBuildTools() []string {
 	return []string{"pip", "poetry", "pipenv"}
 }
 
+// Manifest declares the plugin API Flask targets and that it accepts no options
+func (p *PythonFlaskPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *PythonFlaskPlugin) Features() []string {
+	return testedStackFeatures
+}
+
 func (p *PythonFlaskPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Flask pre-generation logic
 	return nil
 }
 
 func (p *PythonFlaskPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Flask project generation
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *PythonFlaskPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -107,7 +167,7 @@ func (p *PythonFastAPIPlugin) Name() string {
 }
 
 func (p *PythonFastAPIPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *PythonFastAPIPlugin) Description() string {
@@ -122,14 +182,38 @@ func (p *PythonFastAPIPlugin) SupportedBuildTools() []string {
 	return []string{"pip", "poetry", "pipenv"}
 }
 
+// Manifest declares the plugin API FastAPI targets and that it accepts no options
+func (p *PythonFastAPIPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *PythonFastAPIPlugin) Features() []string {
+	return testedStackFeatures
+}
+
 func (p *PythonFastAPIPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement FastAPI pre-generation logic
 	return nil
 }
 
 func (p *PythonFastAPIPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement FastAPI project generation
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *PythonFastAPIPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
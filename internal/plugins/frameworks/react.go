@@ -1,11 +1,11 @@
 package frameworks
 
 import (
-	"path/filepath"
-
 	"github.com/pkg/errors"
 	"github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/internal/utils"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/core/patch"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 // ReactPlugin implements React framework support
@@ -36,16 +36,48 @@ func (p *ReactPlugin) SupportedBuildTools() []string {
 	return []string{"vite", "webpack", "rollup"}
 }
 
+// Manifest declares the plugin API React targets and the options it accepts
+func (p *ReactPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+		Options: []registry.Option{
+			{Name: "strict_mode", Type: registry.OptionBool, Default: true,
+				Description: "Render the app in React.StrictMode"},
+		},
+	}
+}
+
+// Dependencies declares that the plugin writes package.json, so builders
+// that add to the file, such as Vite, run after it
+func (p *ReactPlugin) Dependencies() registry.Dependencies {
+	return registry.Dependencies{Provides: []string{"package.json"}}
+}
+
 func (p *ReactPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// Set React-specific variables
 	ctx.SetVariable("react_version", "^18.2.0")
 	ctx.SetVariable("react_dom_version", "^18.2.0")
-	ctx.SetVariable("typescript_support", true)
-	
+	ctx.SetVariable("typescript_support", selectedFeatures(ctx).Has(features.TypeScript))
+
 	return nil
 }
 
+// Features lists the features the plugin can add to an existing project
+func (p *ReactPlugin) Features() []string {
+	return npmFeatures
+}
+
 func (p *ReactPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// Create directory structure
 	if err := p.createDirectoryStructure(ctx); err != nil {
 		return errors.Wrap(err, "failed to create directory structure")
@@ -74,7 +106,7 @@ func (p *ReactPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
 	ctx.SetMetadata("framework_generated", true)
 	ctx.SetMetadata("install_command", "npm install")
 	ctx.SetMetadata("start_command", "npm run dev")
-	
+
 	return nil
 }
 
@@ -90,8 +122,7 @@ func (p *ReactPlugin) createDirectoryStructure(ctx *tilocontext.ExecutionContext
 	}
 
 	for _, dir := range dirs {
-		dirPath := filepath.Join(ctx.ProjectPath, dir)
-		if err := utils.EnsureDir(dirPath); err != nil {
+		if err := ctx.EnsureDir(dir); err != nil {
 			return err
 		}
 	}
@@ -100,58 +131,56 @@ func (p *ReactPlugin) createDirectoryStructure(ctx *tilocontext.ExecutionContext
 }
 
 func (p *ReactPlugin) generatePackageJson(ctx *tilocontext.ExecutionContext) error {
-	packageJson := `{
-  "name": "` + ctx.Config.ProjectName + `",
-  "private": true,
-  "version": "0.0.0",
-  "type": "module",
-  "scripts": {
-    "dev": "vite",
-    "build": "tsc && vite build",
-    "lint": "eslint . --ext ts,tsx --report-unused-disable-directives --max-warnings 0",
-    "preview": "vite preview",
-    "test": "vitest",
-    "test:ui": "vitest --ui"
-  },
-  "dependencies": {
-    "react": "^18.2.0",
-    "react-dom": "^18.2.0",
-    "react-router-dom": "^6.20.1"
-  },
-  "devDependencies": {
-    "@types/react": "^18.2.37",
-    "@types/react-dom": "^18.2.15",
-    "@typescript-eslint/eslint-plugin": "^6.10.0",
-    "@typescript-eslint/parser": "^6.10.0",
-    "@vitejs/plugin-react": "^4.1.1",
-    "eslint": "^8.53.0",
-    "eslint-plugin-react-hooks": "^4.6.0",
-    "eslint-plugin-react-refresh": "^0.4.4",
-    "typescript": "^5.2.2",
-    "vite": "^5.0.0",
-    "vitest": "^1.0.0",
-    "@vitest/ui": "^1.0.0"
-  }
-}`
-
-	packageJsonPath := filepath.Join(ctx.ProjectPath, "package.json")
-	return utils.WriteFile(packageJsonPath, packageJson)
+	// Language, lint, formatting and test tooling come from the features
+	return writeFeatureProject(ctx, packageJSON{
+		Name:    ctx.Config.ProjectName,
+		Private: true,
+		Version: "0.0.0",
+		Type:    "module",
+		Scripts: patch.Object{
+			{Key: "dev", Value: "vite"},
+			{Key: "build", Value: "vite build"},
+			{Key: "preview", Value: "vite preview"},
+		},
+		Dependencies: patch.Object{
+			{Key: "react", Value: "^18.2.0"},
+			{Key: "react-dom", Value: "^18.2.0"},
+			{Key: "react-router-dom", Value: "^6.20.1"},
+		},
+		DevDependencies: patch.Object{
+			{Key: "@vitejs/plugin-react", Value: "^4.1.1"},
+			{Key: "vite", Value: "^5.0.0"},
+		},
+	})
 }
 
 func (p *ReactPlugin) generateSourceFiles(ctx *tilocontext.ExecutionContext) error {
-	// Generate main.tsx
-	mainTsx := `import React from 'react'
-import ReactDOM from 'react-dom/client'
-import App from './App.tsx'
-import './styles/index.css'
+	selection := selectedFeatures(ctx)
+	ext := selection.ScriptExt() + "x"
+	language, nonNull := "JavaScript", ""
+	if selection.Has(features.TypeScript) {
+		language, nonNull = "TypeScript", "!"
+	}
 
-ReactDOM.createRoot(document.getElementById('root')!).render(
+	// Generate main.tsx or main.jsx
+	root := `
+  <App />,
+`
+	if ctx.BoolOption("strict_mode") {
+		root = `
   <React.StrictMode>
     <App />
   </React.StrictMode>,
-)`
+`
+	}
+	mainTsx := `import React from 'react'
+import ReactDOM from 'react-dom/client'
+import App from './App.` + ext + `'
+import './styles/index.css'
+
+ReactDOM.createRoot(document.getElementById('root')` + nonNull + `).render(` + root + `)`
 
-	// Generate App.tsx
+	// Generate App.tsx or App.jsx
 	appTsx := `import { useState } from 'react'
 import './styles/App.css'
 
@@ -162,13 +191,13 @@ function App() {
     <div className="app">
       <header className="app-header">
         <h1>Welcome to ` + ctx.Config.ProjectName + `</h1>
-        <p>Built with React + TypeScript + Vite</p>
+        <p>Built with React + ` + language + ` + Vite</p>
         <div className="card">
           <button onClick={() => setCount((count) => count + 1)}>
             count is {count}
           </button>
           <p>
-            Edit <code>src/App.tsx</code> and save to test HMR
+            Edit <code>src/App.` + ext + `</code> and save to test HMR
           </p>
         </div>
       </header>
@@ -271,15 +300,14 @@ button:focus-visible {
 
 	// Write files
 	files := map[string]string{
-		"src/main.tsx":        mainTsx,
-		"src/App.tsx":         appTsx,
+		"src/main." + ext:      mainTsx,
+		"src/App." + ext:       appTsx,
 		"src/styles/index.css": indexCss,
 		"src/styles/App.css":   appCss,
 	}
 
 	for path, content := range files {
-		fullPath := filepath.Join(ctx.ProjectPath, path)
-		if err := utils.WriteFile(fullPath, content); err != nil {
+		if err := ctx.WriteFile(path, content); err != nil {
 			return err
 		}
 	}
@@ -288,52 +316,7 @@ button:focus-visible {
 }
 
 func (p *ReactPlugin) generateConfigFiles(ctx *tilocontext.ExecutionContext) error {
-	// Generate tsconfig.json
-	tsConfig := `{
-  "compilerOptions": {
-    "target": "ES2020",
-    "useDefineForClassFields": true,
-    "lib": ["ES2020", "DOM", "DOM.Iterable"],
-    "module": "ESNext",
-    "skipLibCheck": true,
-
-    /* Bundler mode */
-    "moduleResolution": "bundler",
-    "allowImportingTsExtensions": true,
-    "resolveJsonModule": true,
-    "isolatedModules": true,
-    "noEmit": true,
-    "jsx": "react-jsx",
-
-    /* Linting */
-    "strict": true,
-    "noUnusedLocals": true,
-    "noUnusedParameters": true,
-    "noFallthroughCasesInSwitch": true,
-
-    /* Path mapping */
-    "baseUrl": ".",
-    "paths": {
-      "@/*": ["./src/*"]
-    }
-  },
-  "include": ["src"],
-  "references": [{ "path": "./tsconfig.node.json" }]
-}`
-
-	// Generate tsconfig.node.json
-	tsConfigNode := `{
-  "compilerOptions": {
-    "composite": true,
-    "skipLibCheck": true,
-    "module": "ESNext",
-    "moduleResolution": "bundler",
-    "allowSyntheticDefaultImports": true
-  },
-  "include": ["vite.config.ts"]
-}`
-
-	// Generate index.html
+	// TypeScript and ESLint configs are written by their features
 	indexHtml := `<!doctype html>
 <html lang="en">
   <head>
@@ -344,44 +327,9 @@ func (p *ReactPlugin) generateConfigFiles(ctx *tilocontext.ExecutionContext) err
   </head>
   <body>
     <div id="root"></div>
-    <script type="module" src="/src/main.tsx"></script>
+    <script type="module" src="/src/main.` + selectedFeatures(ctx).ScriptExt() + `x"></script>
   </body>
 </html>`
 
-	// Generate .eslintrc.cjs
-	eslintConfig := `module.exports = {
-  root: true,
-  env: { browser: true, es2020: true },
-  extends: [
-    'eslint:recommended',
-    '@typescript-eslint/recommended',
-    'plugin:react-hooks/recommended',
-  ],
-  ignorePatterns: ['dist', '.eslintrc.cjs'],
-  parser: '@typescript-eslint/parser',
-  plugins: ['react-refresh'],
-  rules: {
-    'react-refresh/only-export-components': [
-      'warn',
-      { allowConstantExport: true },
-    ],
-  },
-}`
-
-	// Write config files
-	configs := map[string]string{
-		"tsconfig.json":      tsConfig,
-		"tsconfig.node.json": tsConfigNode,
-		"index.html":         indexHtml,
-		".eslintrc.cjs":      eslintConfig,
-	}
-
-	for path, content := range configs {
-		fullPath := filepath.Join(ctx.ProjectPath, path)
-		if err := utils.WriteFile(fullPath, content); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return ctx.WriteFile("index.html", indexHtml)
 }
@@ -2,7 +2,7 @@ package frameworks
 
 import (
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 )
 
 // RubyRailsPlugin implements Ruby on Rails framework support
@@ -17,7 +17,7 @@ func (p *RubyRailsPlugin) Name() string {
 }
 
 func (p *RubyRailsPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *RubyRailsPlugin) Description() string {
@@ -32,12 +32,34 @@ func (p *RubyRailsPlugin) SupportedBuildTools() []string {
 	return []string{"bundler", "gem"}
 }
 
+// Manifest declares the plugin API Rails targets and that it accepts no options
+func (p *RubyRailsPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *RubyRailsPlugin) Features() []string {
+	return stackFeatures
+}
+
 func (p *RubyRailsPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Rails pre-generation logic
 	return nil
 }
 
 func (p *RubyRailsPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Rails project generation
 	// - Create Rails project structure
 	// - Generate Gemfile with dependencies
@@ -46,7 +68,9 @@ func (p *RubyRailsPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
 	// - Set up routing
 	// - Generate Docker configuration
 	// - Set up testing (RSpec)
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *RubyRailsPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -66,7 +90,7 @@ func (p *RubySinatraPlugin) Name() string {
 }
 
 func (p *RubySinatraPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *RubySinatraPlugin) Description() string {
@@ -81,14 +105,38 @@ func (p *RubySinatraPlugin) SupportedBuildTools() []string {
 	return []string{"bundler", "gem"}
 }
 
+// Manifest declares the plugin API Sinatra targets and that it accepts no options
+func (p *RubySinatraPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *RubySinatraPlugin) Features() []string {
+	return stackFeatures
+}
+
 func (p *RubySinatraPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Sinatra pre-generation logic
 	return nil
 }
 
 func (p *RubySinatraPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Sinatra project generation
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *RubySinatraPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -1,8 +1,11 @@
 package frameworks
 
 import (
+	"github.com/pkg/errors"
+
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/pkg/constants"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/internal/plugins/templates"
 )
 
 // RustActixPlugin implements Actix Web framework support
@@ -17,7 +20,7 @@ func (p *RustActixPlugin) Name() string {
 }
 
 func (p *RustActixPlugin) Version() string {
-	return constants.VERSION
+	return "0.2.0"
 }
 
 func (p *RustActixPlugin) Description() string {
@@ -32,21 +35,53 @@ func (p *RustActixPlugin) SupportedBuildTools() []string {
 	return []string{"cargo"}
 }
 
+// Manifest declares the plugin API Actix targets and the options it accepts
+func (p *RustActixPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+		Options: []registry.Option{
+			{Name: "edition", Type: registry.OptionChoice, Default: "2021", Choices: []string{"2018", "2021"},
+				Description: "Rust edition in Cargo.toml"},
+		},
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *RustActixPlugin) Features() []string {
+	return testedStackFeatures
+}
+
 func (p *RustActixPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
+	optionVariable(ctx, "edition", "rust_edition")
+
 	// TODO: Implement Actix pre-generation logic
 	return nil
 }
 
 func (p *RustActixPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
-	// TODO: Implement Actix project generation
-	// - Create Cargo project structure
-	// - Generate Cargo.toml with dependencies
-	// - Set up main.rs with Actix setup
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
+	// Render the shipped Actix template
+	if err := templates.NewTemplateEngine().CopyShippedTemplate("rust/actix", ".", ctx); err != nil {
+		return errors.Wrap(err, "failed to render rust/actix template")
+	}
+
+	// TODO: Remaining Actix project generation
 	// - Create handlers, middleware
 	// - Configure database (Diesel/SQLx)
 	// - Generate Docker configuration
 	// - Set up testing
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *RustActixPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -66,7 +101,7 @@ func (p *RustRocketPlugin) Name() string {
 }
 
 func (p *RustRocketPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *RustRocketPlugin) Description() string {
@@ -81,14 +116,38 @@ func (p *RustRocketPlugin) SupportedBuildTools() []string {
 	return []string{"cargo"}
 }
 
+// Manifest declares the plugin API Rocket targets and that it accepts no options
+func (p *RustRocketPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *RustRocketPlugin) Features() []string {
+	return testedStackFeatures
+}
+
 func (p *RustRocketPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Rocket pre-generation logic
 	return nil
 }
 
 func (p *RustRocketPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Rocket project generation
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *RustRocketPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -108,7 +167,7 @@ func (p *RustAxumPlugin) Name() string {
 }
 
 func (p *RustAxumPlugin) Version() string {
-	return constants.VERSION
+	return "0.1.0"
 }
 
 func (p *RustAxumPlugin) Description() string {
@@ -123,14 +182,38 @@ func (p *RustAxumPlugin) SupportedBuildTools() []string {
 	return []string{"cargo"}
 }
 
+// Manifest declares the plugin API Axum targets and that it accepts no options
+func (p *RustAxumPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+	}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *RustAxumPlugin) Features() []string {
+	return testedStackFeatures
+}
+
 func (p *RustAxumPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// TODO: Implement Axum pre-generation logic
 	return nil
 }
 
 func (p *RustAxumPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	// TODO: Implement Axum project generation
-	return nil
+
+	// Add the files of the selected features
+	return writeFeatureFiles(ctx)
 }
 
 func (p *RustAxumPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
@@ -0,0 +1,42 @@
+package frameworks
+
+import (
+	"sort"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/core/patch"
+)
+
+// Features the plugins of the server stacks can add to an existing
+// project. Go needs no test setup, so its plugins don't offer testing.
+var (
+	goFeatures          = []string{features.Docker, features.CI}
+	stackFeatures       = []string{features.Docker, features.CI}
+	testedStackFeatures = []string{features.Testing, features.Docker, features.CI}
+)
+
+// writeFeatureFiles writes the files the selected features contribute to
+// a project without a package.json, keeping those the framework's template
+// already wrote, such as its own Dockerfile, and the keys they set
+func writeFeatureFiles(ctx *tilocontext.ExecutionContext) error {
+	contribution := features.Builtin().Contributions(selectedFeatures(ctx))
+	if err := patchFeatureFiles(ctx, contribution, patch.Keep); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(contribution.Files))
+	for path := range contribution.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if ctx.FileExists(path) {
+			continue
+		}
+		if err := ctx.WriteFile(path, contribution.Files[path]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
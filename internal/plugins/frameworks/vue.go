@@ -1,11 +1,11 @@
 package frameworks
 
 import (
-	"path/filepath"
-
 	"github.com/pkg/errors"
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
-	"github.com/ti-lo/tilokit/internal/utils"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/core/patch"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 	"github.com/ti-lo/tilokit/pkg/constants"
 )
 
@@ -37,13 +37,45 @@ func (p *VuePlugin) SupportedBuildTools() []string {
 	return []string{"vite", "webpack"}
 }
 
+// Manifest declares the plugin API Vue targets and the options it accepts
+func (p *VuePlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+		Options: []registry.Option{
+			{Name: "router_history", Type: registry.OptionChoice, Default: "web", Choices: []string{"web", "hash"},
+				Description: "History mode of Vue Router"},
+		},
+	}
+}
+
+// Dependencies declares that the plugin writes package.json, so builders
+// that add to the file, such as Vite, run after it
+func (p *VuePlugin) Dependencies() registry.Dependencies {
+	return registry.Dependencies{Provides: []string{"package.json"}}
+}
+
 func (p *VuePlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	ctx.SetVariable("vue_version", "^3.4.0")
-	ctx.SetVariable("typescript_support", true)
+	ctx.SetVariable("typescript_support", selectedFeatures(ctx).Has(features.TypeScript))
 	return nil
 }
 
+// Features lists the features the plugin can add to an existing project
+func (p *VuePlugin) Features() []string {
+	return npmFeatures
+}
+
 func (p *VuePlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	// An existing project only gets the added features
+	if ctx.Augment {
+		return augmentFeatureProject(ctx)
+	}
+
 	if err := p.createDirectoryStructure(ctx); err != nil {
 		return errors.Wrap(err, "failed to create directory structure")
 	}
@@ -84,8 +116,7 @@ func (p *VuePlugin) createDirectoryStructure(ctx *tilocontext.ExecutionContext)
 	}
 
 	for _, dir := range dirs {
-		dirPath := filepath.Join(ctx.ProjectPath, dir)
-		if err := utils.EnsureDir(dirPath); err != nil {
+		if err := ctx.EnsureDir(dir); err != nil {
 			return err
 		}
 	}
@@ -94,47 +125,38 @@ func (p *VuePlugin) createDirectoryStructure(ctx *tilocontext.ExecutionContext)
 }
 
 func (p *VuePlugin) generatePackageJson(ctx *tilocontext.ExecutionContext) error {
-	packageJson := `{
-  "name": "` + ctx.Config.ProjectName + `",
-  "private": true,
-  "version": "0.0.0",
-  "type": "module",
-  "scripts": {
-    "dev": "vite",
-    "build": "vue-tsc --noEmit && vite build",
-    "preview": "vite preview",
-    "lint": "eslint . --ext .vue,.js,.jsx,.cjs,.mjs,.ts,.tsx,.cts,.mts --fix --ignore-path .gitignore",
-    "type-check": "vue-tsc --noEmit"
-  },
-  "dependencies": {
-    "vue": "^3.4.0",
-    "vue-router": "^4.2.5",
-    "pinia": "^2.1.7"
-  },
-  "devDependencies": {
-    "@rushstack/eslint-patch": "^1.3.3",
-    "@tsconfig/node18": "^18.2.2",
-    "@types/node": "^18.18.5",
-    "@vitejs/plugin-vue": "^4.4.0",
-    "@vue/eslint-config-prettier": "^8.0.0",
-    "@vue/eslint-config-typescript": "^12.0.0",
-    "@vue/tsconfig": "^0.4.0",
-    "eslint": "^8.49.0",
-    "eslint-plugin-vue": "^9.17.0",
-    "npm-run-all2": "^6.1.1",
-    "prettier": "^3.0.3",
-    "typescript": "~5.2.0",
-    "vite": "^5.0.0",
-    "vue-tsc": "^1.8.19"
-  }
-}`
-
-	packageJsonPath := filepath.Join(ctx.ProjectPath, "package.json")
-	return utils.WriteFile(packageJsonPath, packageJson)
+	// Language, lint, formatting and test tooling come from the features
+	return writeFeatureProject(ctx, packageJSON{
+		Name:    ctx.Config.ProjectName,
+		Private: true,
+		Version: "0.0.0",
+		Type:    "module",
+		Scripts: patch.Object{
+			{Key: "dev", Value: "vite"},
+			{Key: "build", Value: "vite build"},
+			{Key: "preview", Value: "vite preview"},
+		},
+		Dependencies: patch.Object{
+			{Key: "vue", Value: "^3.4.0"},
+			{Key: "vue-router", Value: "^4.2.5"},
+			{Key: "pinia", Value: "^2.1.7"},
+		},
+		DevDependencies: patch.Object{
+			{Key: "@vitejs/plugin-vue", Value: "^4.4.0"},
+			{Key: "vite", Value: "^5.0.0"},
+		},
+	})
 }
 
 func (p *VuePlugin) generateSourceFiles(ctx *tilocontext.ExecutionContext) error {
-	// Generate main.ts
+	selection := selectedFeatures(ctx)
+	ext := selection.ScriptExt()
+	lang := ""
+	if selection.Has(features.TypeScript) {
+		lang = ` lang="ts"`
+	}
+
+	// Generate main.ts or main.js
 	mainTs := `import { createApp } from 'vue'
 import { createPinia } from 'pinia'
 import App from './App.vue'
@@ -150,7 +172,7 @@ app.use(router)
 app.mount('#app')`
 
 	// Generate App.vue
-	appVue := `<script setup lang="ts">
+	appVue := `<script setup` + lang + `>
 import { RouterLink, RouterView } from 'vue-router'
 import HelloWorld from './components/HelloWorld.vue'
 </script>
@@ -229,11 +251,23 @@ nav a:first-of-type {
 }
 </style>`
 
-	// Generate HelloWorld.vue
-	helloWorldVue := `<script setup lang="ts">
-defineProps<{
+	// Generate HelloWorld.vue, declaring props the way the language allows
+	helloWorldProps := `defineProps({
+  msg: {
+    type: String,
+    required: true
+  }
+})`
+	madeWith := ""
+	if selection.Has(features.TypeScript) {
+		helloWorldProps = `defineProps<{
   msg: string
-}>()
+}>()`
+		madeWith = ` +
+      <a href="https://www.typescriptlang.org/" target="_blank" rel="noopener">TypeScript</a>`
+	}
+	helloWorldVue := `<script setup` + lang + `>
+` + helloWorldProps + `
 </script>
 
 <template>
@@ -242,8 +276,7 @@ defineProps<{
     <h3>
       You've successfully created a project with
       <a href="https://vitejs.dev/" target="_blank" rel="noopener">Vite</a> +
-      <a href="https://vuejs.org/" target="_blank" rel="noopener">Vue 3</a> +
-      <a href="https://www.typescriptlang.org/" target="_blank" rel="noopener">TypeScript</a>.
+      <a href="https://vuejs.org/" target="_blank" rel="noopener">Vue 3</a>` + madeWith + `.
     </h3>
   </div>
 </template>
@@ -274,11 +307,15 @@ h3 {
 </style>`
 
 	// Generate router
-	routerTs := `import { createRouter, createWebHistory } from 'vue-router'
+	history := "createWebHistory"
+	if ctx.StringOption("router_history") == "hash" {
+		history = "createWebHashHistory"
+	}
+	routerTs := `import { createRouter, ` + history + ` } from 'vue-router'
 import HomeView from '../views/HomeView.vue'
 
 const router = createRouter({
-  history: createWebHistory(import.meta.env.BASE_URL),
+  history: ` + history + `(import.meta.env.BASE_URL),
   routes: [
     {
       path: '/',
@@ -296,7 +333,7 @@ const router = createRouter({
 export default router`
 
 	// Generate views
-	homeViewVue := `<script setup lang="ts">
+	homeViewVue := `<script setup` + lang + `>
 import TheWelcome from '../components/TheWelcome.vue'
 </script>
 
@@ -322,7 +359,7 @@ import TheWelcome from '../components/TheWelcome.vue'
 }
 </style>`
 
-	theWelcomeVue := `<script setup lang="ts">
+	theWelcomeVue := `<script setup` + lang + `>
 import WelcomeItem from './WelcomeItem.vue'
 import DocumentationIcon from './icons/IconDocumentation.vue'
 import ToolingIcon from './icons/IconTooling.vue'
@@ -625,7 +662,7 @@ h3 {
 
 	// Write files
 	files := map[string]string{
-		"src/main.ts":                                mainTs,
+		"src/main." + ext:                            mainTs,
 		"src/App.vue":                                appVue,
 		"src/components/HelloWorld.vue":              helloWorldVue,
 		"src/components/WelcomeItem.vue":             welcomeItemVue,
@@ -634,7 +671,7 @@ h3 {
 		"src/components/icons/IconEcosystem.vue":     iconEcosystem,
 		"src/components/icons/IconCommunity.vue":     iconCommunity,
 		"src/components/icons/IconSupport.vue":       iconSupport,
-		"src/router/index.ts":                        routerTs,
+		"src/router/index." + ext:                    routerTs,
 		"src/views/HomeView.vue":                     homeViewVue,
 		"src/views/AboutView.vue":                    aboutViewVue,
 		"src/components/TheWelcome.vue":              theWelcomeVue,
@@ -643,8 +680,7 @@ h3 {
 	}
 
 	for path, content := range files {
-		fullPath := filepath.Join(ctx.ProjectPath, path)
-		if err := utils.WriteFile(fullPath, content); err != nil {
+		if err := ctx.WriteFile(path, content); err != nil {
 			return err
 		}
 	}
@@ -653,28 +689,7 @@ h3 {
 }
 
 func (p *VuePlugin) generateConfigFiles(ctx *tilocontext.ExecutionContext) error {
-	// Generate tsconfig.json
-	tsConfig := `{
-  "extends": "@vue/tsconfig/tsconfig.dom.json",
-  "include": [
-    "env.d.ts",
-    "src/**/*",
-    "src/**/*.vue",
-    "vite.config.*",
-    "vitest.config.*"
-  ],
-  "compilerOptions": {
-    "baseUrl": ".",
-    "paths": {
-      "@/*": ["./src/*"]
-    }
-  }
-}`
-
-	// Generate env.d.ts
-	envDts := `/// <reference types="vite/client" />`
-
-	// Generate index.html
+	// TypeScript configs are written by the typescript feature
 	indexHtml := `<!DOCTYPE html>
 <html lang="en">
   <head>
@@ -685,23 +700,9 @@ func (p *VuePlugin) generateConfigFiles(ctx *tilocontext.ExecutionContext) error
   </head>
   <body>
     <div id="app"></div>
-    <script type="module" src="/src/main.ts"></script>
+    <script type="module" src="/src/main.` + selectedFeatures(ctx).ScriptExt() + `"></script>
   </body>
 </html>`
 
-	// Write config files
-	configs := map[string]string{
-		"tsconfig.json": tsConfig,
-		"src/env.d.ts":  envDts,
-		"index.html":    indexHtml,
-	}
-
-	for path, content := range configs {
-		fullPath := filepath.Join(ctx.ProjectPath, path)
-		if err := utils.WriteFile(fullPath, content); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return ctx.WriteFile("index.html", indexHtml)
 }
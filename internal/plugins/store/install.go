@@ -0,0 +1,336 @@
+package store
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ti-lo/tilokit/internal/core/archive"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/internal/plugins/external"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// PackageFile describes a plugin package. A package holding a single
+// executable or .wasm module doesn't need one.
+const PackageFile = "tilokit-plugin.yaml"
+
+// packageManifest is the content of PackageFile. The plugin's handshake
+// is authoritative; a name or version given here must agree with it.
+type packageManifest struct {
+	Name    string   `yaml:"name"`
+	Version string   `yaml:"version"`
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+}
+
+// pluginName matches names that can be used as a store directory. The
+// leading letter or digit keeps them apart from the .install-* staging
+// directories and .catalog-* temporaries.
+var pluginName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// storableName reports whether a plugin can be installed under name
+// without clobbering the store's own files
+func storableName(name string) bool {
+	// Compared without case so that case-insensitive filesystems can't
+	// rename a plugin onto the catalogue either
+	return pluginName.MatchString(name) && !strings.EqualFold(name, IndexFile)
+}
+
+// InstallOptions configures Install
+type InstallOptions struct {
+	// Plugin configures how the plugin is started to be checked
+	Plugin external.Options
+	// Reserved reports names that can't be installed, such as those of
+	// built-in plugins
+	Reserved func(name string) bool
+}
+
+// Install installs a plugin, replacing an installed one of the same name.
+// source is a local directory, a single executable or .wasm module, a tar,
+// tar.gz or zip archive, or a git repository URL; file:// repositories are
+// read in place, so installing from them works offline. The plugin is
+// started once to read its handshake and refused if it is incompatible.
+func (s *Store) Install(source string, opts InstallOptions) (Entry, error) {
+	if err := os.MkdirAll(s.dir, 0750); err != nil {
+		return Entry{}, errors.Wrap(err, "failed to create plugin store")
+	}
+	staging, err := os.MkdirTemp(s.dir, ".install-")
+	if err != nil {
+		return Entry{}, errors.Wrap(err, "failed to create plugin store")
+	}
+	defer os.RemoveAll(staging)
+
+	fetched := filepath.Join(staging, "package")
+	if err := fetch(source, fetched); err != nil {
+		return Entry{}, errors.Wrapf(err, "failed to fetch %s", source)
+	}
+	root := packageRoot(fetched)
+	pkg, err := readPackage(root)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	plugin, err := external.Start(filepath.Join(root, filepath.FromSlash(pkg.Command)), pkg.Args, opts.Plugin)
+	if err != nil {
+		return Entry{}, err
+	}
+	plugin.Stop()
+	manifest := registry.ManifestOf(plugin)
+	if err := registry.CheckPlugin(plugin); err != nil {
+		return Entry{}, err
+	}
+	switch {
+	case !storableName(manifest.Name):
+		return Entry{}, errors.Errorf("plugin name %q can't be installed; use letters, digits, dots, dashes and underscores", manifest.Name)
+	case pkg.Name != "" && pkg.Name != manifest.Name:
+		return Entry{}, errors.Errorf("%s names the plugin %s but it calls itself %s", PackageFile, pkg.Name, manifest.Name)
+	case pkg.Version != "" && pkg.Version != manifest.Version:
+		return Entry{}, errors.Errorf("%s gives version %s but the plugin reports %s", PackageFile, pkg.Version, manifest.Version)
+	case opts.Reserved != nil && opts.Reserved(manifest.Name):
+		return Entry{}, errors.Errorf("plugin %s is built in and can't be replaced", manifest.Name)
+	}
+
+	sum, err := Checksum(root)
+	if err != nil {
+		return Entry{}, err
+	}
+	if err := s.replace(manifest.Name, root, staging); err != nil {
+		return Entry{}, err
+	}
+
+	if abs, err := filepath.Abs(source); err == nil && !isGitURL(source) {
+		source = abs
+	}
+	entry := Entry{
+		Name:        manifest.Name,
+		Version:     manifest.Version,
+		Description: manifest.Description,
+		Source:      source,
+		Command:     pkg.Command,
+		Args:        pkg.Args,
+		Checksum:    sum,
+		Frameworks:  manifest.Frameworks,
+		BuildTools:  manifest.BuildTools,
+		Installed:   time.Now().UTC().Truncate(time.Second),
+	}
+	if err := s.put(entry); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// replace moves a checked package into the store in place of the
+// installed plugin of the same name, which is kept until the move worked
+func (s *Store) replace(name, root, staging string) error {
+	target := s.PluginDir(name)
+	previous := filepath.Join(staging, "previous")
+	hadPrevious := utils.DirExists(target)
+	if hadPrevious {
+		if err := os.Rename(target, previous); err != nil {
+			return errors.Wrapf(err, "failed to replace plugin %s", name)
+		}
+	}
+	if err := os.Rename(root, target); err != nil {
+		if hadPrevious {
+			_ = os.Rename(previous, target)
+		}
+		return errors.Wrapf(err, "failed to install plugin %s", name)
+	}
+	return nil
+}
+
+// fetch copies a plugin package from source into dst
+func fetch(source, dst string) error {
+	if isGitURL(source) {
+		if rest, ok := strings.CutPrefix(source, "file://"); ok {
+			return exportRepository(rest, dst)
+		}
+		return cloneRepository(source, dst)
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		if err := utils.CopyDir(source, dst); err != nil {
+			return err
+		}
+		return os.RemoveAll(filepath.Join(dst, ".git"))
+	}
+	if format, ok := archive.FormatOf(source); ok {
+		return archive.Extract(source, format, dst)
+	}
+
+	// A plugin that is a single executable or module
+	if !external.IsWASM(source) && info.Mode()&0111 == 0 {
+		return errors.New("not a directory, archive, git repository, executable or .wasm module")
+	}
+	if err := os.MkdirAll(dst, 0750); err != nil {
+		return err
+	}
+	perm := os.FileMode(0600)
+	if info.Mode()&0111 != 0 {
+		perm = 0750
+	}
+	return copyFile(source, filepath.Join(dst, filepath.Base(source)), perm)
+}
+
+// isGitURL reports whether source names a git repository rather than a
+// local path
+func isGitURL(source string) bool {
+	for _, prefix := range []string{"file://", "https://", "http://", "ssh://", "git://", "git@"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// exportRepository writes the files of the HEAD commit of a local
+// repository to dst. It reads the repository directly, without a git
+// binary or network.
+func exportRepository(dir, dst string) error {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to open git repository")
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return errors.Wrap(err, "repository has no commits")
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	files, err := commit.Files()
+	if err != nil {
+		return err
+	}
+	return files.ForEach(func(f *object.File) error {
+		var perm os.FileMode
+		switch f.Mode {
+		case filemode.Regular, filemode.Deprecated:
+			perm = 0600
+		case filemode.Executable:
+			perm = 0750
+		default:
+			// Links and submodules aren't part of a package
+			return nil
+		}
+
+		target, err := packagePath(dst, f.Name)
+		if err != nil {
+			return err
+		}
+		r, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return writeFile(target, r, perm)
+	})
+}
+
+// cloneRepository clones the default branch of a remote repository to dst,
+// without its history
+func cloneRepository(url, dst string) error {
+	if _, err := git.PlainClone(dst, false, &git.CloneOptions{URL: url, Depth: 1}); err != nil {
+		return errors.Wrap(err, "failed to clone repository")
+	}
+	return os.RemoveAll(filepath.Join(dst, ".git"))
+}
+
+// packageRoot returns the directory of a fetched package holding the
+// plugin, descending into the single top-level directory archives often
+// wrap their content in
+func packageRoot(dir string) string {
+	if utils.FileExists(filepath.Join(dir, PackageFile)) {
+		return dir
+	}
+	entries, err := os.ReadDir(dir)
+	if err == nil && len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name())
+	}
+	return dir
+}
+
+// readPackage reads the package manifest in root, or finds the package's
+// only executable or .wasm module when it has none
+func readPackage(root string) (*packageManifest, error) {
+	pkg := &packageManifest{}
+	data, err := os.ReadFile(filepath.Join(root, PackageFile))
+	if os.IsNotExist(err) {
+		commands, err := external.FindCommands(root)
+		if err != nil {
+			return nil, err
+		}
+		if len(commands) != 1 {
+			return nil, errors.Errorf("the package has no %s and %d executables or .wasm modules at its top; add a %s naming its command",
+				PackageFile, len(commands), PackageFile)
+		}
+		pkg.Command = filepath.Base(commands[0].Path)
+		return pkg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, pkg); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", PackageFile)
+	}
+	if pkg.Command == "" {
+		return nil, errors.Errorf("%s has no command", PackageFile)
+	}
+	if _, err := packagePath(root, pkg.Command); err != nil {
+		return nil, errors.Wrapf(err, "invalid command in %s", PackageFile)
+	}
+	pkg.Command = path.Clean(filepath.ToSlash(pkg.Command))
+	return pkg, nil
+}
+
+// packagePath returns where a slash-separated path inside a package is,
+// refusing paths that leave it
+func packagePath(root, name string) (string, error) {
+	clean := path.Clean(filepath.ToSlash(name))
+	if path.IsAbs(clean) || filepath.IsAbs(name) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", errors.Errorf("path %s is outside the package", name)
+	}
+	return filepath.Join(root, filepath.FromSlash(clean)), nil
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	// #nosec G304 - src is the plugin being installed
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeFile(dst, f, perm)
+}
+
+func writeFile(target string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
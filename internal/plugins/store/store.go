@@ -0,0 +1,265 @@
+// Package store installs external plugins into a local store under
+// ~/.tilokit/store and keeps a catalogue of them, so they run without
+// being copied to the plugin directory by hand. Each plugin lives in a
+// directory named after it; catalog.yaml records where it came from, its
+// version, what it supports and a checksum of its files.
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ti-lo/tilokit/internal/plugins/external"
+)
+
+// IndexFile is the catalogue in the store directory
+const IndexFile = "catalog.yaml"
+
+// catalogVersion is the format version of IndexFile
+const catalogVersion = 1
+
+// DefaultDir returns the store directory, ~/.tilokit/store
+func DefaultDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".tilokit", "store")
+}
+
+// Entry is an installed plugin in the catalogue
+type Entry struct {
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description,omitempty"`
+	// Source is what the plugin was installed from
+	Source string `yaml:"source"`
+	// Command is the plugin's executable or .wasm module, relative to its
+	// directory in the store
+	Command    string    `yaml:"command"`
+	Args       []string  `yaml:"args,omitempty"`
+	Checksum   string    `yaml:"checksum"`
+	Frameworks []string  `yaml:"frameworks,omitempty"`
+	BuildTools []string  `yaml:"build_tools,omitempty"`
+	Installed  time.Time `yaml:"installed"`
+}
+
+// catalog is the content of IndexFile
+type catalog struct {
+	Version int     `yaml:"version"`
+	Plugins []Entry `yaml:"plugins"`
+}
+
+// Store is a plugin store directory
+type Store struct {
+	dir string
+}
+
+// Open returns the store in dir, which is created on first install
+func Open(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the store directory
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// PluginDir returns the directory an installed plugin lives in
+func (s *Store) PluginDir(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+// List returns the installed plugins in name order. An empty or missing
+// store has none.
+func (s *Store) List() ([]Entry, error) {
+	c, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return c.Plugins, nil
+}
+
+// Get returns the catalogue entry of an installed plugin
+func (s *Store) Get(name string) (Entry, bool, error) {
+	entries, err := s.List()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for _, entry := range entries {
+		if entry.Name == name {
+			return entry, true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+// Remove uninstalls a plugin and returns its catalogue entry
+func (s *Store) Remove(name string) (Entry, error) {
+	c, err := s.read()
+	if err != nil {
+		return Entry{}, err
+	}
+	for i, entry := range c.Plugins {
+		if entry.Name != name {
+			continue
+		}
+		c.Plugins = append(c.Plugins[:i], c.Plugins[i+1:]...)
+		if err := s.write(c); err != nil {
+			return Entry{}, err
+		}
+		if err := os.RemoveAll(s.PluginDir(name)); err != nil {
+			return Entry{}, errors.Wrapf(err, "failed to remove plugin %s", name)
+		}
+		return entry, nil
+	}
+	return Entry{}, errors.Errorf("plugin %s is not installed; see --plugin-list", name)
+}
+
+// Commands returns how to start each installed plugin. A plugin whose
+// files changed since it was installed is left out and reported, so
+// nothing runs that wasn't checked at install time.
+func (s *Store) Commands() ([]external.Command, []error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var commands []external.Command
+	var errs []error
+	for _, entry := range entries {
+		dir := s.PluginDir(entry.Name)
+		sum, err := Checksum(dir)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "plugin %s is damaged; reinstall it with --plugin-install", entry.Name))
+			continue
+		}
+		if sum != entry.Checksum {
+			errs = append(errs, errors.Errorf("plugin %s changed since it was installed; reinstall it with --plugin-install", entry.Name))
+			continue
+		}
+		commands = append(commands, external.Command{Path: filepath.Join(dir, entry.Command), Args: entry.Args})
+	}
+	return commands, errs
+}
+
+// put adds or replaces an entry in the catalogue
+func (s *Store) put(entry Entry) error {
+	c, err := s.read()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range c.Plugins {
+		if c.Plugins[i].Name == entry.Name {
+			c.Plugins[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		c.Plugins = append(c.Plugins, entry)
+	}
+	return s.write(c)
+}
+
+func (s *Store) read() (*catalog, error) {
+	c := &catalog{Version: catalogVersion}
+	data, err := os.ReadFile(filepath.Join(s.dir, IndexFile))
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read plugin catalogue")
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, errors.Wrapf(err, "invalid plugin catalogue %s", filepath.Join(s.dir, IndexFile))
+	}
+	if c.Version > catalogVersion {
+		return nil, errors.Errorf("plugin catalogue %s was written by a newer TiLoKit; upgrade TiLoKit",
+			filepath.Join(s.dir, IndexFile))
+	}
+	return c, nil
+}
+
+// write saves the catalogue in name order, replacing the old one at once
+func (s *Store) write(c *catalog) error {
+	sort.Slice(c.Plugins, func(i, j int) bool {
+		return c.Plugins[i].Name < c.Plugins[j].Name
+	})
+	c.Version = catalogVersion
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".catalog-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, IndexFile)); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "failed to write plugin catalogue")
+	}
+	return nil
+}
+
+// Checksum returns the SHA-256 of a directory tree: the paths, execute
+// bits and contents of its files and the targets of its links
+func Checksum(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "link %s %s\n", filepath.ToSlash(rel), target)
+		case info.Mode().IsRegular():
+			fmt.Fprintf(h, "file %s %t %d\n", filepath.ToSlash(rel), info.Mode()&0111 != 0, info.Size())
+			// #nosec G304 - path comes from walking dir
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,233 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/ti-lo/tilokit/internal/core/archive"
+	"github.com/ti-lo/tilokit/internal/plugins/external"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// testPluginEnv makes the test binary answer the handshake as a plugin
+const testPluginEnv = "TILOKIT_STORE_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) != "" {
+		runTestPlugin()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runTestPlugin() {
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		var msg map[string]interface{}
+		if err := json.Unmarshal(in.Bytes(), &msg); err != nil {
+			os.Exit(1)
+		}
+		switch msg["method"] {
+		case external.MethodHandshake:
+			_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      msg["id"],
+				"result": external.Info{
+					ProtocolVersion: external.ProtocolVersion,
+					Name:            "acme",
+					Version:         "1.2.0",
+					Frameworks:      []string{"acme-service"},
+				},
+			})
+		case external.MethodShutdown:
+			return
+		}
+	}
+}
+
+// writePackage writes a plugin package to dir: a copy of the test binary
+// and a package manifest naming it
+func writePackage(t *testing.T, dir, manifest string) {
+	t.Helper()
+	t.Setenv(testPluginEnv, "1")
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyFile(os.Args[0], filepath.Join(dir, "bin", "acme"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, PackageFile), []byte(manifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const acmeManifest = "name: acme\nversion: 1.2.0\ncommand: bin/acme\n"
+
+func TestInstallListAndRemove(t *testing.T) {
+	pkg := t.TempDir()
+	writePackage(t, pkg, acmeManifest)
+	s := Open(filepath.Join(t.TempDir(), "store"))
+
+	entry, err := s.Install(pkg, InstallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Name != "acme" || entry.Version != "1.2.0" || entry.Frameworks[0] != "acme-service" || entry.Source != pkg {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	if !strings.HasPrefix(entry.Checksum, "sha256:") {
+		t.Errorf("Expected a checksum, got %q", entry.Checksum)
+	}
+
+	// The catalogue is read back from disk
+	entries, err := Open(s.Dir()).List()
+	if err != nil || len(entries) != 1 || entries[0].Checksum != entry.Checksum {
+		t.Fatalf("Expected the plugin in the catalogue, got %+v (%v)", entries, err)
+	}
+	commands, errs := s.Commands()
+	if len(errs) != 0 || len(commands) != 1 || commands[0].Path != filepath.Join(s.Dir(), "acme", "bin", "acme") {
+		t.Errorf("Expected the installed command, got %v, %v", commands, errs)
+	}
+
+	// Installing again replaces the plugin
+	if _, err := s.Install(pkg, InstallOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := s.List(); len(entries) != 1 {
+		t.Errorf("Expected the reinstalled plugin once, got %+v", entries)
+	}
+
+	if err := os.WriteFile(filepath.Join(s.PluginDir("acme"), "extra"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if commands, errs := s.Commands(); len(commands) != 0 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "changed since it was installed") {
+		t.Errorf("Expected a modified plugin to be left out, got %v, %v", commands, errs)
+	}
+
+	if _, err := s.Remove("acme"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := s.List(); len(entries) != 0 || utils.DirExists(s.PluginDir("acme")) {
+		t.Errorf("Expected the plugin to be removed, got %+v", entries)
+	}
+	if _, err := s.Remove("acme"); err == nil {
+		t.Error("Expected removing a plugin twice to fail")
+	}
+}
+
+func TestInstallFromGitRepository(t *testing.T) {
+	dir := t.TempDir()
+	writePackage(t, dir, acmeManifest)
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	if _, err := worktree.Commit("Add plugin", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+	// Uncommitted changes aren't installed
+	if err := os.WriteFile(filepath.Join(dir, "scratch"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := Open(t.TempDir())
+	entry, err := s.Install("file://"+dir, InstallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Source != "file://"+dir {
+		t.Errorf("Expected the URL as source, got %s", entry.Source)
+	}
+	if utils.FileExists(filepath.Join(s.PluginDir("acme"), "scratch")) || utils.DirExists(filepath.Join(s.PluginDir("acme"), ".git")) {
+		t.Error("Expected only the committed files to be installed")
+	}
+	info, err := os.Stat(filepath.Join(s.PluginDir("acme"), "bin", "acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("Expected the command to stay executable, got %v", info.Mode())
+	}
+	if info, err := os.Stat(filepath.Join(s.PluginDir("acme"), PackageFile)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the manifest to be private to its owner, got %v", err)
+	}
+}
+
+func TestInstallFromArchive(t *testing.T) {
+	dir := t.TempDir()
+	writePackage(t, filepath.Join(dir, "acme"), acmeManifest)
+	src := filepath.Join(dir, "acme-1.2.0.tar.gz")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = archive.Write(f, archive.TarGz, utils.NewOSFS(), filepath.Join(dir, "acme"), "acme-1.2.0", time.Now())
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := Open(t.TempDir())
+	if _, err := s.Install(src, InstallOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !utils.FileExists(filepath.Join(s.PluginDir("acme"), PackageFile)) {
+		t.Error("Expected the archive's top-level directory to be the package")
+	}
+}
+
+func TestInstallRejectsConflicts(t *testing.T) {
+	s := Open(t.TempDir())
+
+	mismatched := t.TempDir()
+	writePackage(t, mismatched, "name: acme\nversion: 2.0.0\ncommand: bin/acme\n")
+	if _, err := s.Install(mismatched, InstallOptions{}); err == nil || !strings.Contains(err.Error(), "gives version 2.0.0 but the plugin reports 1.2.0") {
+		t.Errorf("Expected the version mismatch to be refused, got %v", err)
+	}
+
+	pkg := t.TempDir()
+	writePackage(t, pkg, acmeManifest)
+	reserved := func(name string) bool { return name == "acme" }
+	if _, err := s.Install(pkg, InstallOptions{Reserved: reserved}); err == nil || !strings.Contains(err.Error(), "built in") {
+		t.Errorf("Expected a built-in name to be refused, got %v", err)
+	}
+
+	escaping := t.TempDir()
+	writePackage(t, escaping, "command: ../acme\n")
+	if _, err := s.Install(escaping, InstallOptions{}); err == nil || !strings.Contains(err.Error(), "outside the package") {
+		t.Errorf("Expected a command outside the package to be refused, got %v", err)
+	}
+
+	if entries, _ := s.List(); len(entries) != 0 {
+		t.Errorf("Expected nothing installed, got %+v", entries)
+	}
+}
+
+func TestStorableName(t *testing.T) {
+	for _, name := range []string{"acme", "acme-service", "acme.v2", "2fa_tools"} {
+		if !storableName(name) {
+			t.Errorf("Expected %q to be storable", name)
+		}
+	}
+	for _, name := range []string{"", IndexFile, "Catalog.YAML", ".hidden", ".install-123", ".catalog-456", "../acme", "acme/bin"} {
+		if storableName(name) {
+			t.Errorf("Expected %q to be refused", name)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// alphaNum is the alphabet used by randAlphaNum
+const alphaNum = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// StaticFuncs returns the template functions that don't depend on a run:
+// case conversion, pluralisation, indentation, defaults, serialisation and
+// semver comparison. Missing variables are an error, so optional values are
+// read with index, e.g. {{ default "8080" (index . "port") }}.
+func StaticFuncs() template.FuncMap {
+	return template.FuncMap{
+		// Case conversion
+		"snakeCase":     utils.ToSnakeCase,
+		"camelCase":     utils.ToCamelCase,
+		"pascalCase":    utils.ToPascalCase,
+		"kebabCase":     utils.ToKebabCase,
+		"screamingCase": utils.ToScreamingSnakeCase,
+		"lower":         strings.ToLower,
+		"upper":         strings.ToUpper,
+		"trim":          strings.TrimSpace,
+		"replace":       func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"plural":        plural,
+
+		// Layout
+		"indent":  indent,
+		"nindent": func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+		"join":    func(sep string, items []string) string { return strings.Join(items, sep) },
+		"quote":   func(s string) string { return fmt.Sprintf("%q", s) },
+
+		// Defaults and validation
+		"default":  defaultValue,
+		"empty":    isEmpty,
+		"required": required,
+		"hasKey": func(m map[string]interface{}, key string) bool {
+			_, exists := m[key]
+			return exists
+		},
+
+		// Serialisation
+		"toJson":       toJSON,
+		"toPrettyJson": toPrettyJSON,
+		"toYaml":       toYAML,
+		"toToml":       toTOML,
+
+		// Versions and dates
+		"semverCompare": SemverCompare,
+		"date":          func(layout string, t time.Time) string { return t.Format(layout) },
+	}
+}
+
+// funcMap builds the functions for one template. Run-dependent functions
+// read from ctx, and include renders other templates associated with tmpl.
+// Plugin functions from ctx override the built-ins, and functions added to
+// the engine override both.
+func (te *TemplateEngine) funcMap(ctx *tilocontext.ExecutionContext, tmpl *template.Template) template.FuncMap {
+	funcs := StaticFuncs()
+
+	funcs["now"] = func() time.Time { return ctx.StartTime }
+	funcs["feature"] = func(name string) bool { return utils.Contains(ctx.Config.Features, name) }
+	funcs["uuid"] = func() (string, error) { return newUUID(ctx.Random()) }
+	funcs["secret"] = func(length int) (string, error) { return newSecret(ctx.Random(), length) }
+	funcs["randAlphaNum"] = func(length int) (string, error) { return randAlphaNum(ctx.Random(), length) }
+	funcs["include"] = func(name string, data interface{}) (string, error) {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	for name, fn := range ctx.TemplateFuncs {
+		funcs[name] = fn
+	}
+	for name, fn := range te.funcs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// defaultValue returns value unless it is empty, in which case it returns
+// fallback. Arguments follow pipeline order: {{ .x | default "y" }}.
+func defaultValue(fallback interface{}, value ...interface{}) interface{} {
+	if len(value) == 0 || isEmpty(value[0]) {
+		return fallback
+	}
+	return value[0]
+}
+
+func required(message string, value interface{}) (interface{}, error) {
+	if isEmpty(value) {
+		return nil, errors.New(message)
+	}
+	return value, nil
+}
+
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+func toJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+func toPrettyJSON(value interface{}) (string, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	return string(data), err
+}
+
+func toYAML(value interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func toTOML(value interface{}) (string, error) {
+	data, err := toml.Marshal(value)
+	return strings.TrimSuffix(string(data), "\n"), err
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID(random io.Reader) (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(random, b[:]); err != nil {
+		return "", errors.Wrap(err, "failed to generate uuid")
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(b[:])
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:]), nil
+}
+
+// newSecret returns length random bytes encoded as unpadded URL-safe base64
+func newSecret(random io.Reader, length int) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("secret length must be positive, got %d", length)
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(random, b); err != nil {
+		return "", errors.Wrap(err, "failed to generate secret")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func randAlphaNum(random io.Reader, length int) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("length must be positive, got %d", length)
+	}
+	out := make([]byte, 0, length)
+	var b [1]byte
+	for len(out) < length {
+		if _, err := io.ReadFull(random, b[:]); err != nil {
+			return "", errors.Wrap(err, "failed to generate random string")
+		}
+		// Reject values that would bias the modulo
+		if int(b[0]) >= 256-256%len(alphaNum) {
+			continue
+		}
+		out = append(out, alphaNum[int(b[0])%len(alphaNum)])
+	}
+	return string(out), nil
+}
+
+// irregularPlurals covers common English nouns that don't follow the rules
+var irregularPlurals = map[string]string{
+	"child":  "children",
+	"foot":   "feet",
+	"goose":  "geese",
+	"man":    "men",
+	"mouse":  "mice",
+	"person": "people",
+	"tooth":  "teeth",
+	"woman":  "women",
+}
+
+// uncountables are nouns whose plural is the same word
+var uncountables = map[string]bool{
+	"data": true, "equipment": true, "fish": true, "information": true,
+	"metadata": true, "news": true, "series": true, "sheep": true, "species": true,
+}
+
+// plural returns the English plural of a noun, keeping its capitalisation.
+// Only the last word of an identifier is changed: userProfile -> userProfiles.
+func plural(word string) string {
+	words := utils.SplitWords(word)
+	if len(words) == 0 {
+		return word
+	}
+	last := words[len(words)-1]
+	prefix := word[:strings.LastIndex(word, last)]
+	return prefix + matchCase(last, pluralWord(strings.ToLower(last)))
+}
+
+func pluralWord(word string) string {
+	if uncountables[word] {
+		return word
+	}
+	if irregular, exists := irregularPlurals[word]; exists {
+		return irregular
+	}
+
+	switch {
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	case strings.HasSuffix(word, "fe"):
+		return strings.TrimSuffix(word, "fe") + "ves"
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return strings.TrimSuffix(word, "y") + "ies"
+	default:
+		return word + "s"
+	}
+}
+
+// matchCase applies the capitalisation of original to word
+func matchCase(original, word string) string {
+	if strings.ToUpper(original) == original && strings.ToLower(original) != original {
+		return strings.ToUpper(word)
+	}
+	if r := []rune(original); len(r) > 0 && unicode.IsUpper(r[0]) {
+		w := []rune(word)
+		w[0] = unicode.ToUpper(w[0])
+		return string(w)
+	}
+	return word
+}
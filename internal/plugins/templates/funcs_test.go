@@ -0,0 +1,102 @@
+package templates
+
+import (
+	"regexp"
+	"testing"
+	"text/template"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+)
+
+func render(t *testing.T, te *TemplateEngine, ctx *tilocontext.ExecutionContext, content string) string {
+	t.Helper()
+	result, err := te.ProcessTemplate(content, ctx)
+	if err != nil {
+		t.Fatalf("Expected %q to render, got: %v", content, err)
+	}
+	return result
+}
+
+func TestStaticFuncs(t *testing.T) {
+	ctx := newSampleContext()
+	ctx.Variables["entity"] = "userProfile"
+	ctx.Variables["config"] = map[string]interface{}{"name": "api", "port": 8080}
+
+	tests := map[string]string{
+		`{{ snakeCase "myHTTPServer" }}`:            "my_http_server",
+		`{{ camelCase "sample-app" }}`:              "sampleApp",
+		`{{ pascalCase .project_name }}`:            "SampleApp",
+		`{{ kebabCase "SampleApp" }}`:               "sample-app",
+		`{{ screamingCase .project_name }}`:         "SAMPLE_APP",
+		`{{ plural .entity }}`:                      "userProfiles",
+		`{{ plural "Category" }}`:                   "Categories",
+		`{{ plural "person" }}`:                     "people",
+		`{{ plural "box" }}`:                        "boxes",
+		`{{ "a\nb" | indent 2 }}`:                   "  a\n  b",
+		`x:{{ "a" | nindent 2 }}`:                   "x:\n  a",
+		`{{ index . "missing" | default "none" }}`:  "none",
+		`{{ .framework | default "none" }}`:         "sample",
+		`{{ toJson .config }}`:                      `{"name":"api","port":8080}`,
+		`{{ toYaml .config }}`:                      "name: api\nport: 8080",
+		`{{ toToml .config }}`:                      "name = 'api'\nport = 8080",
+		`{{ semverCompare ">=1.2, <2" "v1.10.0" }}`: "true",
+		`{{ semverCompare "^2" "1.9.9" }}`:          "false",
+		`{{ semverCompare "<1.0.0" "1.0.0-rc.1" }}`: "true",
+		`{{ date "2006" now }}`:                     ctx.StartTime.Format("2006"),
+	}
+
+	te := NewTemplateEngine()
+	for content, want := range tests {
+		if got := render(t, te, ctx, content); got != want {
+			t.Errorf("%s: expected %q, got %q", content, want, got)
+		}
+	}
+}
+
+func TestRequiredFails(t *testing.T) {
+	ctx := newSampleContext()
+	_, err := NewTemplateEngine().ProcessTemplate(`{{ index . "db" | required "db is required" }}`, ctx)
+	if err == nil {
+		t.Fatal("Expected required to fail on a missing value")
+	}
+}
+
+func TestRandomFuncsAreSeeded(t *testing.T) {
+	content := `{{ uuid }} {{ secret 16 }} {{ randAlphaNum 12 }}`
+
+	// The random source is chosen when the context is created
+	seeded := func(seed int64) *tilocontext.ExecutionContext {
+		config := newSampleContext().Config
+		config.Seed = seed
+		return tilocontext.NewExecutionContext(config)
+	}
+
+	a := render(t, NewTemplateEngine(), seeded(42), content)
+	b := render(t, NewTemplateEngine(), seeded(42), content)
+	if a != b {
+		t.Fatalf("Expected identical output for the same seed, got %q and %q", a, b)
+	}
+
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12} [A-Za-z0-9_-]{22} [A-Za-z0-9]{12}$`)
+	if !pattern.MatchString(a) {
+		t.Errorf("Unexpected random output format: %q", a)
+	}
+
+	unseeded := render(t, NewTemplateEngine(), newSampleContext(), content)
+	if unseeded == a {
+		t.Error("Expected unseeded output to differ")
+	}
+}
+
+func TestIncludeAndExtensionFuncs(t *testing.T) {
+	ctx := newSampleContext()
+	ctx.TemplateFuncs["shout"] = func(s string) string { return s + "!" }
+
+	te := NewTemplateEngine().Funcs(template.FuncMap{"greet": func(s string) string { return "hello " + s }})
+	te.AddPartial("_helpers.tmpl", `{{ define "banner" }}# {{ .project_name | upper }}{{ end }}`)
+
+	got := render(t, te, ctx, `{{ include "banner" . }} {{ shout "go" }} {{ greet "you" }}`)
+	if want := "# SAMPLE-APP go! hello you"; got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,147 @@
+package templates
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestName is the optional manifest at the root of a template tree. It
+// is read by the engine and never written to the generated project.
+const ManifestName = "tilokit.template.yaml"
+
+// frontMatterStart opens a front-matter block on the first line of a .tmpl
+// file. A plain "---" is not used because YAML templates start with it.
+const frontMatterStart = "---tilokit"
+
+// frontMatterEnd closes a front-matter block
+const frontMatterEnd = "---"
+
+// Manifest describes a template tree
+type Manifest struct {
+	// Questions are asked in order before rendering; answers become
+	// template variables
+	Questions []Question `yaml:"questions"`
+	// Files holds inclusion rules, applied in order to template paths
+	Files []FileRule `yaml:"files"`
+}
+
+// FileRule includes or excludes the template files matching Pattern.
+// Pattern is matched against the template-relative path before rendering
+// and supports path.Match syntax plus "**" for any number of directories.
+//
+// When is a template condition such as `.use_docker` or `feature "docker"`.
+// An include rule (the default) skips matching files when its condition is
+// false; an exclude rule skips them when its condition is true or empty.
+// Excluding a directory skips everything below it.
+type FileRule struct {
+	Pattern string `yaml:"pattern"`
+	When    string `yaml:"when,omitempty"`
+	Exclude bool   `yaml:"exclude,omitempty"`
+}
+
+// FrontMatter holds the per-file settings a template may declare in a
+// leading ---tilokit block
+type FrontMatter struct {
+	// When is a condition the file is only rendered under, as in FileRule
+	When string `yaml:"when,omitempty"`
+}
+
+// LoadManifest reads the manifest at the root of a template tree. A tree
+// without a manifest gets an empty one.
+func LoadManifest(fsys fs.FS, root string) (*Manifest, error) {
+	manifestPath := path.Join(root, ManifestName)
+	data, err := fs.ReadFile(fsys, manifestPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", manifestPath)
+	}
+
+	manifest := &Manifest{}
+	if err := decodeStrict(data, manifest); err != nil {
+		return nil, errors.Wrapf(err, "invalid template manifest %s", manifestPath)
+	}
+
+	if err := checkQuestions(manifest.Questions); err != nil {
+		return nil, errors.Wrapf(err, "invalid template manifest %s", manifestPath)
+	}
+	for i, rule := range manifest.Files {
+		if rule.Pattern == "" {
+			return nil, errors.Errorf("invalid template manifest %s: files[%d] has no pattern", manifestPath, i)
+		}
+		if _, err := path.Match(strings.ReplaceAll(rule.Pattern, "**", "*"), ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid template manifest %s: bad pattern %q", manifestPath, rule.Pattern)
+		}
+	}
+	return manifest, nil
+}
+
+// splitFrontMatter separates a leading ---tilokit block from the template
+// body. Content without front-matter is returned unchanged.
+func splitFrontMatter(content string) (*FrontMatter, string, error) {
+	firstLine, rest, found := strings.Cut(content, "\n")
+	if !found || strings.TrimRight(firstLine, "\r ") != frontMatterStart {
+		return &FrontMatter{}, content, nil
+	}
+
+	var header strings.Builder
+	for {
+		line, remaining, more := strings.Cut(rest, "\n")
+		if strings.TrimRight(line, "\r ") == frontMatterEnd {
+			matter := &FrontMatter{}
+			if err := decodeStrict([]byte(header.String()), matter); err != nil {
+				return nil, "", errors.Wrap(err, "invalid front-matter")
+			}
+			return matter, remaining, nil
+		}
+		if !more {
+			return nil, "", errors.New("front-matter is not closed with ---")
+		}
+		header.WriteString(line + "\n")
+		rest = remaining
+	}
+}
+
+// decodeStrict decodes YAML and rejects unknown keys
+func decodeStrict(data []byte, out interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// matchGlob matches a slash-separated path against a pattern in which "**"
+// stands for zero or more whole path segments
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchSegments(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,285 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+// QuestionType is the kind of answer a question expects
+type QuestionType string
+
+// Supported question types
+const (
+	QuestionString      QuestionType = "string"
+	QuestionBool        QuestionType = "bool"
+	QuestionInt         QuestionType = "int"
+	QuestionChoice      QuestionType = "choice"
+	QuestionMultiChoice QuestionType = "multichoice"
+)
+
+// identifier matches valid question names, which become template variables
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Question is a prompt declared in a template manifest. Its answer is
+// stored in the template variables under Name.
+type Question struct {
+	Name    string       `yaml:"name" json:"name"`
+	Type    QuestionType `yaml:"type,omitempty" json:"type"`
+	Message string       `yaml:"message,omitempty" json:"message,omitempty"`
+	Help    string       `yaml:"help,omitempty" json:"help,omitempty"`
+	// Default may be a template, e.g. "{{ snakeCase .project_name }}"
+	Default interface{} `yaml:"default,omitempty" json:"default,omitempty"`
+	Choices []string    `yaml:"choices,omitempty" json:"choices,omitempty"`
+	// Validate is a regular expression string answers must match
+	Validate string `yaml:"validate,omitempty" json:"validate,omitempty"`
+	// When is a condition on earlier answers. When it is false the question
+	// is skipped and its variable holds the type's zero value, so templates
+	// can still refer to it.
+	When     string `yaml:"when,omitempty" json:"when,omitempty"`
+	Required bool   `yaml:"required,omitempty" json:"required,omitempty"`
+}
+
+// Asker asks a single question interactively and returns the raw answer.
+// def is the question's rendered default, or nil.
+type Asker func(q Question, def interface{}) (interface{}, error)
+
+// MissingAnswersError lists the required questions that have no answer
+type MissingAnswersError struct {
+	Questions []Question
+}
+
+func (e *MissingAnswersError) Error() string {
+	names := make([]string, len(e.Questions))
+	for i, q := range e.Questions {
+		names[i] = q.Name
+	}
+	return fmt.Sprintf("missing answers for required questions: %s", strings.Join(names, ", "))
+}
+
+// Prompt returns the text shown when asking the question
+func (q Question) Prompt() string {
+	if q.Message != "" {
+		return q.Message
+	}
+	return q.Name
+}
+
+// check validates the question definition itself
+func (q Question) check() error {
+	if !identifier.MatchString(q.Name) {
+		return errors.Errorf("question name %q must be a valid identifier", q.Name)
+	}
+	switch q.Type {
+	case QuestionString, QuestionBool, QuestionInt:
+	case QuestionChoice, QuestionMultiChoice:
+		if len(q.Choices) == 0 {
+			return errors.Errorf("question %s of type %s needs choices", q.Name, q.Type)
+		}
+	default:
+		return errors.Errorf("question %s has unknown type %q", q.Name, q.Type)
+	}
+	if q.Validate != "" {
+		if _, err := regexp.Compile(q.Validate); err != nil {
+			return errors.Wrapf(err, "question %s has an invalid validate pattern", q.Name)
+		}
+	}
+	if q.Default != nil && !isTemplated(q.Default) {
+		if _, err := q.Coerce(q.Default); err != nil {
+			return errors.Wrapf(err, "question %s has an invalid default", q.Name)
+		}
+	}
+	return nil
+}
+
+// Coerce converts a raw answer, such as a string from a flag or a YAML
+// value, to the question's type and validates it
+func (q Question) Coerce(raw interface{}) (interface{}, error) {
+	switch q.Type {
+	case QuestionBool:
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "yes", "y", "1", "on":
+				return true, nil
+			case "false", "no", "n", "0", "off":
+				return false, nil
+			}
+		}
+		return nil, errors.Errorf("%s expects yes or no, got %v", q.Name, raw)
+
+	case QuestionInt:
+		switch v := raw.(type) {
+		case int:
+			return v, nil
+		case int64:
+			return int(v), nil
+		case float64:
+			if v == float64(int(v)) {
+				return int(v), nil
+			}
+		case string:
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return n, nil
+			}
+		}
+		return nil, errors.Errorf("%s expects a whole number, got %v", q.Name, raw)
+
+	case QuestionMultiChoice:
+		var values []string
+		switch v := raw.(type) {
+		case []string:
+			values = v
+		case []interface{}:
+			for _, item := range v {
+				values = append(values, fmt.Sprint(item))
+			}
+		case string:
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+		default:
+			return nil, errors.Errorf("%s expects a list, got %v", q.Name, raw)
+		}
+		for _, value := range values {
+			if !utils.Contains(q.Choices, value) {
+				return nil, errors.Errorf("%s: %q is not one of %s", q.Name, value, strings.Join(q.Choices, ", "))
+			}
+		}
+		if values == nil {
+			values = []string{}
+		}
+		return values, nil
+
+	default:
+		value := fmt.Sprint(raw)
+		if q.Type == QuestionChoice && !utils.Contains(q.Choices, value) {
+			return nil, errors.Errorf("%s: %q is not one of %s", q.Name, value, strings.Join(q.Choices, ", "))
+		}
+		if q.Validate != "" && !regexp.MustCompile(q.Validate).MatchString(value) {
+			return nil, errors.Errorf("%s: %q does not match %s", q.Name, value, q.Validate)
+		}
+		if q.Required && value == "" {
+			return nil, errors.Errorf("%s is required", q.Name)
+		}
+		return value, nil
+	}
+}
+
+// Answer resolves every question in order and stores the answers in
+// ctx.Variables, so later defaults and when conditions can use them.
+//
+// Answers already present in ctx.Variables, e.g. from flags or a values
+// file, are validated and kept. The rest are asked with ask; when ask is nil
+// they take their default. Required questions left without an answer are
+// reported together in a MissingAnswersError.
+func (te *TemplateEngine) Answer(questions []Question, ctx *tilocontext.ExecutionContext, ask Asker) (map[string]interface{}, error) {
+	answers := make(map[string]interface{})
+	var missing []Question
+
+	for _, q := range questions {
+		if q.When != "" {
+			ok, err := te.Condition(q.When, ctx)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid when condition for question %s", q.Name)
+			}
+			if !ok {
+				if _, provided := ctx.Variables[q.Name]; !provided {
+					ctx.SetVariable(q.Name, zeroAnswer(q.Type))
+				}
+				continue
+			}
+		}
+
+		def, err := te.renderDefault(q, ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, provided := ctx.Variables[q.Name]
+		switch {
+		case provided:
+		case ask != nil:
+			if raw, err = ask(q, def); err != nil {
+				return nil, err
+			}
+		case def != nil:
+			raw = def
+		case q.Required:
+			missing = append(missing, q)
+			continue
+		default:
+			raw = zeroAnswer(q.Type)
+		}
+
+		value, err := q.Coerce(raw)
+		if err != nil {
+			return nil, err
+		}
+		answers[q.Name] = value
+		ctx.SetVariable(q.Name, value)
+	}
+
+	if len(missing) > 0 {
+		return answers, &MissingAnswersError{Questions: missing}
+	}
+	return answers, nil
+}
+
+// renderDefault renders a templated default against the answers so far
+func (te *TemplateEngine) renderDefault(q Question, ctx *tilocontext.ExecutionContext) (interface{}, error) {
+	if !isTemplated(q.Default) {
+		return q.Default, nil
+	}
+	rendered, err := te.ProcessTemplate(q.Default.(string), ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render default for question %s", q.Name)
+	}
+	return rendered, nil
+}
+
+func isTemplated(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.Contains(s, "{{")
+}
+
+func zeroAnswer(t QuestionType) interface{} {
+	switch t {
+	case QuestionBool:
+		return false
+	case QuestionInt:
+		return 0
+	case QuestionMultiChoice:
+		return []string{}
+	default:
+		return ""
+	}
+}
+
+// checkQuestions validates the questions of a manifest
+func checkQuestions(questions []Question) error {
+	seen := make(map[string]bool)
+	for i := range questions {
+		if questions[i].Type == "" {
+			questions[i].Type = QuestionString
+		}
+		if err := questions[i].check(); err != nil {
+			return err
+		}
+		if seen[questions[i].Name] {
+			return errors.Errorf("question %s is declared twice", questions[i].Name)
+		}
+		seen[questions[i].Name] = true
+	}
+	return nil
+}
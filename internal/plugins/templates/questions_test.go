@@ -0,0 +1,124 @@
+package templates
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestQuestionCoerce(t *testing.T) {
+	tests := []struct {
+		question Question
+		raw      interface{}
+		want     interface{}
+		wantErr  bool
+	}{
+		{Question{Name: "debug", Type: QuestionBool}, "yes", true, false},
+		{Question{Name: "debug", Type: QuestionBool}, false, false, false},
+		{Question{Name: "debug", Type: QuestionBool}, "maybe", nil, true},
+		{Question{Name: "port", Type: QuestionInt}, "8080", 8080, false},
+		{Question{Name: "port", Type: QuestionInt}, float64(3000), 3000, false},
+		{Question{Name: "port", Type: QuestionInt}, "80.5", nil, true},
+		{Question{Name: "db", Type: QuestionChoice, Choices: []string{"postgresql", "sqlite"}}, "sqlite", "sqlite", false},
+		{Question{Name: "db", Type: QuestionChoice, Choices: []string{"postgresql", "sqlite"}}, "mysql", nil, true},
+		{Question{Name: "extras", Type: QuestionMultiChoice, Choices: []string{"a", "b"}}, "a, b", []string{"a", "b"}, false},
+		{Question{Name: "extras", Type: QuestionMultiChoice, Choices: []string{"a", "b"}}, []interface{}{"c"}, nil, true},
+		{Question{Name: "slug", Type: QuestionString, Validate: `^[a-z]+$`}, "app", "app", false},
+		{Question{Name: "slug", Type: QuestionString, Validate: `^[a-z]+$`}, "App", nil, true},
+		{Question{Name: "author", Type: QuestionString, Required: true}, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.question.Coerce(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Coerce(%s, %v) error = %v, wantErr %v", tt.question.Name, tt.raw, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Coerce(%s, %v) = %#v, want %#v", tt.question.Name, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestAnswerUsesProvidedAskedAndDefaultValues(t *testing.T) {
+	questions := []Question{
+		{Name: "module", Type: QuestionString, Default: "{{ snakeCase .project_name }}"},
+		{Name: "database", Type: QuestionChoice, Choices: []string{"postgresql", "sqlite"}, Default: "postgresql"},
+		{Name: "use_pool", Type: QuestionBool, Default: true, When: `eq .database "postgresql"`},
+		{Name: "port", Type: QuestionInt, Default: 8000},
+	}
+
+	ctx := newSampleContext()
+	ctx.SetVariable("database", "sqlite")
+
+	var asked []string
+	ask := func(q Question, def interface{}) (interface{}, error) {
+		asked = append(asked, q.Name)
+		if q.Name == "port" {
+			return "9000", nil
+		}
+		return def, nil
+	}
+
+	answers, err := NewTemplateEngine().Answer(questions, ctx, ask)
+	if err != nil {
+		t.Fatalf("Expected answers, got: %v", err)
+	}
+
+	if answers["module"] != "sample_app" {
+		t.Errorf("Expected templated default sample_app, got %v", answers["module"])
+	}
+	if answers["database"] != "sqlite" {
+		t.Errorf("Expected provided answer to be kept, got %v", answers["database"])
+	}
+	if ctx.Variables["use_pool"] != false {
+		t.Errorf("Expected skipped question to be false, got %v", ctx.Variables["use_pool"])
+	}
+	if answers["port"] != 9000 {
+		t.Errorf("Expected asked answer to be coerced to 9000, got %#v", answers["port"])
+	}
+	if want := []string{"module", "port"}; !reflect.DeepEqual(asked, want) {
+		t.Errorf("Expected to ask %v, asked %v", want, asked)
+	}
+}
+
+func TestAnswerReportsEveryMissingRequiredQuestion(t *testing.T) {
+	questions := []Question{
+		{Name: "author", Type: QuestionString, Required: true},
+		{Name: "license", Type: QuestionString, Default: "MIT"},
+		{Name: "email", Type: QuestionString, Required: true},
+	}
+
+	_, err := NewTemplateEngine().Answer(questions, newSampleContext(), nil)
+
+	var missing *MissingAnswersError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected MissingAnswersError, got: %v", err)
+	}
+	if len(missing.Questions) != 2 || !strings.Contains(err.Error(), "author, email") {
+		t.Errorf("Expected author and email to be reported, got: %v", err)
+	}
+}
+
+func TestLoadManifestRejectsInvalidQuestions(t *testing.T) {
+	tests := map[string]string{
+		"bad name":       "questions:\n  - name: my-var\n",
+		"unknown type":   "questions:\n  - name: x\n    type: float\n",
+		"no choices":     "questions:\n  - name: x\n    type: choice\n",
+		"bad pattern":    "questions:\n  - name: x\n    validate: \"[\"\n",
+		"bad default":    "questions:\n  - name: x\n    type: int\n    default: many\n",
+		"duplicate name": "questions:\n  - name: x\n  - name: x\n",
+		"unknown field":  "questions:\n  - name: x\n    prompt: X?\n",
+	}
+
+	for name, manifest := range tests {
+		t.Run(name, func(t *testing.T) {
+			fsys := fstest.MapFS{ManifestName: {Data: []byte(manifest)}}
+			if _, err := LoadManifest(fsys, "."); err == nil {
+				t.Error("Expected manifest to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package templates
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	shipped "github.com/ti-lo/tilokit/templates"
+)
+
+// revisionHash matches the hash of a revision returned by Revision
+var revisionHash = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// DefaultRevisionDir returns the template revision cache directory,
+// ~/.tilokit/cache/templates
+func DefaultRevisionDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".tilokit", "cache", "templates")
+}
+
+// Revisions keeps copies of shipped template trees by revision, so a
+// project can be rendered with the templates it was generated from after
+// TiLoKit ships newer ones. Each tree lives in <name>/<revision hash>.
+type Revisions struct {
+	dir string
+}
+
+// OpenRevisions opens the revision cache in dir, which is created on the
+// first Save
+func OpenRevisions(dir string) *Revisions {
+	return &Revisions{dir: dir}
+}
+
+// Save copies the shipped tree of the template name into the cache, unless
+// its revision is there already
+func (r *Revisions) Save(name string) error {
+	if _, err := fs.Stat(shipped.FS, name); err != nil {
+		return errors.Wrapf(err, "unknown template %s", name)
+	}
+	revision, err := Revision(shipped.FS, name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to hash template %s", name)
+	}
+	target, _ := r.treeDir(name, revision)
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+
+	parent := filepath.Dir(target)
+	if err := os.MkdirAll(parent, 0750); err != nil {
+		return errors.Wrap(err, "failed to create template cache")
+	}
+	staging, err := os.MkdirTemp(parent, ".save-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create template cache")
+	}
+	defer os.RemoveAll(staging)
+
+	err = fs.WalkDir(shipped.FS, name, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(staging, filepath.FromSlash(strings.TrimPrefix(strings.TrimPrefix(filePath, name), "/")))
+		if entry.IsDir() {
+			return os.MkdirAll(dest, 0750)
+		}
+		content, err := fs.ReadFile(shipped.FS, filePath)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dest, content, 0600)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to cache template %s", name)
+	}
+
+	// Another run may have saved the same revision meanwhile
+	if err := os.Rename(staging, target); err != nil && !os.IsExist(err) {
+		return errors.Wrapf(err, "failed to cache template %s", name)
+	}
+	return nil
+}
+
+// Open returns the tree of the template name at revision: the shipped tree
+// if it is that revision, otherwise a cached copy. It reports false when
+// neither has it.
+func (r *Revisions) Open(name, revision string) (fs.FS, bool) {
+	if current, err := Revision(shipped.FS, name); err == nil && current == revision {
+		tree, err := fs.Sub(shipped.FS, name)
+		return tree, err == nil
+	}
+
+	dir, ok := r.treeDir(name, revision)
+	if !ok {
+		return nil, false
+	}
+	// A copy that was changed since it was saved is no use as a base
+	tree := os.DirFS(dir)
+	if cached, err := Revision(tree, "."); err != nil || cached != revision {
+		return nil, false
+	}
+	return tree, true
+}
+
+// treeDir returns the cache directory of a template revision. Names and
+// revisions come from lockfiles, so anything but a template ID and a
+// SHA-256 revision is refused.
+func (r *Revisions) treeDir(name, revision string) (string, bool) {
+	hash := strings.TrimPrefix(revision, "sha256:")
+	if !fs.ValidPath(name) || name == "." || !revisionHash.MatchString(hash) {
+		return "", false
+	}
+	return filepath.Join(r.dir, filepath.FromSlash(name), hash), true
+}
@@ -0,0 +1,98 @@
+package templates
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ti-lo/tilokit/internal/utils"
+	shipped "github.com/ti-lo/tilokit/templates"
+)
+
+func TestRevisionsSaveShippedTemplates(t *testing.T) {
+	dir := t.TempDir()
+	revisions := OpenRevisions(dir)
+	if err := revisions.Save("go/gin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := revisions.Save("go/gin"); err != nil {
+		t.Fatalf("Expected saving a cached revision again to succeed, got %v", err)
+	}
+
+	revision, err := Revision(shipped.FS, "go/gin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := Revision(os.DirFS(filepath.Join(dir, "go", "gin", strings.TrimPrefix(revision, "sha256:"))), ".")
+	if err != nil || cached != revision {
+		t.Errorf("Expected the cached copy to be revision %s, got %s (%v)", revision, cached, err)
+	}
+	if _, ok := revisions.Open("go/gin", revision); !ok {
+		t.Error("Expected the shipped revision to open")
+	}
+}
+
+func TestRevisionsOpenOlderRevisions(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, "older")
+	if err := os.MkdirAll(older, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(older, "README.md.tmpl"), []byte("# {{.project_name}}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	revision, err := Revision(os.DirFS(older), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeDir := filepath.Join(dir, "go", "gin", strings.TrimPrefix(revision, "sha256:"))
+	if err := os.MkdirAll(filepath.Dir(treeDir), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(older, treeDir); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions := OpenRevisions(dir)
+	if _, ok := revisions.Open("go/gin", revision); !ok {
+		t.Error("Expected the cached revision to open")
+	}
+	for name, rev := range map[string]string{
+		"go/gin":    "sha256:../../../etc",
+		"../go/gin": revision,
+		"go/echo":   revision,
+	} {
+		if _, ok := revisions.Open(name, rev); ok {
+			t.Errorf("Expected %s at %s not to open", name, rev)
+		}
+	}
+
+	// An edited copy is not that revision any more
+	if err := os.WriteFile(filepath.Join(treeDir, "README.md.tmpl"), []byte("# edited\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := revisions.Open("go/gin", revision); ok {
+		t.Error("Expected an edited copy not to open")
+	}
+}
+
+func TestCopyShippedTemplateRendersPinnedTree(t *testing.T) {
+	ctx := newSampleContext()
+	tree := fstest.MapFS{"README.md.tmpl": {Data: []byte("# {{.project_name}} (pinned)\n")}}
+	ctx.Templates = map[string]fs.FS{"go/gin": tree}
+
+	if err := NewTemplateEngine().CopyShippedTemplate("go/gin", ".", ctx); err != nil {
+		t.Fatal(err)
+	}
+	content, err := utils.ReadFileFS(ctx.FS, filepath.Join("/project", "sample-app", "README.md"))
+	if err != nil || content != "# sample-app (pinned)\n" {
+		t.Errorf("Expected the pinned README, got %q (%v)", content, err)
+	}
+	revision, _ := Revision(tree, ".")
+	if records := ctx.RenderedTemplates(); len(records) != 1 || records[0].Revision != revision {
+		t.Errorf("Expected the pinned revision to be recorded, got %+v", records)
+	}
+}
@@ -0,0 +1,136 @@
+package templates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a parsed semantic version
+type version struct {
+	parts      [3]int
+	prerelease string
+}
+
+// SemverCompare reports whether v satisfies constraint. A constraint is a
+// comma-separated list of comparisons that must all hold, using =, !=, >,
+// >=, <, <=, ~ (same minor) or ^ (same major), e.g. ">=1.2, <2". Versions
+// may carry a leading "v" and omit trailing components.
+func SemverCompare(constraint, v string) (bool, error) {
+	target, err := parseVersion(v)
+	if err != nil {
+		return false, err
+	}
+
+	for _, clause := range strings.Split(constraint, ",") {
+		clause = strings.TrimSpace(clause)
+		op := strings.TrimRight(clause[:len(clause)-len(strings.TrimLeft(clause, "=!<>~^"))], " ")
+		bound, err := parseVersion(strings.TrimSpace(clause[len(op):]))
+		if err != nil {
+			return false, fmt.Errorf("invalid constraint %q: %w", clause, err)
+		}
+
+		cmp := compareVersions(target, bound)
+		var ok bool
+		switch op {
+		case "", "=", "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "~":
+			ok = cmp >= 0 && target.parts[0] == bound.parts[0] && target.parts[1] == bound.parts[1]
+		case "^":
+			ok = cmp >= 0 && target.parts[0] == bound.parts[0]
+		default:
+			return false, fmt.Errorf("invalid constraint operator %q", op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func parseVersion(s string) (version, error) {
+	var v version
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if s == "" {
+		return v, fmt.Errorf("empty version")
+	}
+
+	// Build metadata never affects precedence
+	s, _, _ = strings.Cut(s, "+")
+	s, v.prerelease, _ = strings.Cut(s, "-")
+
+	components := strings.Split(s, ".")
+	if len(components) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	for i, component := range components {
+		n, err := strconv.Atoi(component)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v.parts[i] = n
+	}
+	return v, nil
+}
+
+// compareVersions orders versions by semver precedence
+func compareVersions(a, b version) int {
+	for i := range a.parts {
+		if a.parts[i] != b.parts[i] {
+			if a.parts[i] < b.parts[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	// A pre-release sorts before the release it precedes
+	switch {
+	case a.prerelease == b.prerelease:
+		return 0
+	case a.prerelease == "":
+		return 1
+	case b.prerelease == "":
+		return -1
+	}
+
+	aIDs, bIDs := strings.Split(a.prerelease, "."), strings.Split(b.prerelease, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if c := compareIdentifier(aIDs[i], bIDs[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(aIDs) < len(bIDs):
+		return -1
+	case len(aIDs) > len(bIDs):
+		return 1
+	}
+	return 0
+}
+
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return an - bn
+	case aErr == nil:
+		// Numeric identifiers sort before alphanumeric ones
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -1,33 +1,87 @@
 package templates
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 
 	"github.com/pkg/errors"
 
-	"github.com/ti-lo/tilokit/internal/core/context"
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
 	"github.com/ti-lo/tilokit/internal/utils"
+	shipped "github.com/ti-lo/tilokit/templates"
 )
 
+// TemplateSuffix marks files that are rendered rather than copied as-is
+const TemplateSuffix = ".tmpl"
+
+// PartialPrefix marks template files that are only used through include
+// and never written to the project, e.g. _helpers.tmpl
+const PartialPrefix = "_"
+
 // TemplateEngine handles template processing
 type TemplateEngine struct {
 	templates map[string]*template.Template
+	funcs     template.FuncMap
+	partials  map[string]string
 }
 
 // NewTemplateEngine creates a new template engine
 func NewTemplateEngine() *TemplateEngine {
 	return &TemplateEngine{
 		templates: make(map[string]*template.Template),
+		funcs:     make(template.FuncMap),
+		partials:  make(map[string]string),
 	}
 }
 
-// ProcessTemplate processes a template string with context variables
+// Funcs adds functions to every template this engine renders, overriding
+// built-in and plugin functions of the same name
+func (te *TemplateEngine) Funcs(funcs template.FuncMap) *TemplateEngine {
+	for name, fn := range funcs {
+		te.funcs[name] = fn
+	}
+	return te
+}
+
+// AddPartial makes a template available to include under name. Templates
+// it defines with {{define}} can be included by their own names.
+func (te *TemplateEngine) AddPartial(name, content string) {
+	te.partials[name] = content
+}
+
+// ProcessTemplate processes a template string with context variables.
+// Referencing a variable that isn't set is an error.
 func (te *TemplateEngine) ProcessTemplate(templateContent string, ctx *tilocontext.ExecutionContext) (string, error) {
-	tmpl, err := template.New("template").Parse(templateContent)
-	if err != nil {
+	return te.processNamed("template", templateContent, ctx, "error")
+}
+
+// processNamed renders a template. missingKey is the text/template
+// missingkey option: "error" for file content, "zero" for conditions.
+func (te *TemplateEngine) processNamed(name, templateContent string, ctx *tilocontext.ExecutionContext, missingKey string) (string, error) {
+	tmpl := template.New(name).Option("missingkey=" + missingKey)
+	tmpl.Funcs(te.funcMap(ctx, tmpl))
+
+	partialNames := make([]string, 0, len(te.partials))
+	for partialName := range te.partials {
+		partialNames = append(partialNames, partialName)
+	}
+	sort.Strings(partialNames)
+	for _, partialName := range partialNames {
+		if _, err := tmpl.New(partialName).Parse(te.partials[partialName]); err != nil {
+			return "", errors.Wrapf(err, "failed to parse partial %s", partialName)
+		}
+	}
+
+	if _, err := tmpl.Parse(templateContent); err != nil {
 		return "", errors.Wrap(err, "failed to parse template")
 	}
 
@@ -54,7 +108,7 @@ func (te *TemplateEngine) ProcessTemplateFile(templatePath, outputPath string, c
 	}
 
 	// Write result
-	if err := utils.WriteFile(outputPath, result); err != nil {
+	if err := ctx.WriteFile(outputPath, result); err != nil {
 		return errors.Wrap(err, "failed to write processed template")
 	}
 
@@ -63,31 +117,294 @@ func (te *TemplateEngine) ProcessTemplateFile(templatePath, outputPath string, c
 
 // CopyTemplateDirectory copies and processes all templates in a directory
 func (te *TemplateEngine) CopyTemplateDirectory(templateDir, outputDir string, ctx *tilocontext.ExecutionContext) error {
-	return filepath.Walk(templateDir, func(path string, info os.FileInfo, err error) error {
+	return te.CopyTemplateFS(os.DirFS(templateDir), ".", outputDir, ctx)
+}
+
+// CopyShippedTemplate renders one of the templates embedded in the binary,
+// e.g. "go/gin", into outputDir. A tree for name in ctx.Templates is
+// rendered instead.
+func (te *TemplateEngine) CopyShippedTemplate(name, outputDir string, ctx *tilocontext.ExecutionContext) error {
+	var fsys fs.FS = shipped.FS
+	root := name
+	if tree, ok := ctx.Templates[name]; ok {
+		fsys, root = tree, "."
+	}
+	if _, err := fs.Stat(fsys, root); err != nil {
+		return errors.Wrapf(err, "unknown template %s", name)
+	}
+	revision, err := Revision(fsys, root)
+	if err != nil {
+		return errors.Wrapf(err, "failed to hash template %s", name)
+	}
+	ctx.RecordTemplate(name, revision)
+	return te.CopyTemplateFS(fsys, root, outputDir, ctx)
+}
+
+// Revision returns a content hash of the template tree at root in fsys. It
+// changes whenever a file in the tree is added, removed, renamed or edited.
+func Revision(fsys fs.FS, root string) (string, error) {
+	hash := sha256.New()
+	err := fs.WalkDir(fsys, root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, filePath)
 		if err != nil {
 			return err
 		}
+		relPath := filePath
+		if root != "." {
+			relPath = strings.TrimPrefix(strings.TrimPrefix(filePath, root), "/")
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", relPath, len(content))
+		hash.Write(content)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ShippedTemplates returns the IDs of the templates embedded in the binary,
+// such as "python/django"
+func ShippedTemplates() []string {
+	roots, _ := fs.Glob(shipped.FS, "*/*")
+	sort.Strings(roots)
+	return roots
+}
+
+// FindShippedTemplate resolves a shipped template by ID ("python/django")
+// or by framework name ("django")
+func FindShippedTemplate(name string) (string, bool) {
+	for _, id := range ShippedTemplates() {
+		if id == name || path.Base(id) == name {
+			return id, true
+		}
+	}
+	return "", false
+}
 
-		// Calculate relative path
-		relPath, err := filepath.Rel(templateDir, path)
+// CopyTemplateFS copies and processes the template tree at root in fsys.
+//
+// Files ending in .tmpl are rendered and written without the suffix; other
+// files are copied unchanged. Every path segment is rendered too, so a
+// directory may be named {{.module_name}} or just {{module_name}}; a segment
+// that renders empty skips the file or directory. Files are also skipped by
+// the rules in the tree's manifest and by a "when" condition in a template's
+// front-matter. Manifest questions without an answer in ctx.Variables take
+// their defaults. Templates named with a leading underscore are partials:
+// they can be included by every template in the tree but are not written.
+func (te *TemplateEngine) CopyTemplateFS(fsys fs.FS, root, outputDir string, ctx *tilocontext.ExecutionContext) error {
+	manifest, err := LoadManifest(fsys, root)
+	if err != nil {
+		return err
+	}
+	// Questions nobody asked, e.g. during a non-interactive run, take their
+	// defaults
+	if _, err := te.Answer(manifest.Questions, ctx, nil); err != nil {
+		return err
+	}
+	if err := te.loadPartials(fsys, root); err != nil {
+		return err
+	}
+
+	return fs.WalkDir(fsys, root, func(filePath string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		outputPath := filepath.Join(outputDir, relPath)
+		// Calculate relative path; fs.FS paths always use forward slashes
+		relPath := filePath
+		if root != "." {
+			relPath = strings.TrimPrefix(strings.TrimPrefix(filePath, root), "/")
+		}
+		if relPath == "." || relPath == "" {
+			return ctx.EnsureDir(outputDir)
+		}
+		if relPath == ManifestName || isPartial(filePath) {
+			return nil
+		}
+
+		include, err := te.included(manifest, relPath, ctx)
+		if err != nil {
+			return err
+		}
+		renderedPath, err := te.renderPath(relPath, ctx)
+		if err != nil {
+			return err
+		}
+		if !include || renderedPath == "" {
+			return skipEntry(entry)
+		}
+		outputPath := filepath.Join(outputDir, filepath.FromSlash(renderedPath))
 
-		if info.IsDir() {
-			return utils.EnsureDir(outputPath)
+		if entry.IsDir() {
+			return ctx.EnsureDir(outputPath)
 		}
 
-		// Process template files
-		if strings.HasSuffix(path, ".tmpl") {
-			// Remove .tmpl extension from output
-			outputPath = strings.TrimSuffix(outputPath, ".tmpl")
-			return te.ProcessTemplateFile(path, outputPath, ctx)
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read template file %s", filePath)
 		}
 
+		// Scripts stay executable
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		executable := info.Mode()&0111 != 0
+
 		// Copy non-template files as-is
-		return utils.CopyFile(path, outputPath)
+		if !strings.HasSuffix(filePath, TemplateSuffix) {
+			return writeTemplateOutput(ctx, outputPath, string(content), executable)
+		}
+
+		matter, body, err := splitFrontMatter(string(content))
+		if err != nil {
+			return errors.Wrapf(err, "failed to read template %s", filePath)
+		}
+		if matter.When != "" {
+			ok, err := te.Condition(matter.When, ctx)
+			if err != nil {
+				return errors.Wrapf(err, "invalid when condition in %s", filePath)
+			}
+			if !ok {
+				return nil
+			}
+		}
+
+		// Remove .tmpl extension from output
+		result, err := te.processNamed(path.Base(filePath), body, ctx, "error")
+		if err != nil {
+			return errors.Wrapf(err, "failed to process template %s", filePath)
+		}
+		return writeTemplateOutput(ctx, strings.TrimSuffix(outputPath, TemplateSuffix), result, executable)
 	})
 }
+
+// writeTemplateOutput writes a rendered file, keeping the template's
+// execute bit
+func writeTemplateOutput(ctx *tilocontext.ExecutionContext, outputPath, content string, executable bool) error {
+	if err := ctx.WriteFile(outputPath, content); err != nil {
+		return err
+	}
+	if executable {
+		return ctx.MakeExecutable(outputPath)
+	}
+	return nil
+}
+
+// Condition evaluates a template condition such as `.use_docker` or
+// `and (feature "docker") (ne .database "none")`. Missing variables are
+// treated as unset rather than as an error.
+func (te *TemplateEngine) Condition(expr string, ctx *tilocontext.ExecutionContext) (bool, error) {
+	result, err := te.processNamed("condition", "{{ if "+expr+" }}true{{ end }}", ctx, "zero")
+	if err != nil {
+		return false, err
+	}
+	return result == "true", nil
+}
+
+// included applies the manifest's file rules to a template path
+func (te *TemplateEngine) included(manifest *Manifest, relPath string, ctx *tilocontext.ExecutionContext) (bool, error) {
+	for _, rule := range manifest.Files {
+		if !matchGlob(rule.Pattern, relPath) {
+			continue
+		}
+
+		holds := true
+		if rule.When != "" {
+			var err error
+			if holds, err = te.Condition(rule.When, ctx); err != nil {
+				return false, errors.Wrapf(err, "invalid when condition for %s", rule.Pattern)
+			}
+		}
+		if rule.Exclude == holds {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// renderPath renders each segment of a template path. It returns an empty
+// path if any segment renders empty.
+func (te *TemplateEngine) renderPath(relPath string, ctx *tilocontext.ExecutionContext) (string, error) {
+	segments := strings.Split(relPath, "/")
+	for i, segment := range segments {
+		if !strings.Contains(segment, "{{") {
+			continue
+		}
+
+		rendered, err := te.processNamed(segment, te.bareVariables(segment, ctx), ctx, "error")
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to render path %s", relPath)
+		}
+		rendered = strings.TrimSpace(rendered)
+		if rendered == "" {
+			return "", nil
+		}
+		// A segment may not climb out of its directory or add new ones
+		if rendered == "." || rendered == ".." || strings.ContainsAny(rendered, `/\`) {
+			return "", errors.Errorf("path segment %s rendered to invalid name %q", segment, rendered)
+		}
+		segments[i] = rendered
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// bareVariable matches cookiecutter-style references like {{module_name}}
+var bareVariable = regexp.MustCompile(`\{\{(-?\s*)([A-Za-z_][A-Za-z0-9_]*)(\s*-?)\}\}`)
+
+// bareVariables rewrites {{name}} to {{.name}} unless name is a function,
+// so path segments can use the short form
+func (te *TemplateEngine) bareVariables(segment string, ctx *tilocontext.ExecutionContext) string {
+	funcs := te.funcMap(ctx, nil)
+	return bareVariable.ReplaceAllStringFunc(segment, func(match string) string {
+		parts := bareVariable.FindStringSubmatch(match)
+		if _, isFunc := funcs[parts[2]]; isFunc || isBuiltin(parts[2]) {
+			return match
+		}
+		return "{{" + parts[1] + "." + parts[2] + parts[3] + "}}"
+	})
+}
+
+// isBuiltin reports whether name is a text/template keyword or builtin
+func isBuiltin(name string) bool {
+	switch name {
+	case "and", "or", "not", "len", "index", "print", "printf", "println", "html", "js", "urlquery",
+		"eq", "ne", "lt", "le", "gt", "ge", "slice", "call", "true", "false", "nil", "end", "else":
+		return true
+	}
+	return false
+}
+
+func skipEntry(entry fs.DirEntry) error {
+	if entry.IsDir() {
+		return fs.SkipDir
+	}
+	return nil
+}
+
+// loadPartials registers every partial in the tree under its base name
+func (te *TemplateEngine) loadPartials(fsys fs.FS, root string) error {
+	return fs.WalkDir(fsys, root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !isPartial(filePath) {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read partial %s", filePath)
+		}
+		te.AddPartial(path.Base(filePath), string(content))
+		return nil
+	})
+}
+
+// isPartial reports whether a template is a partial. Names starting with a
+// double underscore, such as Python's __init__.py, are regular files.
+func isPartial(filePath string) bool {
+	base := path.Base(filePath)
+	return strings.HasPrefix(base, PartialPrefix) && !strings.HasPrefix(base, PartialPrefix+PartialPrefix) &&
+		strings.HasSuffix(base, TemplateSuffix)
+}
@@ -0,0 +1,194 @@
+package templates
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/utils"
+	shipped "github.com/ti-lo/tilokit/templates"
+)
+
+func newSampleContext() *tilocontext.ExecutionContext {
+	ctx := tilocontext.NewExecutionContext(&tilocontext.ProjectConfig{
+		ProjectName:    "sample-app",
+		Framework:      "sample",
+		BuildTool:      "sample",
+		PackageManager: "npm",
+		OutputDir:      "/project",
+	})
+	ctx.FS = utils.NewMemoryFS()
+	return ctx
+}
+
+// shippedTemplateRoots returns every <language>/<framework> tree
+func shippedTemplateRoots(t *testing.T) []string {
+	t.Helper()
+	roots, err := fs.Glob(shipped.FS, "*/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) == 0 {
+		t.Fatal("Expected embedded templates")
+	}
+	return roots
+}
+
+func TestShippedTemplatesRender(t *testing.T) {
+	for _, root := range shippedTemplateRoots(t) {
+		t.Run(root, func(t *testing.T) {
+			ctx := newSampleContext()
+			if err := NewTemplateEngine().CopyShippedTemplate(root, ".", ctx); err != nil {
+				t.Fatalf("Expected template to render, got: %v", err)
+			}
+
+			files := ctx.WrittenFiles()
+			if len(files) == 0 {
+				t.Fatal("Expected rendered files")
+			}
+			for _, file := range files {
+				if strings.HasSuffix(file.Path, TemplateSuffix) {
+					t.Errorf("Expected %s suffix to be stripped from %s", TemplateSuffix, file.Path)
+				}
+				content, err := ctx.ReadFile(file.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if strings.Contains(content, "{{") {
+					t.Errorf("Unrendered action left in %s", path.Join(root, file.Path))
+				}
+			}
+		})
+	}
+}
+
+func TestMissingVariablesFail(t *testing.T) {
+	ctx := newSampleContext()
+
+	_, err := NewTemplateEngine().ProcessTemplate("name: {{.ProjectName}}", ctx)
+	if err == nil {
+		t.Fatal("Expected an error for a missing variable")
+	}
+}
+
+func TestCopyTemplateFSRendersPathsAndRules(t *testing.T) {
+	tree := fstest.MapFS{
+		"tilokit.template.yaml": {Data: []byte(strings.Join([]string{
+			"files:",
+			"  - pattern: Dockerfile.tmpl",
+			"    when: .use_docker",
+			"  - pattern: docs/**",
+			"    exclude: true",
+			"  - pattern: \"**/*.orig\"",
+			"    exclude: true",
+			"",
+		}, "\n"))},
+		"Dockerfile.tmpl":                          {Data: []byte("FROM python\n")},
+		"docs/guide.md":                            {Data: []byte("guide\n")},
+		"notes.orig":                               {Data: []byte("old\n")},
+		"src/{{module_name}}/__init__.py.tmpl":     {Data: []byte("# {{.project_name}}\n")},
+		"src/{{.module_name}}_test.py":             {Data: []byte("static\n")},
+		"{{if .use_ci}}.github{{end}}/ci.yml.tmpl": {Data: []byte("name: ci\n")},
+		"docker-compose.yml.tmpl": {Data: []byte(strings.Join([]string{
+			"---tilokit",
+			"when: feature \"docker\"",
+			"---",
+			"services: {}",
+			"",
+		}, "\n"))},
+		"_helpers.tmpl":  {Data: []byte(`{{ define "name" }}{{ pascalCase .project_name }}{{ end }}`)},
+		"README.md.tmpl": {Data: []byte("# {{ include \"name\" . }}\n")},
+	}
+
+	ctx := newSampleContext()
+	ctx.Variables["use_docker"] = false
+	ctx.Variables["use_ci"] = false
+	if err := NewTemplateEngine().CopyTemplateFS(tree, ".", ".", ctx); err != nil {
+		t.Fatalf("Expected template tree to render, got: %v", err)
+	}
+
+	var got []string
+	for _, file := range ctx.WrittenFiles() {
+		got = append(got, file.Path)
+	}
+	want := []string{"README.md", "src/sample_app/__init__.py", "src/sample_app_test.py"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected files %v, got %v", want, got)
+	}
+
+	readme, err := ctx.ReadFile("README.md")
+	if err != nil || readme != "# SampleApp\n" {
+		t.Fatalf("Expected README rendered with partial, got %q (%v)", readme, err)
+	}
+
+	// Flipping the conditions brings the skipped files back
+	ctx = newSampleContext()
+	ctx.Config.Features = []string{"docker"}
+	ctx.Variables["use_docker"] = true
+	ctx.Variables["use_ci"] = true
+	if err := NewTemplateEngine().CopyTemplateFS(tree, ".", ".", ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Dockerfile", ".github/ci.yml", "docker-compose.yml"} {
+		if !ctx.FileExists(name) {
+			t.Errorf("Expected %s to be rendered", name)
+		}
+	}
+	compose, _ := ctx.ReadFile("docker-compose.yml")
+	if compose != "services: {}\n" {
+		t.Errorf("Expected front-matter to be stripped, got %q", compose)
+	}
+}
+
+func TestCopyTemplateFSKeepsExecuteBit(t *testing.T) {
+	tree := fstest.MapFS{
+		"bin/setup.sh.tmpl": {Data: []byte("#!/bin/sh\necho {{.project_name}}\n"), Mode: 0755},
+		"README.md":         {Data: []byte("readme\n"), Mode: 0644},
+	}
+
+	ctx := newSampleContext()
+	if err := NewTemplateEngine().CopyTemplateFS(tree, ".", ".", ctx); err != nil {
+		t.Fatal(err)
+	}
+	for name, executable := range map[string]bool{"bin/setup.sh": true, "README.md": false} {
+		info, err := ctx.FS.Stat(ctx.Path(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode()&0100 != 0; got != executable {
+			t.Errorf("Expected %s executable=%v, got mode %v", name, executable, info.Mode())
+		}
+	}
+}
+
+func TestRenderPathRejectsTraversal(t *testing.T) {
+	ctx := newSampleContext()
+	ctx.Variables["name"] = "../escape"
+
+	tree := fstest.MapFS{"{{name}}.txt": {Data: []byte("x")}}
+	if err := NewTemplateEngine().CopyTemplateFS(tree, ".", ".", ctx); err == nil {
+		t.Fatal("Expected a path segment rendering to ../ to be rejected")
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"docs/**", "docs", true},
+		{"docs/**", "docs/a/b.md", true},
+		{"**/*.orig", "a.orig", true},
+		{"**/*.orig", "x/y/a.orig", true},
+		{"*.md", "docs/a.md", false},
+		{"src/*/init.py", "src/app/init.py", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,13 +1,15 @@
 package tools
 
 import (
-	"path/filepath"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/pkg/errors"
 	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/features"
+	"github.com/ti-lo/tilokit/internal/core/registry"
 	"github.com/ti-lo/tilokit/internal/utils"
 )
 
@@ -39,6 +41,39 @@ func (p *GitPlugin) SupportedBuildTools() []string {
 	return []string{"*"} // Support all build tools
 }
 
+// Manifest declares the plugin API Git integration targets and the options it accepts
+func (p *GitPlugin) Manifest() registry.Manifest {
+	return registry.Manifest{
+		Name:        p.Name(),
+		Version:     p.Version(),
+		Description: p.Description(),
+		APIVersion:  1,
+		Frameworks:  p.SupportedFrameworks(),
+		BuildTools:  p.SupportedBuildTools(),
+		Options: []registry.Option{
+			{Name: "default_branch", Type: registry.OptionString, Default: "main",
+				Description: "Branch the repository starts on"},
+			{Name: "initial_commit", Type: registry.OptionBool, Default: true,
+				Description: "Commit the generated project"},
+		},
+	}
+}
+
+// IsTool marks git integration as a cross-cutting plugin attached to every run
+func (p *GitPlugin) IsTool() bool {
+	return true
+}
+
+func (p *GitPlugin) Dependencies() registry.Dependencies {
+	// The initial commit must include every other plugin's output
+	return registry.Dependencies{After: []string{registry.RunAfterAll}}
+}
+
+// Features lists the features the plugin can add to an existing project
+func (p *GitPlugin) Features() []string {
+	return []string{features.Git}
+}
+
 func (p *GitPlugin) PreGenerate(ctx *tilocontext.ExecutionContext) error {
 	// Check if git should be initialized
 	if !ctx.Config.GitInit {
@@ -59,9 +94,9 @@ func (p *GitPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
 		return nil
 	}
 
-	// Initialize git repository
-	if err := p.initGitRepo(ctx); err != nil {
-		return errors.Wrap(err, "failed to initialize git repository")
+	// Keep the .gitignore of an existing project
+	if ctx.Augment && ctx.FileExists(".gitignore") {
+		return nil
 	}
 
 	// Create .gitignore
@@ -69,20 +104,48 @@ func (p *GitPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
 		return errors.Wrap(err, "failed to create .gitignore")
 	}
 
-	// Create initial commit
-	if err := p.createInitialCommit(ctx); err != nil {
-		utils.Warning("Failed to create initial commit: %v", err)
-		// Don't fail the entire process for this
-	}
-
 	return nil
 }
 
+// PostGenerate initializes the repository once every file, including the
+// lockfile the engine writes after generation, is in place
 func (p *GitPlugin) PostGenerate(ctx *tilocontext.ExecutionContext) error {
-	if ctx.Config.GitInit {
-		ctx.SetMetadata("git_initialized", true)
-		utils.Success("Git repository initialized")
+	if !ctx.Config.GitInit {
+		return nil
 	}
+
+	// A repository needs a real directory
+	if ctx.Virtual {
+		ctx.SkipStep("git init", "output is not written to disk")
+		return nil
+	}
+
+	// An existing project may already be a repository
+	if ctx.Augment && ctx.FileExists(".git") {
+		ctx.SkipStep("git init", "the project is already a git repository")
+		return nil
+	}
+
+	// Initialize git repository
+	if err := p.initGitRepo(ctx); err != nil {
+		return errors.Wrap(err, "failed to initialize git repository")
+	}
+
+	// Stop before staging every file if the run was cancelled
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Create initial commit
+	if !ctx.BoolOption("initial_commit") {
+		ctx.SkipStep("git commit", "the initial_commit option is off")
+	} else if err := p.createInitialCommit(ctx); err != nil {
+		utils.Warning("Failed to create initial commit: %v", err)
+		// Don't fail the entire process for this
+	}
+
+	ctx.SetMetadata("git_initialized", true)
+	utils.Success("Git repository initialized")
 	return nil
 }
 
@@ -92,7 +155,9 @@ func (p *GitPlugin) isGitAvailable() bool {
 }
 
 func (p *GitPlugin) initGitRepo(ctx *tilocontext.ExecutionContext) error {
-	_, err := git.PlainInit(ctx.ProjectPath, false)
+	_, err := git.PlainInitWithOptions(ctx.ProjectPath, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName(ctx.StringOption("default_branch"))},
+	})
 	if err != nil {
 		return errors.Wrap(err, "failed to initialize git repository")
 	}
@@ -103,9 +168,7 @@ func (p *GitPlugin) initGitRepo(ctx *tilocontext.ExecutionContext) error {
 
 func (p *GitPlugin) createGitignore(ctx *tilocontext.ExecutionContext) error {
 	gitignoreContent := p.generateGitignore(ctx)
-	gitignorePath := filepath.Join(ctx.ProjectPath, ".gitignore")
-
-	return utils.WriteFile(gitignorePath, gitignoreContent)
+	return ctx.WriteFile(".gitignore", gitignoreContent)
 }
 
 func (p *GitPlugin) generateGitignore(ctx *tilocontext.ExecutionContext) string {
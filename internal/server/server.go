@@ -0,0 +1,288 @@
+// Package server exposes project generation over HTTP, so a developer
+// portal can offer self-service scaffolding with the same plugins as the
+// CLI. Projects are rendered in memory and returned as zip archives.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ti-lo/tilokit/internal/core/archive"
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/plugins/templates"
+	"github.com/ti-lo/tilokit/internal/utils"
+	"github.com/ti-lo/tilokit/pkg/tilokit"
+	shipped "github.com/ti-lo/tilokit/templates"
+)
+
+const (
+	// DefaultTimeout bounds a generation request
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxConcurrent is how many projects are generated at once
+	DefaultMaxConcurrent = 4
+	// maxRequestBytes limits the size of a generation request body
+	maxRequestBytes = 1 << 20
+)
+
+// Options configures a Server
+type Options struct {
+	// Generator selects the plugins. Each generation gets a generator of
+	// its own, with FS replaced by an in-memory filesystem.
+	Generator tilokit.Options
+	// Defaults returns the configuration a generation request is decoded
+	// over, so fields the request leaves out, such as git_init, keep its
+	// values rather than becoming false. An empty configuration if nil.
+	Defaults func() *tilocontext.ProjectConfig
+	// Complete fills in what a generation request leaves out, such as the
+	// build tool, default features and template answers
+	Complete func(config *tilocontext.ProjectConfig) error
+	// Timeout bounds each generation request, DefaultTimeout if zero
+	Timeout time.Duration
+	// MaxConcurrent limits the generations running at once; requests over
+	// the limit wait until their timeout. DefaultMaxConcurrent if zero.
+	MaxConcurrent int
+	// TempDir holds the temporary directory of each generation, the
+	// system default if empty
+	TempDir string
+}
+
+// Server serves the generation API
+type Server struct {
+	opts      Options
+	generator *tilokit.Generator
+	slots     chan struct{}
+}
+
+// New creates a server
+func New(opts Options) (*Server, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = DefaultMaxConcurrent
+	}
+
+	// This generator only answers the listing endpoints
+	gen, err := tilokit.New(opts.Generator)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		opts:      opts,
+		generator: gen,
+		slots:     make(chan struct{}, opts.MaxConcurrent),
+	}, nil
+}
+
+// Handler returns the API's routes:
+//
+//	GET  /frameworks                    frameworks of the registered plugins
+//	GET  /build-tools                   build tools of the registered plugins
+//	GET  /templates/{id}/questions      questions of a shipped template, by
+//	                                    ID ("python/django") or framework
+//	POST /generate                      a ProjectConfig as JSON; returns a zip
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /frameworks", s.listFrameworks)
+	mux.HandleFunc("GET /build-tools", s.listBuildTools)
+	mux.HandleFunc("GET /templates/{id}/questions", s.templateQuestions)
+	mux.HandleFunc("GET /templates/{language}/{name}/questions", s.templateQuestions)
+	mux.HandleFunc("POST /generate", s.generate)
+	return mux
+}
+
+// ListenAndServe serves the API on addr until ctx is done, then lets
+// requests in flight finish
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       s.opts.Timeout,
+		// Leave time to send the archive after generation timed out
+		WriteTimeout: 2 * s.opts.Timeout,
+		IdleTimeout:  time.Minute,
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.ListenAndServe() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdown, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+		defer cancel()
+		return server.Shutdown(shutdown)
+	}
+}
+
+func (s *Server) listFrameworks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"frameworks": s.generator.Frameworks(),
+	})
+}
+
+func (s *Server) listBuildTools(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"build_tools": s.generator.BuildTools(),
+	})
+}
+
+func (s *Server) templateQuestions(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("id")
+	if name == "" {
+		name = r.PathValue("language") + "/" + r.PathValue("name")
+	}
+	id, found := templates.FindShippedTemplate(name)
+	if !found {
+		writeError(w, http.StatusNotFound, errors.Errorf("no template %s", name))
+		return
+	}
+
+	manifest, err := templates.LoadManifest(shipped.FS, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	questions := manifest.Questions
+	if questions == nil {
+		questions = []templates.Question{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"template":  id,
+		"questions": questions,
+	})
+}
+
+func (s *Server) generate(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.opts.Timeout)
+	defer cancel()
+
+	config := &tilocontext.ProjectConfig{}
+	if s.opts.Defaults != nil {
+		config = s.opts.Defaults()
+	}
+	if err := decodeProjectConfig(http.MaxBytesReader(w, r.Body, maxRequestBytes), config); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Wait for a free slot for as long as the request may take
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many projects are being generated, try again later"))
+		return
+	}
+
+	// Each generation works in a directory of its own
+	dir, err := os.MkdirTemp(s.opts.TempDir, "tilokit-serve-")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	config.OutputDir = dir
+
+	if s.opts.Complete != nil {
+		if err := s.opts.Complete(config); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	fsys := tilokit.NewMemoryFS()
+	genOpts := s.opts.Generator
+	genOpts.FS = fsys
+	result, err := tilokit.Generate(ctx, config, genOpts)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, errors.Errorf("generation took longer than %s", s.opts.Timeout))
+		return
+	case errors.Is(err, context.Canceled):
+		return
+	case err != nil:
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	// Build the archive before answering, so a failure is still an error
+	// response rather than a truncated download
+	name := config.ProjectName + archive.Zip.Extension()
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer file.Close()
+	if err := archive.Write(file, archive.Zip, fsys, result.ProjectPath, config.ProjectName, result.StartTime); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var skipped []string
+	for _, step := range result.Skipped {
+		skipped = append(skipped, fmt.Sprintf("%s (%s)", step.Step, step.Plugin))
+	}
+	header := w.Header()
+	header.Set("Content-Type", "application/zip")
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	header.Set("Content-Length", fmt.Sprint(size))
+	if len(skipped) > 0 {
+		header.Set("X-TiLoKit-Skipped", strings.Join(skipped, ", "))
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, file)
+}
+
+// decodeProjectConfig reads a generation request into config. Unknown keys
+// are errors, as in recipes, and the project name must be a plain directory
+// name since the output directory is the server's.
+func decodeProjectConfig(body io.Reader, config *tilocontext.ProjectConfig) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return errors.Wrap(err, "invalid project config")
+	}
+
+	if err := utils.ValidateProjectName(config.ProjectName); err != nil {
+		return err
+	}
+	if strings.ContainsAny(config.ProjectName, `/\`) || config.ProjectName == ".." {
+		return errors.Errorf("project name %q must not be a path", config.ProjectName)
+	}
+	if config.Framework == "" {
+		return errors.New("framework is required")
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
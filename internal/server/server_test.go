@@ -0,0 +1,210 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/pkg/tilokit"
+)
+
+// stubPlugin writes a README for the "stub" framework, optionally waiting
+// for the run to be cancelled first
+type stubPlugin struct {
+	block bool
+}
+
+func (p *stubPlugin) Name() string                                     { return "stub" }
+func (p *stubPlugin) Version() string                                  { return "1.0.0" }
+func (p *stubPlugin) Description() string                              { return "Stub framework" }
+func (p *stubPlugin) SupportedFrameworks() []string                    { return []string{"stub"} }
+func (p *stubPlugin) SupportedBuildTools() []string                    { return []string{"stub-build"} }
+func (p *stubPlugin) PreGenerate(*tilocontext.ExecutionContext) error  { return nil }
+func (p *stubPlugin) PostGenerate(*tilocontext.ExecutionContext) error { return nil }
+
+func (p *stubPlugin) Generate(ctx *tilocontext.ExecutionContext) error {
+	if p.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return ctx.WriteFile("README.md", "# "+ctx.Config.ProjectName+"\n")
+}
+
+func newTestServer(t *testing.T, opts Options, plugin *stubPlugin) *httptest.Server {
+	t.Helper()
+	opts.Generator = tilokit.Options{Plugins: []tilokit.Plugin{plugin}, NoBuiltins: true}
+	opts.TempDir = t.TempDir()
+	srv, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func getJSON(t *testing.T, url string, out interface{}) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestListsComeFromRegistry(t *testing.T) {
+	ts := newTestServer(t, Options{}, &stubPlugin{})
+
+	var frameworks struct{ Frameworks []string }
+	getJSON(t, ts.URL+"/frameworks", &frameworks)
+	if !reflect.DeepEqual(frameworks.Frameworks, []string{"stub"}) {
+		t.Errorf("Expected the registered framework, got %v", frameworks.Frameworks)
+	}
+
+	var buildTools struct {
+		BuildTools []string `json:"build_tools"`
+	}
+	getJSON(t, ts.URL+"/build-tools", &buildTools)
+	if !reflect.DeepEqual(buildTools.BuildTools, []string{"stub-build"}) {
+		t.Errorf("Expected the registered build tool, got %v", buildTools.BuildTools)
+	}
+}
+
+func TestTemplateQuestions(t *testing.T) {
+	ts := newTestServer(t, Options{}, &stubPlugin{})
+
+	for _, path := range []string{"/templates/django/questions", "/templates/python/django/questions"} {
+		var body struct {
+			Template  string
+			Questions []map[string]interface{}
+		}
+		if status := getJSON(t, ts.URL+path, &body); status != http.StatusOK {
+			t.Fatalf("Expected %s to succeed, got %d", path, status)
+		}
+		if body.Template != "python/django" || len(body.Questions) == 0 {
+			t.Errorf("Expected the django questions from %s, got %+v", path, body)
+		}
+	}
+
+	var body map[string]string
+	if status := getJSON(t, ts.URL+"/templates/cobol/questions", &body); status != http.StatusNotFound {
+		t.Errorf("Expected an unknown template to be 404, got %d", status)
+	}
+}
+
+func TestGenerateReturnsZip(t *testing.T) {
+	var completed *tilocontext.ProjectConfig
+	ts := newTestServer(t, Options{Complete: func(config *tilocontext.ProjectConfig) error {
+		completed = config
+		return nil
+	}}, &stubPlugin{})
+
+	resp, err := http.Post(ts.URL+"/generate", "application/json",
+		strings.NewReader(`{"project_name": "portal-app", "framework": "stub", "build_tool": "stub-build"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("Expected a zip, got %d: %s", resp.StatusCode, data)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, file := range zr.File {
+		names[file.Name] = true
+	}
+	if !names["portal-app/README.md"] || !names["portal-app/.tilokit/lock.yaml"] {
+		t.Errorf("Expected the project files in the zip, got %v", names)
+	}
+
+	// The request's temporary directory is gone once it is answered
+	if _, err := os.Stat(completed.OutputDir); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got: %v", completed.OutputDir, err)
+	}
+}
+
+func TestGenerateKeepsDefaultsForMissingFields(t *testing.T) {
+	var completed []tilocontext.ProjectConfig
+	ts := newTestServer(t, Options{
+		Defaults: func() *tilocontext.ProjectConfig {
+			return &tilocontext.ProjectConfig{GitInit: true, InstallDeps: true}
+		},
+		Complete: func(config *tilocontext.ProjectConfig) error {
+			completed = append(completed, *config)
+			return nil
+		},
+	}, &stubPlugin{})
+
+	for _, body := range []string{
+		`{"project_name": "app", "framework": "stub"}`,
+		`{"project_name": "app", "framework": "stub", "git_init": false}`,
+	} {
+		resp, err := http.Post(ts.URL+"/generate", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected %s to be generated, got %d", body, resp.StatusCode)
+		}
+	}
+
+	if !completed[0].GitInit || !completed[0].InstallDeps {
+		t.Errorf("Expected missing fields to keep the defaults, got %+v", completed[0])
+	}
+	if completed[1].GitInit || !completed[1].InstallDeps {
+		t.Errorf("Expected git_init to be turned off alone, got %+v", completed[1])
+	}
+}
+
+func TestGenerateRejectsBadRequests(t *testing.T) {
+	ts := newTestServer(t, Options{}, &stubPlugin{})
+
+	for body, status := range map[string]int{
+		`{"project_name": "../escape", "framework": "stub"}`:       http.StatusBadRequest,
+		`{"project_name": "app", "framework": "stub", "extra": 1}`: http.StatusBadRequest,
+		`{"project_name": "app"}`:                                  http.StatusBadRequest,
+		`{"project_name": "app", "framework": "cobol"}`:            http.StatusUnprocessableEntity,
+	} {
+		resp, err := http.Post(ts.URL+"/generate", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("Expected %s to be %d, got %d", body, status, resp.StatusCode)
+		}
+	}
+}
+
+func TestGenerateTimesOut(t *testing.T) {
+	ts := newTestServer(t, Options{Timeout: 50 * time.Millisecond, MaxConcurrent: 1}, &stubPlugin{block: true})
+
+	resp, err := http.Post(ts.URL+"/generate", "application/json",
+		strings.NewReader(`{"project_name": "slow-app", "framework": "stub"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("Expected a timeout, got %d", resp.StatusCode)
+	}
+}
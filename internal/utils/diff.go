@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffOp identifies the kind of a line in a diff
+type DiffOp int
+
+const (
+	// DiffEqual marks a line present in both inputs
+	DiffEqual DiffOp = iota
+	// DiffDelete marks a line only present in the first input
+	DiffDelete
+	// DiffInsert marks a line only present in the second input
+	DiffInsert
+)
+
+// DiffLine is a single line of a line-based diff
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// SplitLines splits text into lines, keeping each line's terminator
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// DiffLines computes a shortest edit script between two sets of lines
+// using Myers' algorithm
+func DiffLines(a, b []string) []DiffLine {
+	n, m := len(a), len(b)
+	maxSteps := n + m
+	offset := maxSteps + 1
+	v := make([]int, 2*maxSteps+2)
+	var trace [][]int
+
+	steps := 0
+search:
+	for d := 0; d <= maxSteps; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				steps = d
+				break search
+			}
+		}
+	}
+
+	var reversed []DiffLine
+	x, y := n, m
+	for d := steps; d > 0; d-- {
+		prev := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[offset+k-1] < prev[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffLine{Op: DiffEqual, Text: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, DiffLine{Op: DiffInsert, Text: b[y-1]})
+		} else {
+			reversed = append(reversed, DiffLine{Op: DiffDelete, Text: a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, DiffLine{Op: DiffEqual, Text: a[x-1]})
+		x--
+		y--
+	}
+
+	lines := make([]DiffLine, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+// UnifiedDiff renders the difference between two texts in unified diff
+// format. It returns an empty string when the texts are identical.
+func UnifiedDiff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+
+	lines := DiffLines(SplitLines(from), SplitLines(to))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	// oldLine and newLine hold the 1-based line numbers at each diff index
+	oldLine := make([]int, len(lines)+1)
+	newLine := make([]int, len(lines)+1)
+	oldLine[0], newLine[0] = 1, 1
+	for i, line := range lines {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if line.Op != DiffInsert {
+			oldLine[i+1]++
+		}
+		if line.Op != DiffDelete {
+			newLine[i+1]++
+		}
+	}
+
+	for start := 0; start < len(lines); {
+		// Find the next change
+		for start < len(lines) && lines[start].Op == DiffEqual {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+
+		// Extend the hunk while changes are close enough to share context
+		end := start
+		for i := start; i < len(lines); i++ {
+			if lines[i].Op != DiffEqual {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+
+		from := max(start-diffContext, 0)
+		to := min(end+diffContext, len(lines))
+
+		oldCount := oldLine[to] - oldLine[from]
+		newCount := newLine[to] - newLine[from]
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldLine[from], oldCount), hunkRange(newLine[from], newCount))
+
+		for _, line := range lines[from:to] {
+			prefix := " "
+			switch line.Op {
+			case DiffDelete:
+				prefix = "-"
+			case DiffInsert:
+				prefix = "+"
+			}
+			out.WriteString(prefix + line.Text)
+			if !strings.HasSuffix(line.Text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		start = to
+	}
+
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -25,18 +25,12 @@ func ReadFile(path string) (string, error) {
 
 // WriteFile writes content to a file, creating directories if needed
 func WriteFile(path, content string) error {
-	dir := filepath.Dir(path)
-	if err := EnsureDir(dir); err != nil {
-		return err
-	}
-	// Use more restrictive permissions (0600 instead of 0644)
-	return os.WriteFile(path, []byte(content), 0600)
+	return WriteFileFS(osFS, path, content)
 }
 
 // EnsureDir creates a directory and all parent directories if they don't exist
 func EnsureDir(path string) error {
-	// Use more restrictive permissions (0750 instead of 0755)
-	return os.MkdirAll(path, 0750)
+	return EnsureDirFS(osFS, path)
 }
 
 // FileExists checks if a file exists
@@ -75,6 +69,40 @@ func CopyFile(src, dst string) error {
 	return WriteFile(cleanDst, string(data))
 }
 
+// CopyDir recursively copies a directory tree, preserving file modes and
+// symbolic links
+func CopyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relPath)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			// #nosec G304 - path comes from walking src
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(target, data, info.Mode().Perm())
+		}
+	})
+}
+
 // RemoveFile removes a file if it exists
 func RemoveFile(path string) error {
 	if FileExists(path) {
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// FS is the filesystem abstraction the file helpers operate on. The engine
+// swaps it per run, e.g. for an in-memory filesystem during a dry run.
+type FS = afero.Fs
+
+// osFS backs the helpers that don't take an explicit filesystem
+var osFS = afero.NewOsFs()
+
+// NewOSFS returns a filesystem backed by the host operating system
+func NewOSFS() FS {
+	return afero.NewOsFs()
+}
+
+// NewMemoryFS returns an empty in-memory filesystem
+func NewMemoryFS() FS {
+	return afero.NewMemMapFs()
+}
+
+// NewOverlayFS returns a filesystem that reads through to base but keeps
+// every write in memory, leaving base untouched
+func NewOverlayFS(base FS) FS {
+	return afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(base), afero.NewMemMapFs())
+}
+
+// WriteFileFS writes content to a file on fsys, creating directories if needed
+func WriteFileFS(fsys FS, path, content string) error {
+	if err := EnsureDirFS(fsys, filepath.Dir(path)); err != nil {
+		return err
+	}
+	// Use more restrictive permissions (0600 instead of 0644)
+	return afero.WriteFile(fsys, path, []byte(content), 0600)
+}
+
+// EnsureDirFS creates a directory and its parents on fsys
+func EnsureDirFS(fsys FS, path string) error {
+	// Use more restrictive permissions (0750 instead of 0755)
+	return fsys.MkdirAll(path, 0750)
+}
+
+// ReadFileFS reads a file from fsys
+func ReadFileFS(fsys FS, path string) (string, error) {
+	data, err := afero.ReadFile(fsys, path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FileExistsFS checks if a file or directory exists on fsys
+func FileExistsFS(fsys FS, path string) bool {
+	_, err := fsys.Stat(path)
+	return !os.IsNotExist(err)
+}
@@ -2,9 +2,11 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"unicode"
 
 	"github.com/fatih/color"
 	"github.com/sirupsen/logrus"
@@ -25,6 +27,12 @@ func SetQuiet(q bool) {
 	}
 }
 
+// SetOutput sets where messages are printed, e.g. stderr while stdout
+// carries an archive
+func SetOutput(w io.Writer) {
+	color.Output = w
+}
+
 // IsProduction checks if running in production mode
 func IsProduction() bool {
 	return os.Getenv("TILOKIT_ENV") == "production"
@@ -96,29 +104,86 @@ func Contains(slice []string, item string) bool {
 	return false
 }
 
+// SplitWords splits an identifier into words at separators, case changes
+// and acronym boundaries, e.g. "myHTTPServer_v2" becomes my, HTTP, Server, v2
+func SplitWords(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+
+		prev := runes[i-1]
+		lowerToUpper := unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev))
+		// The last capital of an acronym starts the next word: HTTPServer
+		acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(r) &&
+			i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if lowerToUpper || acronymEnd {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
 // ToKebabCase converts a string to kebab-case
 func ToKebabCase(s string) string {
-	s = strings.ToLower(s)
-	s = strings.ReplaceAll(s, " ", "-")
-	s = strings.ReplaceAll(s, "_", "-")
-	return s
+	return strings.ToLower(strings.Join(SplitWords(s), "-"))
+}
+
+// ToSnakeCase converts a string to snake_case
+func ToSnakeCase(s string) string {
+	return strings.ToLower(strings.Join(SplitWords(s), "_"))
+}
+
+// ToScreamingSnakeCase converts a string to SCREAMING_SNAKE_CASE
+func ToScreamingSnakeCase(s string) string {
+	return strings.ToUpper(strings.Join(SplitWords(s), "_"))
 }
 
 // ToPascalCase converts a string to PascalCase
 func ToPascalCase(s string) string {
-	words := strings.FieldsFunc(s, func(c rune) bool {
-		return c == ' ' || c == '-' || c == '_'
-	})
+	words := SplitWords(s)
+	for i, word := range words {
+		words[i] = capitalize(word)
+	}
+	return strings.Join(words, "")
+}
 
+// ToCamelCase converts a string to camelCase
+func ToCamelCase(s string) string {
+	words := SplitWords(s)
 	for i, word := range words {
-		if len(word) > 0 {
-			words[i] = strings.ToUpper(word[:1]) + strings.ToLower(word[1:])
+		if i == 0 {
+			words[i] = strings.ToLower(word)
+			continue
 		}
+		words[i] = capitalize(word)
 	}
-
 	return strings.Join(words, "")
 }
 
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
+
 // ValidateProjectName validates a project name
 func ValidateProjectName(name string) error {
 	if name == "" {
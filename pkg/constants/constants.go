@@ -12,7 +12,13 @@ var (
 var KnownLongFlags = []string{
 	"version", "init", "name", "framework", "build-tool",
 	"output", "list-frameworks", "list-build-tools",
-	"quiet", "force", "update", "help",
+	"quiet", "force", "update", "help", "explain-plugins", "skip-plugin",
+	"dry-run", "config-get", "config-set", "config-list", "show-origin",
+	"config-edit", "config-validate", "seed", "set", "values", "no-input",
+	"features", "without", "from", "save-recipe", "upgrade-project",
+	"add", "project", "output-format", "serve", "serve-timeout",
+	"serve-concurrency", "plugin-install", "plugin-list", "plugin-remove",
+	"plugin-info",
 }
 
 // Supported Frameworks - central registry
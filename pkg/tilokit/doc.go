@@ -0,0 +1,36 @@
+// Package tilokit is the Go API of the TiLoKit project generator. It runs
+// the same plugins as the tilokit command, so tools can scaffold projects
+// without shelling out to it.
+//
+// Generate a project into a directory on disk:
+//
+//	result, err := tilokit.Generate(ctx, &tilokit.Spec{
+//		ProjectName: "billing-api",
+//		Framework:   "gin",
+//		BuildTool:   "go-modules",
+//		OutputDir:   "services",
+//	}, tilokit.Options{})
+//
+// or into any other filesystem, such as tilokit.NewMemoryFS(), by setting
+// Options.FS. Plugins of your own are added with Register or
+// Options.Plugins; Options.OnEvent reports progress.
+//
+// # Plugins
+//
+// Plugin is kept small. Further capabilities are optional interfaces found
+// by type assertion: FeaturePlugin, Augmenter, TemplateFuncProvider,
+// Validator, Prompter and ManifestProvider. A plugin declaring no Manifest
+// targets plugin API 1. New and Register reject plugins targeting an API
+// this version doesn't support, or with an invalid manifest, with an
+// *IncompatibleError saying what to change.
+//
+// # Compatibility
+//
+// This package follows semantic versioning together with the module.
+// Within a major version, exported identifiers of this package are not
+// removed and keep their meaning; fields and options may be added, so
+// build Spec and Options with field names. The types it re-exports, such
+// as Spec, Context and Plugin, are covered by the same promise even
+// though they are defined in internal packages. Nothing under internal/
+// is covered, and importing it from outside this module isn't possible.
+package tilokit
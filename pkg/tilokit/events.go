@@ -0,0 +1,49 @@
+package tilokit
+
+import (
+	"github.com/ti-lo/tilokit/internal/core/engine"
+	"github.com/ti-lo/tilokit/internal/core/patch"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+)
+
+type (
+	// Event reports progress of a generation run to Options.OnEvent
+	Event = engine.Event
+	// EventKind identifies what an Event reports
+	EventKind = engine.EventKind
+)
+
+const (
+	// EventHook is sent before a plugin hook runs, with Phase and Plugin
+	EventHook = engine.EventHook
+	// EventFile is sent after a file is written, with Plugin and Path
+	EventFile = engine.EventFile
+	// EventSkip is sent when a plugin skips a side effect, with Plugin,
+	// Step and Reason
+	EventSkip = engine.EventSkip
+)
+
+// Lifecycle phases, in the order they run
+const (
+	PhasePreGenerate  = engine.PhasePreGenerate
+	PhaseGenerate     = engine.PhaseGenerate
+	PhasePostGenerate = engine.PhasePostGenerate
+)
+
+// PhaseAugment replaces PhaseGenerate when an Augmenter adds features
+const PhaseAugment = engine.PhaseAugment
+
+type (
+	// SpecError is returned when a spec can't be generated, e.g. because
+	// it has no project name or no plugin supports its framework
+	SpecError = engine.ConfigError
+	// PluginError is returned when a plugin hook fails; Plugin and Phase
+	// say which
+	PluginError = engine.PluginError
+	// IncompatibleError is returned by New and Register for a plugin this
+	// version can't run; Reason says what to change
+	IncompatibleError = registry.IncompatibleError
+	// ConflictError is returned when two plugins set a key of a structured
+	// file such as package.json to different values
+	ConflictError = patch.ConflictError
+)
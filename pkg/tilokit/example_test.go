@@ -0,0 +1,100 @@
+package tilokit_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ti-lo/tilokit/pkg/tilokit"
+)
+
+// servicePlugin generates a minimal project for the "acme-service" framework
+type servicePlugin struct{}
+
+func (servicePlugin) Name() string                        { return "acme-service" }
+func (servicePlugin) Version() string                     { return "1.0.0" }
+func (servicePlugin) Description() string                 { return "ACME service skeleton" }
+func (servicePlugin) SupportedFrameworks() []string       { return []string{"acme-service"} }
+func (servicePlugin) SupportedBuildTools() []string       { return []string{"make"} }
+func (servicePlugin) PreGenerate(*tilokit.Context) error  { return nil }
+func (servicePlugin) PostGenerate(*tilokit.Context) error { return nil }
+
+func (servicePlugin) Generate(ctx *tilokit.Context) error {
+	if err := ctx.WriteFile("README.md", "# "+ctx.Config.ProjectName+"\n"); err != nil {
+		return err
+	}
+	return ctx.WriteFile("Makefile", "build:\n\tgo build ./...\n")
+}
+
+func ExampleGenerate() {
+	fsys := tilokit.NewMemoryFS()
+	result, err := tilokit.Generate(context.Background(), &tilokit.Spec{
+		ProjectName: "billing",
+		Framework:   "acme-service",
+		BuildTool:   "make",
+	}, tilokit.Options{
+		FS:         fsys,
+		Plugins:    []tilokit.Plugin{servicePlugin{}},
+		NoBuiltins: true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, file := range result.Files {
+		fmt.Println(file.Path, file.Plugin)
+	}
+	// Output:
+	// .tilokit/lock.yaml tilokit
+	// Makefile acme-service
+	// README.md acme-service
+}
+
+func ExampleOptions_onEvent() {
+	_, err := tilokit.Generate(context.Background(), &tilokit.Spec{
+		ProjectName: "billing",
+		Framework:   "acme-service",
+		BuildTool:   "make",
+	}, tilokit.Options{
+		FS:         tilokit.NewMemoryFS(),
+		Plugins:    []tilokit.Plugin{servicePlugin{}},
+		NoBuiltins: true,
+		OnEvent: func(event tilokit.Event) {
+			switch event.Kind {
+			case tilokit.EventHook:
+				fmt.Println(event.Phase, event.Plugin)
+			case tilokit.EventFile:
+				fmt.Println("wrote", event.Path)
+			}
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// pre-generate acme-service
+	// generate acme-service
+	// wrote README.md
+	// wrote Makefile
+	// wrote .tilokit/lock.yaml
+	// post-generate acme-service
+}
+
+func ExampleRegister() {
+	// Typically done from an init function of the package providing the plugin
+	if err := tilokit.Register(servicePlugin{}); err != nil {
+		log.Fatal(err)
+	}
+
+	gen, err := tilokit.New(tilokit.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, decision := range gen.Explain(&tilokit.Spec{Framework: "acme-service", BuildTool: "make"}) {
+		if decision.Selected && !decision.Tool {
+			fmt.Println(decision.Plugin.Name())
+		}
+	}
+	// Output:
+	// acme-service
+}
@@ -0,0 +1,113 @@
+package tilokit
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/internal/plugins/builders"
+	"github.com/ti-lo/tilokit/internal/plugins/frameworks"
+	"github.com/ti-lo/tilokit/internal/plugins/tools"
+)
+
+var (
+	registered      []Plugin
+	registeredMutex sync.Mutex
+)
+
+// Register adds a plugin to every Generator created afterwards, the way
+// database/sql drivers register themselves from init. Plugin names must be
+// unique, built-in plugins included, and incompatible plugins are rejected
+// with an *IncompatibleError.
+func Register(plugin Plugin) error {
+	if plugin == nil {
+		return errors.New("plugin is nil")
+	}
+	if err := registry.CheckPlugin(plugin); err != nil {
+		return err
+	}
+
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+
+	for _, other := range append(builtinPlugins(), registered...) {
+		if other.Name() == plugin.Name() {
+			return errors.Errorf("plugin %s is already registered", plugin.Name())
+		}
+	}
+	registered = append(registered, plugin)
+	return nil
+}
+
+// registeredPlugins returns the plugins added with Register
+func registeredPlugins() []Plugin {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+	return append([]Plugin(nil), registered...)
+}
+
+// builtinPlugins returns new instances of the plugins shipped with TiLoKit
+func builtinPlugins() []Plugin {
+	return []Plugin{
+		// JavaScript/TypeScript Frameworks
+		frameworks.NewReactPlugin(),
+		frameworks.NewVuePlugin(),
+		// More JS frameworks can be added here
+
+		// Backend Frameworks
+		// Python
+		frameworks.NewPythonDjangoPlugin(),
+		frameworks.NewPythonFlaskPlugin(),
+		frameworks.NewPythonFastAPIPlugin(),
+
+		// PHP
+		frameworks.NewPHPLaravelPlugin(),
+		frameworks.NewPHPSymfonyPlugin(),
+
+		// Java
+		frameworks.NewJavaSpringBootPlugin(),
+		frameworks.NewJavaQuarkusPlugin(),
+
+		// Go
+		frameworks.NewGoGinPlugin(),
+		frameworks.NewGoEchoPlugin(),
+		frameworks.NewGoFiberPlugin(),
+
+		// Rust
+		frameworks.NewRustActixPlugin(),
+		frameworks.NewRustRocketPlugin(),
+		frameworks.NewRustAxumPlugin(),
+
+		// C#
+		frameworks.NewCSharpASPNetCorePlugin(),
+		frameworks.NewCSharpBlazorPlugin(),
+
+		// Ruby
+		frameworks.NewRubyRailsPlugin(),
+		frameworks.NewRubySinatraPlugin(),
+
+		// Node.js
+		frameworks.NewNodeExpressPlugin(),
+		frameworks.NewNodeNestJSPlugin(),
+		frameworks.NewNodeFastifyPlugin(),
+
+		// Mobile Frameworks
+		frameworks.NewReactNativePlugin(),
+		frameworks.NewFlutterPlugin(),
+		frameworks.NewIonicPlugin(),
+
+		// Desktop Frameworks
+		frameworks.NewElectronPlugin(),
+		frameworks.NewTauriPlugin(),
+		frameworks.NewWailsPlugin(),
+
+		// Build Tools
+		builders.NewVitePlugin(),
+		builders.NewWebpackPlugin(),
+		builders.NewRollupPlugin(),
+
+		// Tools
+		tools.NewGitPlugin(),
+	}
+}
@@ -0,0 +1,260 @@
+package tilokit
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	tilocontext "github.com/ti-lo/tilokit/internal/core/context"
+	"github.com/ti-lo/tilokit/internal/core/engine"
+	"github.com/ti-lo/tilokit/internal/core/registry"
+	"github.com/ti-lo/tilokit/internal/core/upgrade"
+	"github.com/ti-lo/tilokit/internal/plugins/templates"
+	"github.com/ti-lo/tilokit/internal/utils"
+)
+
+type (
+	// Spec describes the project to generate. Saved as YAML it is a recipe
+	// for tilokit --from.
+	Spec = tilocontext.ProjectConfig
+	// Plugin generates part of a project. Plugins are selected by the
+	// frameworks and build tools they support and run in three phases:
+	// PreGenerate, Generate and PostGenerate.
+	Plugin = registry.Plugin
+	// FeaturePlugin is a Plugin that can add features to an existing project
+	FeaturePlugin = registry.FeaturePlugin
+	// TemplateFuncProvider is a Plugin that adds functions to every
+	// template rendered during a run
+	TemplateFuncProvider = registry.TemplateFuncProvider
+	// Manifest describes a plugin, the plugin API it targets and the
+	// options it accepts
+	Manifest = registry.Manifest
+	// ManifestProvider is a Plugin that declares its Manifest
+	ManifestProvider = registry.ManifestProvider
+	// Option is a setting a plugin accepts
+	Option = registry.Option
+	// OptionType is the kind of value an Option or Question takes
+	OptionType = registry.OptionType
+	// Validator is a Plugin that checks a spec before anything is generated
+	Validator = registry.Validator
+	// Prompter is a Plugin that asks questions of its own before generation
+	Prompter = registry.Prompter
+	// Question is asked by a Prompter; its answer becomes a variable
+	Question = registry.Question
+	// Augmenter is a FeaturePlugin that adds features to an existing
+	// project with Augment instead of Generate
+	Augmenter = registry.Augmenter
+	// Context is what plugin hooks receive: the spec, variables and the
+	// filesystem to write the project through
+	Context = tilocontext.ExecutionContext
+	// FS is a filesystem a project can be generated into
+	FS = utils.FS
+	// FileRecord describes a generated file
+	FileRecord = tilocontext.FileRecord
+	// SkippedStep is a side effect a plugin skipped because the project
+	// was not generated to disk
+	SkippedStep = tilocontext.SkippedStep
+	// Plan is the outcome of a dry run
+	Plan = engine.Plan
+	// PlannedFile is a file a dry run would write
+	PlannedFile = engine.PlannedFile
+	// Decision explains why a plugin was or wasn't selected
+	Decision = registry.Decision
+	// UpgradeResult describes what an upgrade did to each file
+	UpgradeResult = upgrade.Result
+)
+
+// APIVersion is the plugin API implemented by this version. A plugin whose
+// Manifest targets a newer one is rejected by New and Register.
+const APIVersion = registry.APIVersion
+
+// Option types
+const (
+	OptionString      = registry.OptionString
+	OptionBool        = registry.OptionBool
+	OptionInt         = registry.OptionInt
+	OptionChoice      = registry.OptionChoice
+	OptionMultiChoice = registry.OptionMultiChoice
+)
+
+// ManifestOf returns the manifest of a plugin, built from its methods when
+// it isn't a ManifestProvider
+func ManifestOf(plugin Plugin) Manifest {
+	return registry.ManifestOf(plugin)
+}
+
+// NewMemoryFS returns an empty in-memory filesystem for Options.FS
+func NewMemoryFS() FS {
+	return utils.NewMemoryFS()
+}
+
+// Options configures a Generator
+type Options struct {
+	// FS receives generated projects. When nil they are written to disk
+	// under Spec.OutputDir, all at once when generation succeeds. Any other
+	// filesystem is treated as virtual: plugins skip side effects such as
+	// git init and report them in Result.Skipped.
+	FS FS
+	// Plugins are used along with the built-in plugins and those added
+	// with Register
+	Plugins []Plugin
+	// NoBuiltins leaves out the plugins shipped with TiLoKit
+	NoBuiltins bool
+	// OnEvent, when set, is called as plugins run and write files, from
+	// the goroutine generating the project
+	OnEvent func(Event)
+	// TemplateCache, when set, is a directory keeping the shipped templates
+	// by revision. Generate and Upgrade add the templates they render, and
+	// Upgrade renders a project's last generation from them when its git
+	// history doesn't have it.
+	TemplateCache string
+}
+
+// Result describes a generated project
+type Result struct {
+	// ProjectPath is the project directory, on disk or on Options.FS
+	ProjectPath string
+	Files       []FileRecord
+	Skipped     []SkippedStep
+	// StartTime is when generation started
+	StartTime time.Time
+}
+
+// Generator generates projects with a fixed set of plugins. It is safe for
+// concurrent use if its plugins are.
+type Generator struct {
+	opts   Options
+	engine *engine.Engine
+}
+
+// New creates a generator with the built-in plugins, the registered ones
+// and those in opts
+func New(opts Options) (*Generator, error) {
+	var plugins []Plugin
+	if !opts.NoBuiltins {
+		plugins = builtinPlugins()
+	}
+	plugins = append(plugins, registeredPlugins()...)
+	plugins = append(plugins, opts.Plugins...)
+
+	eng := engine.New()
+	for _, plugin := range plugins {
+		if err := eng.RegisterPlugin(plugin); err != nil {
+			return nil, errors.Wrapf(err, "failed to register plugin %s", plugin.Name())
+		}
+	}
+	if opts.OnEvent != nil {
+		eng.OnEvent(opts.OnEvent)
+	}
+	return &Generator{opts: opts, engine: eng}, nil
+}
+
+// Generate generates a project with a new Generator
+func Generate(ctx context.Context, spec *Spec, opts Options) (*Result, error) {
+	g, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.Generate(ctx, spec)
+}
+
+// Generate generates the project spec describes. Errors are a *SpecError
+// when the spec can't be generated, a *PluginError when a plugin fails and
+// match context.Canceled or context.DeadlineExceeded when ctx ends first;
+// nothing is written to disk then.
+func (g *Generator) Generate(ctx context.Context, spec *Spec) (*Result, error) {
+	output, err := g.engine.Generate(ctx, spec, g.opts.FS)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range output.Templates {
+		g.keepTemplate(record.Name)
+	}
+	return &Result{
+		ProjectPath: output.Root,
+		Files:       output.Files,
+		Skipped:     output.Skipped,
+		StartTime:   output.StartTime,
+	}, nil
+}
+
+// Plan reports what Generate would write to disk, with diffs against files
+// that already exist, without writing anything
+func (g *Generator) Plan(ctx context.Context, spec *Spec) (*Plan, error) {
+	return g.engine.Plan(ctx, spec)
+}
+
+// AddFeatures adds features to the project generated from spec, which
+// already exists on disk. spec.Features lists every feature the project
+// will have; only the plugins providing the added ones run.
+func (g *Generator) AddFeatures(ctx context.Context, spec *Spec, added []string) error {
+	return g.engine.Augment(ctx, spec, added)
+}
+
+// Upgrade merges what the current plugins generate into the project in
+// dir, which must have a .tilokit/lock.yaml. Unless force is set, a git
+// project must have no uncommitted changes.
+func (g *Generator) Upgrade(ctx context.Context, dir string, force bool) (*UpgradeResult, error) {
+	opts := upgrade.Options{Force: force}
+	if g.opts.TemplateCache != "" {
+		opts.Templates = templates.OpenRevisions(g.opts.TemplateCache).Open
+	}
+	result, err := upgrade.Run(ctx, g.engine, dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	if lock, err := engine.ReadLock(dir); err == nil {
+		for _, locked := range lock.Templates {
+			g.keepTemplate(locked.Name)
+		}
+	}
+	return result, nil
+}
+
+// keepTemplate saves the shipped tree of a template to the template cache.
+// The project is complete without it, so a failure is only reported.
+func (g *Generator) keepTemplate(name string) {
+	if g.opts.TemplateCache == "" {
+		return
+	}
+	if err := templates.OpenRevisions(g.opts.TemplateCache).Save(name); err != nil {
+		utils.Warning("Failed to cache template %s: %v", name, err)
+	}
+}
+
+// Questions returns what the Prompter plugins selected for spec ask,
+// leaving out questions whose variables spec already sets. Store the
+// answers in spec.Variables before generating.
+func (g *Generator) Questions(spec *Spec) ([]Question, error) {
+	return g.engine.Questions(spec)
+}
+
+// Explain reports why each plugin would or wouldn't run for spec
+func (g *Generator) Explain(spec *Spec) []Decision {
+	return g.engine.ExplainPlugins(spec)
+}
+
+// Plugins returns the generator's plugins in name order
+func (g *Generator) Plugins() []Plugin {
+	registered := g.engine.Registry().ListPlugins()
+	plugins := make([]Plugin, 0, len(registered))
+	for _, plugin := range registered {
+		plugins = append(plugins, plugin)
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name() < plugins[j].Name()
+	})
+	return plugins
+}
+
+// Frameworks returns the frameworks the generator's plugins support
+func (g *Generator) Frameworks() []string {
+	return g.engine.Registry().GetSupportedFrameworks()
+}
+
+// BuildTools returns the build tools the generator's plugins support
+func (g *Generator) BuildTools() []string {
+	return g.engine.Registry().GetSupportedBuildTools()
+}
@@ -0,0 +1,298 @@
+package tilokit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ti-lo/tilokit/internal/core/registry"
+)
+
+// widgetPlugin writes a README for the "widget" framework, or fails in the
+// phase named by failIn
+type widgetPlugin struct {
+	name   string
+	failIn string
+}
+
+func (p *widgetPlugin) Name() string                  { return p.name }
+func (p *widgetPlugin) Version() string               { return "1.0.0" }
+func (p *widgetPlugin) Description() string           { return "Widget framework" }
+func (p *widgetPlugin) SupportedFrameworks() []string { return []string{"widget"} }
+func (p *widgetPlugin) SupportedBuildTools() []string { return []string{"widget-build"} }
+
+func (p *widgetPlugin) PreGenerate(*Context) error { return p.fail(PhasePreGenerate) }
+
+func (p *widgetPlugin) Generate(ctx *Context) error {
+	if err := p.fail(PhaseGenerate); err != nil {
+		return err
+	}
+	return ctx.WriteFile("README.md", "# "+ctx.Config.ProjectName+"\n")
+}
+
+func (p *widgetPlugin) PostGenerate(*Context) error { return p.fail(PhasePostGenerate) }
+
+func (p *widgetPlugin) fail(phase string) error {
+	if p.failIn == phase {
+		return errors.New("widget broke")
+	}
+	return nil
+}
+
+func widgetSpec(dir string) *Spec {
+	return &Spec{
+		ProjectName: "gadget",
+		Framework:   "widget",
+		BuildTool:   "widget-build",
+		OutputDir:   dir,
+	}
+}
+
+func TestGenerateWritesToDisk(t *testing.T) {
+	dir := t.TempDir()
+	result, err := Generate(context.Background(), widgetSpec(dir), Options{
+		Plugins:    []Plugin{&widgetPlugin{name: "widget"}},
+		NoBuiltins: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ProjectPath != filepath.Join(dir, "gadget") {
+		t.Errorf("Expected the project under the output dir, got %s", result.ProjectPath)
+	}
+	data, err := os.ReadFile(filepath.Join(result.ProjectPath, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "# gadget\n" {
+		t.Errorf("Unexpected README: %q", data)
+	}
+}
+
+func TestGenerateToFSLeavesDiskAlone(t *testing.T) {
+	dir := t.TempDir()
+	fsys := NewMemoryFS()
+	result, err := Generate(context.Background(), widgetSpec(dir), Options{
+		FS:         fsys,
+		Plugins:    []Plugin{&widgetPlugin{name: "widget"}},
+		NoBuiltins: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fsys.Stat(filepath.Join(result.ProjectPath, "README.md")); err != nil {
+		t.Errorf("Expected the README on the filesystem: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected nothing on disk, got %d entries", len(entries))
+	}
+}
+
+func TestGenerateErrorsAreTyped(t *testing.T) {
+	for _, phase := range []string{PhasePreGenerate, PhaseGenerate, PhasePostGenerate} {
+		t.Run(phase, func(t *testing.T) {
+			_, err := Generate(context.Background(), widgetSpec(t.TempDir()), Options{
+				Plugins:    []Plugin{&widgetPlugin{name: "widget", failIn: phase}},
+				NoBuiltins: true,
+			})
+
+			var pluginErr *PluginError
+			if !errors.As(err, &pluginErr) {
+				t.Fatalf("Expected a PluginError, got %v", err)
+			}
+			if pluginErr.Plugin != "widget" || pluginErr.Phase != phase {
+				t.Errorf("Expected widget to fail in %s, got %s in %s", phase, pluginErr.Plugin, pluginErr.Phase)
+			}
+		})
+	}
+
+	t.Run("spec", func(t *testing.T) {
+		spec := widgetSpec(t.TempDir())
+		spec.Framework = "gizmo"
+		_, err := Generate(context.Background(), spec, Options{
+			Plugins:    []Plugin{&widgetPlugin{name: "widget"}},
+			NoBuiltins: true,
+		})
+
+		var specErr *SpecError
+		if !errors.As(err, &specErr) {
+			t.Fatalf("Expected a SpecError, got %v", err)
+		}
+	})
+}
+
+func TestOnEventReportsFiles(t *testing.T) {
+	var files []string
+	_, err := Generate(context.Background(), widgetSpec(""), Options{
+		FS:         NewMemoryFS(),
+		Plugins:    []Plugin{&widgetPlugin{name: "widget"}},
+		NoBuiltins: true,
+		OnEvent: func(event Event) {
+			if event.Kind == EventFile && event.Plugin == "widget" {
+				files = append(files, event.Path)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != "README.md" {
+		t.Errorf("Expected an event for README.md, got %v", files)
+	}
+}
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	t.Cleanup(func() { registered = nil })
+
+	if err := Register(nil); err == nil {
+		t.Error("Expected an error for a nil plugin")
+	}
+	if err := Register(&widgetPlugin{name: "react-framework"}); err == nil {
+		t.Error("Expected an error for the name of a built-in plugin")
+	}
+
+	if err := Register(&widgetPlugin{name: "registered-widget"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(&widgetPlugin{name: "registered-widget"}); err == nil {
+		t.Error("Expected an error for a plugin registered twice")
+	}
+}
+
+// futureWidget targets a plugin API newer than this version's
+type futureWidget struct {
+	widgetPlugin
+}
+
+func (p *futureWidget) Manifest() Manifest {
+	return Manifest{APIVersion: APIVersion + 1}
+}
+
+func TestIncompatiblePluginsAreRejected(t *testing.T) {
+	plugin := &futureWidget{widgetPlugin{name: "future-widget"}}
+
+	var incompatible *IncompatibleError
+	if err := Register(plugin); !errors.As(err, &incompatible) {
+		t.Errorf("Expected Register to reject the plugin, got %v", err)
+	}
+	if _, err := New(Options{Plugins: []Plugin{plugin}, NoBuiltins: true}); !errors.As(err, &incompatible) {
+		t.Errorf("Expected New to reject the plugin, got %v", err)
+	}
+}
+
+func TestBuiltinPluginsDeclareManifests(t *testing.T) {
+	for _, plugin := range builtinPlugins() {
+		provider, ok := plugin.(ManifestProvider)
+		if !ok {
+			t.Errorf("Expected plugin %s to declare a manifest", plugin.Name())
+			continue
+		}
+		if err := registry.CheckPlugin(plugin); err != nil {
+			t.Errorf("Expected plugin %s to be compatible, got %v", plugin.Name(), err)
+		}
+		manifest := provider.Manifest()
+		if manifest.APIVersion == 0 || manifest.Version != plugin.Version() {
+			t.Errorf("Expected plugin %s to declare its API and version, got %+v", plugin.Name(), manifest)
+		}
+	}
+}
+
+func TestPluginOptionsReachBuiltins(t *testing.T) {
+	spec := &Spec{
+		ProjectName:   "app",
+		Framework:     "react",
+		BuildTool:     "vite",
+		OutputDir:     t.TempDir(),
+		PluginOptions: map[string]map[string]interface{}{"vite-builder": {"port": 8080}},
+	}
+	fsys := NewMemoryFS()
+	result, err := Generate(context.Background(), spec, Options{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open(filepath.Join(result.ProjectPath, "vite.config.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "port: 8080,") || !strings.Contains(string(data), "open: true,") {
+		t.Errorf("Expected the port option and the default of open, got:\n%s", data)
+	}
+
+	spec.PluginOptions = map[string]map[string]interface{}{"vite-builder": {"port": "high"}}
+	var specErr *SpecError
+	if _, err := Generate(context.Background(), spec, Options{FS: NewMemoryFS()}); !errors.As(err, &specErr) {
+		t.Errorf("Expected an invalid option to be rejected, got %v", err)
+	}
+}
+
+func TestPackageJSONKeepsScriptOrder(t *testing.T) {
+	spec := &Spec{
+		ProjectName: "app",
+		Framework:   "react",
+		BuildTool:   "vite",
+		OutputDir:   t.TempDir(),
+		Features:    []string{"typescript", "eslint", "prettier", "testing"},
+	}
+	fsys := NewMemoryFS()
+	result, err := Generate(context.Background(), spec, Options{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open(filepath.Join(result.ProjectPath, "package.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The framework's scripts come first, then those the features patch in
+	last := -1
+	for _, script := range []string{"dev", "build", "preview", "lint", "format", "test"} {
+		index := strings.Index(string(data), `"`+script+`":`)
+		if index <= last {
+			t.Fatalf("Expected %s to follow the scripts before it, got:\n%s", script, data)
+		}
+		last = index
+	}
+}
+
+func TestAddFeaturesToServerProject(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "api")
+	if err := os.MkdirAll(target, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "go.mod"), []byte("module example.com/api\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	gen, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := &Spec{ProjectName: "api", Framework: "gin", BuildTool: "go-modules", OutputDir: dir, Features: []string{"docker"}}
+	if err := gen.AddFeatures(context.Background(), spec, []string{"docker"}); err != nil {
+		t.Fatalf("Expected docker to be added to a gin project, got: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(target, "Dockerfile"))
+	if err != nil || !strings.Contains(string(data), "go build") {
+		t.Errorf("Expected a Go Dockerfile, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "main.go")); !os.IsNotExist(err) {
+		t.Errorf("Expected the framework's own files not to be generated, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+// Package templates embeds the project templates shipped with TiLoKit.
+//
+// Templates are Go text/template files with a .tmpl suffix, laid out as
+// <language>/<framework>/... and rendered with the execution context's
+// variables, e.g. {{.project_name}}.
+package templates
+
+import "embed"
+
+// FS holds every shipped template tree. New language directories must be
+// added to the embed pattern below; the all: prefix keeps partials and dot
+// files such as _helpers.tmpl and .gitignore.
+//
+//go:embed all:go all:python all:rust
+var FS embed.FS